@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/urfave/cli/v2"
+)
+
+// errViolation occurs when a check fails
+var errViolation = errors.New("stress: circulation rules violated")
+
+// response is the body of a response from realmsd
+type response struct {
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+// client is a user logged in to realmsd
+type client struct {
+	server string
+	http   *http.Client
+	id     uint
+}
+
+// stress holds the state of a run
+type stress struct {
+	admin    *client
+	users    []*client
+	bookID   uint
+	copies   int
+	failures int
+}
+
+func main() {
+	app := &cli.App{
+		Name:  "realms-stress",
+		Usage: "Hammers the circulation endpoints of a running realmsd concurrently, and checks that no copy is lent twice",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server", Value: "http://localhost:7274", Usage: "the address of realmsd"},
+			&cli.StringFlag{Name: "username", Value: "admin", Usage: "the username of an admin"},
+			&cli.StringFlag{Name: "password", Usage: "the password of the admin", Required: true},
+			&cli.IntFlag{Name: "users", Value: 20, Usage: "the number of users borrowing at the same time"},
+			&cli.IntFlag{Name: "copies", Value: 3, Usage: "the number of copies of the book"},
+			&cli.IntFlag{Name: "rounds", Value: 3, Usage: "the number of rounds to borrow, renew and return"},
+		},
+		Action: run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println("realms-stress: " + err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(c *cli.Context) error {
+	server := strings.TrimRight(c.String("server"), "/")
+	s := &stress{admin: newClient(server), copies: c.Int("copies")}
+	if err := s.admin.login(c.String("username"), c.String("password")); err != nil {
+		return err
+	}
+	if err := s.setup(c.Int("users")); err != nil {
+		return err
+	}
+	defer s.cleanup()
+
+	for round := 1; round <= c.Int("rounds"); round++ {
+		fmt.Printf("Round %v\n", round)
+		borrowers := s.borrow()
+		s.renew(borrowers)
+		s.giveBack(borrowers)
+	}
+
+	if s.failures > 0 {
+		return fmt.Errorf("%w: %v checks failed", errViolation, s.failures)
+	}
+	fmt.Println("All checks passed.")
+	return nil
+}
+
+func newClient(server string) *client {
+	jar, _ := cookiejar.New(nil)
+	return &client{server: server, http: &http.Client{Jar: jar, Timeout: time.Minute}}
+}
+
+// call sends a request with a JSON body, and decodes the data in the response
+// into out if not nil
+func (cl *client) call(method, path string, body, out interface{}) (int, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(method, cl.server+path, bytes.NewReader(buf))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cl.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return resp.StatusCode, err
+	}
+	if r.Error != "" {
+		return resp.StatusCode, errors.New(r.Error)
+	}
+	if out != nil {
+		return resp.StatusCode, json.Unmarshal(r.Data, out)
+	}
+	return resp.StatusCode, nil
+}
+
+func (cl *client) login(username, password string) error {
+	resp, err := cl.http.PostForm(cl.server+"/login", url.Values{
+		"username": {username},
+		"password": {password},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	return nil
+}
+
+// setup adds a book with its copies, and the users who borrow it
+func (s *stress) setup(users int) error {
+	prefix := fmt.Sprintf("stress-%v", time.Now().Unix())
+
+	var book models.Book
+	input := map[string]interface{}{"title": "Stress test " + prefix}
+	if _, err := s.admin.call("POST", "/admin/books", input, &book); err != nil {
+		return err
+	}
+	s.bookID = book.ID
+	for i := 1; i <= s.copies; i++ {
+		input := map[string]interface{}{"barcode": fmt.Sprintf("%v-%v", prefix, i)}
+		path := fmt.Sprintf("/admin/books/%v/copies", book.ID)
+		if _, err := s.admin.call("POST", path, input, nil); err != nil {
+			return err
+		}
+	}
+
+	for i := 1; i <= users; i++ {
+		username := fmt.Sprintf("%v-%v", prefix, i)
+		var user models.User
+		input := map[string]interface{}{"username": username, "password": prefix, "role": "user"}
+		if _, err := s.admin.call("POST", "/admin/users", input, &user); err != nil {
+			return err
+		}
+		cl := newClient(s.admin.server)
+		cl.id = user.ID
+		if err := cl.login(username, prefix); err != nil {
+			return err
+		}
+		s.users = append(s.users, cl)
+	}
+
+	fmt.Printf("Added book %v with %v copies, and %v users\n", s.bookID, s.copies, users)
+	return nil
+}
+
+// cleanup removes the users and the book added
+func (s *stress) cleanup() {
+	for _, cl := range s.users {
+		s.admin.call("DELETE", fmt.Sprintf("/admin/users/%v", cl.id), nil, nil)
+	}
+	s.admin.call("DELETE", fmt.Sprintf("/admin/books/%v", s.bookID),
+		map[string]string{"message": "stress test"}, nil)
+}
+
+// hammer sends n requests of each user at the same time, and returns the
+// number of successful requests of each user
+func (s *stress) hammer(users []*client, n int, method, path string) ([]int, map[int]int) {
+	succeeded := make([]int, len(users))
+	statuses := make(map[int]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, cl := range users {
+		for j := 0; j < n; j++ {
+			wg.Add(1)
+			go func(i int, cl *client) {
+				defer wg.Done()
+				<-start
+				status, err := cl.call(method, path, map[string]interface{}{}, nil)
+				mu.Lock()
+				defer mu.Unlock()
+				statuses[status]++
+				if err == nil && status == http.StatusOK {
+					succeeded[i]++
+				}
+			}(i, cl)
+		}
+	}
+	close(start)
+	wg.Wait()
+	return succeeded, statuses
+}
+
+// check reports a failed check
+func (s *stress) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		s.failures++
+		fmt.Printf("  FAIL: "+format+"\n", args...)
+	}
+}
+
+// activeRecords returns the active records of the book
+func (s *stress) activeRecords() []models.Record {
+	var records []models.Record
+	path := fmt.Sprintf("/admin/records?book_id=%v&status=active&limit=100", s.bookID)
+	if _, err := s.admin.call("GET", path, nil, &records); err != nil {
+		s.check(false, "failed to list records: %v", err)
+	}
+	return records
+}
+
+// borrow lets every user borrow the book twice at the same time, and checks
+// that each copy is lent to one user at most, and each user borrows it once
+// at most
+func (s *stress) borrow() []*client {
+	path := fmt.Sprintf("/user/books/%v", s.bookID)
+	succeeded, statuses := s.hammer(s.users, 2, "POST", path)
+	fmt.Printf("  Borrow: %v\n", statuses)
+
+	var borrowers []*client
+	for i, n := range succeeded {
+		s.check(n <= 1, "user %v borrowed the book %v times", s.users[i].id, n)
+		if n > 0 {
+			borrowers = append(borrowers, s.users[i])
+		}
+	}
+	s.check(len(borrowers) <= s.copies, "%v loans of %v copies", len(borrowers), s.copies)
+
+	records := s.activeRecords()
+	s.check(len(records) == len(borrowers), "%v active records, expected %v", len(records), len(borrowers))
+	copies := make(map[uint]bool)
+	for _, record := range records {
+		s.check(!copies[record.CopyID], "copy %v lent twice", record.CopyID)
+		copies[record.CopyID] = true
+	}
+
+	var items []models.Copy
+	if _, err := s.admin.call("GET", fmt.Sprintf("/admin/books/%v/copies", s.bookID), nil, &items); err != nil {
+		s.check(false, "failed to list copies: %v", err)
+	}
+	onLoan := 0
+	for _, item := range items {
+		if item.Status == models.CopyOnLoan {
+			onLoan++
+		}
+	}
+	s.check(onLoan == len(records), "%v copies on loan, expected %v", onLoan, len(records))
+	return borrowers
+}
+
+// renew lets every borrower renew the book 3 times at the same time, and
+// checks that each renewal is counted once
+func (s *stress) renew(borrowers []*client) {
+	path := fmt.Sprintf("/user/books/%v", s.bookID)
+	succeeded, statuses := s.hammer(borrowers, 3, "PATCH", path)
+	fmt.Printf("  Renew: %v\n", statuses)
+
+	for i, cl := range borrowers {
+		var record models.Record
+		if _, err := cl.call("GET", path, nil, &record); err != nil {
+			s.check(false, "failed to show the record of user %v: %v", cl.id, err)
+			continue
+		}
+		s.check(int(record.ExtendTimes) == succeeded[i], "user %v renewed %v times, but extended %v times",
+			cl.id, succeeded[i], record.ExtendTimes)
+	}
+}
+
+// giveBack lets every borrower return the book twice at the same time, and
+// checks that it's returned once
+func (s *stress) giveBack(borrowers []*client) {
+	path := fmt.Sprintf("/user/books/%v", s.bookID)
+	succeeded, statuses := s.hammer(borrowers, 2, "DELETE", path)
+	fmt.Printf("  Return: %v\n", statuses)
+
+	for i, n := range succeeded {
+		s.check(n == 1, "user %v returned the book %v times", borrowers[i].id, n)
+	}
+	records := s.activeRecords()
+	s.check(len(records) == 0, "%v active records after returning", len(records))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http/cookiejar"
 	"os"
+	"strings"
 
 	"github.com/hakula139/REALMS/internal/app/frontend"
 	"github.com/urfave/cli/v2"
@@ -20,7 +21,15 @@ func main() {
 				Email: "i@hakula.xyz",
 			},
 		},
-		Usage:  "REALMS Establishes A Library Management System",
+		Usage:     "REALMS Establishes A Library Management System",
+		ArgsUsage: "[command]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "authenticate with an API token instead of logging in",
+				EnvVars: []string{"REALMS_TOKEN"},
+			},
+		},
 		Action: router,
 	}
 
@@ -31,111 +40,349 @@ func main() {
 }
 
 func router(c *cli.Context) error {
-	fmt.Println("Welcome to REALMS! Check the manual using the command 'help'.")
+	frontend.Token = c.String("token")
 	jar, _ := cookiejar.New(nil)
+
+	// Runs the command given in the arguments if any, e.g. show loans
+	if c.Args().Present() {
+		op, arg := splitArg(strings.Join(c.Args().Slice(), " "))
+		run(jar, op, arg)
+		return nil
+	}
+
+	fmt.Println("Welcome to REALMS! Check the manual using the command 'help'.")
 	for {
 		fmt.Print("> ")
 		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		op := scanner.Text()
-		switch op {
-		case "":
-			// Does nothing
-		case "help":
-			frontend.ShowHelp()
-		case "login":
-			if err := frontend.Login(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "logout":
-			if err := frontend.Logout(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "me":
-			if err := frontend.Me(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "status":
-			if err := frontend.Status(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "add book":
-			if err := frontend.AddBook(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "update book":
-			if err := frontend.UpdateBook(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "remove book":
-			if err := frontend.RemoveBook(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show books":
-			if err := frontend.ShowBooks(); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show book":
-			if err := frontend.ShowBook(); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "find books":
-			if err := frontend.FindBooks(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "add user":
-			if err := frontend.AddUser(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "update user":
-			if err := frontend.UpdateUser(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "remove user":
-			if err := frontend.RemoveUser(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show users":
-			if err := frontend.ShowUsers(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show user":
-			if err := frontend.ShowUser(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "borrow book":
-			if err := frontend.BorrowBook(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "return book":
-			if err := frontend.ReturnBook(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "check ddl":
-			if err := frontend.ShowBorrowed(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "extend ddl":
-			if err := frontend.ExtendDeadline(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show list":
-			if err := frontend.ShowBookList(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show overdue":
-			if err := frontend.ShowOverdueList(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "show history":
-			if err := frontend.ShowHistory(jar); err != nil {
-				fmt.Println(err.Error())
-			}
-		case "exit":
-			fmt.Println("Bye!")
+		if !scanner.Scan() {
+			// Quits at the end of input
+			return nil
+		}
+		op, arg := splitArg(scanner.Text())
+		if !run(jar, op, arg) {
 			return nil
-		default:
-			fmt.Println("Invalid operation! Check the manual using the command 'help'.")
 		}
 	}
 }
+
+// splitArg splits the file argument of commands, e.g. import books books.csv
+func splitArg(op string) (string, string) {
+	for _, cmd := range []string{"import books", "export books", "import ical"} {
+		if strings.HasPrefix(op, cmd+" ") {
+			return cmd, strings.TrimSpace(op[len(cmd):])
+		}
+	}
+	return op, ""
+}
+
+// run runs a command, and returns false if the command is exit
+func run(jar *cookiejar.Jar, op, arg string) bool {
+	switch op {
+	case "":
+		// Does nothing
+	case "help":
+		frontend.ShowHelp()
+	case "register":
+		if err := frontend.Register(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "login":
+		if err := frontend.Login(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "logout":
+		if err := frontend.Logout(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show tokens":
+		if err := frontend.ShowTokens(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "create token":
+		if err := frontend.CreateToken(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "revoke token":
+		if err := frontend.RevokeToken(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "logout all":
+		if err := frontend.LogoutAll(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show sessions":
+		if err := frontend.ShowSessions(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "me":
+		if err := frontend.Me(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "passwd":
+		if err := frontend.ChangePassword(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "profile":
+		if err := frontend.UpdateProfile(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "status":
+		if err := frontend.Status(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "add book":
+		if err := frontend.AddBook(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "update book":
+		if err := frontend.UpdateBook(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "remove book":
+		if err := frontend.RemoveBook(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show books":
+		if err := frontend.ShowBooks(); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show book":
+		if err := frontend.ShowBook(); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "find books":
+		if err := frontend.FindBooks(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "import books":
+		if err := frontend.ImportBooks(jar, arg); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "export books":
+		if err := frontend.ExportBooks(jar, arg); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "add copy":
+		if err := frontend.AddCopy(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "update copy":
+		if err := frontend.UpdateCopy(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "retire copy":
+		if err := frontend.RetireCopy(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show copies":
+		if err := frontend.ShowCopies(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show queue":
+		if err := frontend.ShowHoldQueue(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "add user":
+		if err := frontend.AddUser(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "update user":
+		if err := frontend.UpdateUser(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "remove user":
+		if err := frontend.RemoveUser(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show users":
+		if err := frontend.ShowUsers(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show user":
+		if err := frontend.ShowUser(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show pending users":
+		if err := frontend.ShowPendingUsers(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "approve user":
+		if err := frontend.ApproveUser(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "set user state":
+		if err := frontend.SetUserState(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "reset password":
+		if err := frontend.ResetPassword(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show lockouts":
+		if err := frontend.ShowLockouts(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "clear lockout":
+		if err := frontend.ClearLockout(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show roles":
+		if err := frontend.ShowRoles(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "set role":
+		if err := frontend.SetRole(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "remove role":
+		if err := frontend.RemoveRole(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show loans":
+		if err := frontend.ShowLoans(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show all overdue":
+		if err := frontend.ShowAllOverdue(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show user history":
+		if err := frontend.ShowUserHistory(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "check out":
+		if err := frontend.CheckOut(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "renew":
+		if err := frontend.Renew(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "check in":
+		if err := frontend.CheckIn(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "desk":
+		if err := frontend.Desk(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show all fines":
+		if err := frontend.ShowAllFines(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "pay fine":
+		if err := frontend.PayFine(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "waive fine":
+		if err := frontend.WaiveFine(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show policies":
+		if err := frontend.ShowPolicies(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "set policy":
+		if err := frontend.SetPolicy(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "reset policy":
+		if err := frontend.ResetPolicy(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show hours":
+		if err := frontend.ShowOpeningHours(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "set hours":
+		if err := frontend.SetOpeningHours(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show closed days":
+		if err := frontend.ShowClosedDays(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "add closed day":
+		if err := frontend.AddClosedDay(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "remove closed day":
+		if err := frontend.RemoveClosedDay(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "import ical":
+		if err := frontend.ImportCalendar(jar, arg); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show all notices":
+		if err := frontend.ShowAllNotices(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "send notices":
+		if err := frontend.SendNotices(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "borrow book":
+		if err := frontend.BorrowBook(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "return book":
+		if err := frontend.ReturnBook(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "check ddl":
+		if err := frontend.ShowBorrowed(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "extend ddl":
+		if err := frontend.ExtendDeadline(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show list":
+		if err := frontend.ShowBookList(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show overdue":
+		if err := frontend.ShowOverdueList(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show history":
+		if err := frontend.ShowHistory(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "place hold":
+		if err := frontend.PlaceHold(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "cancel hold":
+		if err := frontend.CancelHold(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show holds":
+		if err := frontend.ShowHolds(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show fines":
+		if err := frontend.ShowFines(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show notifications":
+		if err := frontend.ShowNotifications(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "set notifications":
+		if err := frontend.SetNotifications(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "show notices":
+		if err := frontend.ShowNotices(jar); err != nil {
+			fmt.Println(err.Error())
+		}
+	case "exit":
+		fmt.Println("Bye!")
+		return false
+	default:
+		fmt.Println("Invalid operation! Check the manual using the command 'help'.")
+	}
+	return true
+}
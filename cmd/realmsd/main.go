@@ -2,19 +2,47 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/hakula139/REALMS/internal/app/config"
 	ctrl "github.com/hakula139/REALMS/internal/app/controllers"
+	"github.com/hakula139/REALMS/internal/app/migrations"
 	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/notify"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"github.com/hakula139/REALMS/internal/app/service"
+	"github.com/hakula139/REALMS/internal/app/sessionstore"
+	"github.com/jinzhu/gorm"
+	"github.com/urfave/cli/v2"
 )
 
 const session = "mysession"
 
 func main() {
+	app := &cli.App{
+		Name:    "realmsd",
+		Version: "v0.1.0",
+		Authors: []*cli.Author{
+			&cli.Author{
+				Name:  "Hakula Chen",
+				Email: "i@hakula.xyz",
+			},
+		},
+		Usage:    "The back end of REALMS",
+		Action:   serve,
+		Commands: []*cli.Command{migrateCommand},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Println("[error] realmsd: " + err.Error())
+	}
+}
+
+// serve brings the database schema up to date, and starts the server
+func serve(c *cli.Context) error {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
@@ -30,11 +58,22 @@ func main() {
 	sugar := logger.Sugar()
 
 	// Sets up a database connection
-	dbcfg, err := config.LoadDbConfig("./configs/db_config.json")
+	db, err := dbSetup()
 	if err != nil {
 		panic(err.Error())
 	}
-	db, err := models.DbSetup(dbcfg)
+
+	// Applies pending migrations
+	applied, err := migrations.Up(db, 0)
+	for _, m := range applied {
+		sugar.Infof("Applied migration %v", m)
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Builds the full-text search index of books
+	index, err := models.IndexSetup(db)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -44,24 +83,60 @@ func main() {
 		panic(err.Error())
 	}
 
+	authcfg, err := config.LoadAuthConfig("./configs/auth_config.json")
+	if err != nil {
+		panic(err.Error())
+	}
+	if authcfg.Session.Secret == "" {
+		secret, _, err := models.NewSessionToken()
+		if err != nil {
+			panic(err.Error())
+		}
+		authcfg.Session.Secret = secret
+		sugar.Warnf("No session secret set in the config file or %v, users have to log in again once restarted",
+			config.SessionSecretEnv)
+	}
+
+	// Sends due-date reminders and overdue notices in the background
+	notifycfg, err := config.LoadNotifyConfig("./configs/notify_config.json")
+	if err != nil {
+		panic(err.Error())
+	}
+	notifiers, err := notify.New(notifycfg, sugar)
+	if err != nil {
+		panic(err.Error())
+	}
+	reminder := service.NewReminder(repository.NewGorm(db), notifiers, notifycfg, libcfg, sugar)
+	if notifycfg.Enabled {
+		go reminder.Schedule(nil)
+	}
+
+	// Keeps sessions in the database, where the cookies only hold the tokens
+	store := sessionstore.New(repository.NewGorm(db), authcfg.Session, ctrl.UserKey)
+
 	// Provides variables to controllers
 	r.Use(func(c *gin.Context) {
 		c.Set("logger", sugar)
 		c.Set("db", db)
+		c.Set("index", index)
 		c.Set("libcfg", libcfg)
+		c.Set("authcfg", authcfg)
+		c.Set("reminder", reminder)
+		c.Set("sessions", store)
 		c.Next()
 	})
 
-	// Uses cookies to store sessions
-	store := cookie.NewStore([]byte("secret"))
 	r.Use(sessions.Sessions(session, store))
 
 	// Public
+	r.POST("/register", ctrl.Register)
 	r.POST("/login", ctrl.Login)
 	r.GET("/logout", ctrl.Logout)
 	r.GET("/status", ctrl.Status)
 
 	r.GET("/books", ctrl.ShowBooks)
+	// Serves /books/search as well, which gin only allows through the
+	// wildcard, see ctrl.ShowBook
 	r.GET("/books/:id", ctrl.ShowBook)
 	r.POST("/books/find", ctrl.FindBooks)
 
@@ -70,6 +145,12 @@ func main() {
 	user.Use(ctrl.AuthRequired)
 	{
 		user.GET("/me", ctrl.Me)
+		user.PATCH("/me", ctrl.UpdateProfile)
+		user.GET("/sessions", ctrl.ShowSessions)
+		user.DELETE("/sessions", ctrl.LogoutAll)
+		user.GET("/tokens", ctrl.ShowTokens)
+		user.POST("/tokens", ctrl.CreateToken)
+		user.DELETE("/tokens/:id", ctrl.RevokeToken)
 
 		user.GET("/books", ctrl.ShowBookList)
 		user.GET("/books/:id", ctrl.ShowBorrowed)
@@ -78,24 +159,95 @@ func main() {
 		user.POST("/books/:id", ctrl.BorrowBook)
 		user.PATCH("/books/:id", ctrl.ExtendDeadline)
 		user.DELETE("/books/:id", ctrl.ReturnBook)
+
+		user.GET("/holds", ctrl.ShowHolds)
+		user.POST("/holds/:book_id", ctrl.PlaceHold)
+		user.DELETE("/holds/:book_id", ctrl.CancelHold)
+
+		user.GET("/fines", ctrl.ShowFines)
+
+		user.GET("/notifications", ctrl.ShowNotifications)
+		user.PUT("/notifications", ctrl.SetNotifications)
+		user.GET("/notices", ctrl.ShowNotices)
 	}
 
-	// Admin privilege required
+	// Staff permissions required, see models.Permissions
 	admin := r.Group("/admin")
-	admin.Use(ctrl.AdminRequired)
+	catalog := admin.Group("", ctrl.PermissionRequired(models.PermCatalogWrite))
+	{
+		catalog.POST("/books", ctrl.AddBook)
+		// Serves /admin/books/import and /admin/books/export, which gin only
+		// allows through the wildcard, see ctrl.ImportBooks
+		catalog.POST("/books/:id", ctrl.ImportBooks)
+		catalog.GET("/books/:id", ctrl.ExportBooks)
+		catalog.PATCH("/books/:id", ctrl.UpdateBook)
+		catalog.DELETE("/books/:id", ctrl.RemoveBook)
+
+		catalog.GET("/books/:id/copies", ctrl.ShowCopies)
+		catalog.POST("/books/:id/copies", ctrl.AddCopy)
+		catalog.PATCH("/books/:id/copies/:copy_id", ctrl.UpdateCopy)
+		catalog.DELETE("/books/:id/copies/:copy_id", ctrl.RetireCopy)
+	}
+	users := admin.Group("", ctrl.PermissionRequired(models.PermUsersManage))
+	{
+		users.GET("/users", ctrl.ShowUsers)
+		users.GET("/users/:id", ctrl.ShowUser)
+		users.POST("/users", ctrl.AddUser)
+		users.PATCH("/users/:id", ctrl.UpdateUser)
+		users.DELETE("/users/:id", ctrl.RemoveUser)
+		users.POST("/users/:id/approve", ctrl.ApproveUser)
+		users.PUT("/users/:id/state", ctrl.SetUserState)
+		users.POST("/users/:id/reset-password", ctrl.ResetPassword)
+		users.GET("/lockouts", ctrl.ShowLockouts)
+		users.DELETE("/lockouts/:id", ctrl.ClearLockout)
+
+		users.GET("/roles", ctrl.ShowRoles)
+		users.PUT("/roles/:name", ctrl.SetRole)
+		users.DELETE("/roles/:name", ctrl.RemoveRole)
+	}
+	desk := admin.Group("", ctrl.PermissionRequired(models.PermCirculationDesk))
 	{
-		admin.POST("/books", ctrl.AddBook)
-		admin.PATCH("/books/:id", ctrl.UpdateBook)
-		admin.DELETE("/books/:id", ctrl.RemoveBook)
+		desk.GET("/books/:id/holds", ctrl.ShowHoldQueue)
 
-		admin.GET("/users", ctrl.ShowUsers)
-		admin.GET("/users/:id", ctrl.ShowUser)
-		admin.POST("/users", ctrl.AddUser)
-		admin.PATCH("/users/:id", ctrl.UpdateUser)
-		admin.DELETE("/users/:id", ctrl.RemoveUser)
+		desk.POST("/circulation/checkout", ctrl.CheckOut)
+		desk.POST("/circulation/renew", ctrl.Renew)
+		desk.POST("/circulation/checkin", ctrl.CheckIn)
+
+		desk.GET("/fines", ctrl.ShowAllFines)
+		desk.POST("/fines/:id/pay", ctrl.PayFine)
+		desk.POST("/fines/:id/waive", ctrl.WaiveFine)
+
+		desk.GET("/policies", ctrl.ShowPolicies)
+		desk.PUT("/policies/:patron/:item", ctrl.SetPolicy)
+		desk.DELETE("/policies/:patron/:item", ctrl.ResetPolicy)
+
+		desk.GET("/calendar/hours", ctrl.ShowOpeningHours)
+		desk.PUT("/calendar/hours/:weekday", ctrl.SetOpeningHours)
+		desk.GET("/calendar/closed", ctrl.ShowClosedDays)
+		desk.POST("/calendar/closed", ctrl.AddClosedDay)
+		desk.DELETE("/calendar/closed/:date", ctrl.RemoveClosedDay)
+		desk.POST("/calendar/import", ctrl.ImportCalendar)
+
+		desk.POST("/notices/send", ctrl.SendNotices)
+	}
+	reports := admin.Group("", ctrl.PermissionRequired(models.PermReportsRead))
+	{
+		reports.GET("/users/:id/records", ctrl.ShowUserRecords)
+		reports.GET("/records", ctrl.ShowAllRecords)
+		reports.GET("/notices", ctrl.ShowAllNotices)
 	}
 
 	if err := r.Run(":7274"); err != nil {
 		fmt.Println("[error] realmsd: failed to start: " + err.Error())
 	}
+	return nil
+}
+
+// dbSetup opens a database connection using the config file
+func dbSetup() (*gorm.DB, error) {
+	dbcfg, err := config.LoadDbConfig("./configs/db_config.json")
+	if err != nil {
+		return nil, err
+	}
+	return models.DbSetup(dbcfg)
 }
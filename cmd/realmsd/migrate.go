@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hakula139/REALMS/internal/app/migrations"
+	"github.com/urfave/cli/v2"
+)
+
+var migrateCommand = &cli.Command{
+	Name:  "migrate",
+	Usage: "Manages the database schema",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "up",
+			Usage: "Applies pending migrations",
+			Flags: []cli.Flag{
+				&cli.UintFlag{
+					Name:  "to",
+					Usage: "the version to migrate up to, the latest by default",
+				},
+			},
+			Action: migrateUp,
+		},
+		{
+			Name:  "down",
+			Usage: "Reverts applied migrations",
+			Flags: []cli.Flag{
+				&cli.UintFlag{
+					Name:  "steps",
+					Value: 1,
+					Usage: "the number of migrations to revert",
+				},
+				&cli.UintFlag{
+					Name:  "to",
+					Usage: "the version to migrate down to, which overrides steps",
+				},
+			},
+			Action: migrateDown,
+		},
+		{
+			Name:   "status",
+			Usage:  "Shows applied and pending migrations",
+			Action: migrateStatus,
+		},
+	},
+}
+
+// migrateUp applies pending migrations
+func migrateUp(c *cli.Context) error {
+	db, err := dbSetup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := migrations.Up(db, c.Uint("to"))
+	for _, m := range applied {
+		fmt.Println("Applied " + m.String())
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("No pending migrations.")
+	}
+	return nil
+}
+
+// migrateDown reverts applied migrations
+func migrateDown(c *cli.Context) error {
+	db, err := dbSetup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	to := c.Uint("to")
+	if !c.IsSet("to") {
+		if to, err = migrations.Previous(db, c.Uint("steps")); err != nil {
+			return err
+		}
+	}
+	reverted, err := migrations.Down(db, to)
+	for _, m := range reverted {
+		fmt.Println("Reverted " + m.String())
+	}
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		fmt.Println("No migrations to revert.")
+	}
+	return nil
+}
+
+// migrateStatus shows applied and pending migrations
+func migrateStatus(c *cli.Context) error {
+	db, err := dbSetup()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	list, err := migrations.List(db)
+	if err != nil {
+		return err
+	}
+	current, _ := migrations.Current(db)
+	fmt.Printf("Current version: %v, latest version: %v\n", current, migrations.Latest())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Version\tName\tApplied at")
+	for _, m := range list {
+		appliedAt := "pending"
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", m.Version, m.Name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,73 @@
+package calendar
+
+import (
+	"errors"
+	"time"
+)
+
+// DateLayout is the format of dates in the calendar
+const DateLayout = "2006-01-02"
+
+// ClockLayout is the format of opening and closing times
+const ClockLayout = "15:04"
+
+// MaxRollDays is the maximum number of days to look ahead for an open day
+const MaxRollDays = 366
+
+// ErrNoOpenDay occurs when the library is closed on every day within
+// MaxRollDays after a due date
+var ErrNoOpenDay = errors.New("calendar: no open day within a year")
+
+// Hours is the opening hours of a weekday in the format 15:04
+type Hours struct {
+	Opens  string
+	Closes string
+	Closed bool
+}
+
+// Calendar decides when the library is open
+// The library is open all day on the weekdays not in Hours, and closed on the
+// dates in Closed, which are in the format 2006-01-02
+type Calendar struct {
+	Location *time.Location
+	Hours    map[time.Weekday]Hours
+	Closed   map[string]bool
+}
+
+// IsOpen checks if the library is open on the date in the location
+func (c *Calendar) IsOpen(date time.Time) bool {
+	date = date.In(c.Location)
+	if hours, ok := c.Hours[date.Weekday()]; ok && hours.Closed {
+		return false
+	}
+	return !c.Closed[date.Format(DateLayout)]
+}
+
+// EndOfDay returns the closing time of the library on the date in the
+// location, which is the last second of the day if not specified
+func (c *Calendar) EndOfDay(date time.Time) time.Time {
+	date = date.In(c.Location)
+	y, m, d := date.Date()
+	if hours, ok := c.Hours[date.Weekday()]; ok {
+		if closes, err := time.Parse(ClockLayout, hours.Closes); err == nil {
+			return time.Date(y, m, d, closes.Hour(), closes.Minute(), 0, 0, c.Location)
+		}
+	}
+	return time.Date(y, m, d, 23, 59, 59, 0, c.Location)
+}
+
+// DueDate returns the closing time of the first day the library is open, which
+// is at least days after from in the location
+// Days are counted by the calendar rather than 24 hours, so that a due date
+// is not shifted by daylight saving time
+func (c *Calendar) DueDate(from time.Time, days uint) (time.Time, error) {
+	from = from.In(c.Location)
+	y, m, d := from.Date()
+	for i := 0; i <= MaxRollDays; i++ {
+		date := time.Date(y, m, d+int(days)+i, 12, 0, 0, 0, c.Location)
+		if c.IsOpen(date) {
+			return c.EndOfDay(date), nil
+		}
+	}
+	return time.Time{}, ErrNoOpenDay
+}
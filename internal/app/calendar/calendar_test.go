@@ -0,0 +1,179 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+// loadLocation loads a time zone, skipping the test if not installed
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %v not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestDueDate(t *testing.T) {
+	// 2026-03-02 is a Monday
+	monday := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		cal  Calendar
+		from time.Time
+		days uint
+		want time.Time
+	}{
+		{
+			name: "open all day",
+			from: monday,
+			days: 14,
+			want: time.Date(2026, time.March, 16, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "same day",
+			from: monday,
+			days: 0,
+			want: time.Date(2026, time.March, 2, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "closing time",
+			cal:  Calendar{Hours: map[time.Weekday]Hours{time.Monday: {Opens: "09:00", Closes: "17:30"}}},
+			from: monday,
+			days: 7,
+			want: time.Date(2026, time.March, 9, 17, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "malformed closing time",
+			cal:  Calendar{Hours: map[time.Weekday]Hours{time.Monday: {Opens: "09:00", Closes: "5pm"}}},
+			from: monday,
+			days: 7,
+			want: time.Date(2026, time.March, 9, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "closed weekday",
+			cal:  Calendar{Hours: map[time.Weekday]Hours{time.Saturday: {Closed: true}, time.Sunday: {Closed: true}}},
+			from: monday,
+			days: 5,
+			want: time.Date(2026, time.March, 9, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "closed dates",
+			cal:  Calendar{Closed: map[string]bool{"2026-03-16": true, "2026-03-17": true}},
+			from: monday,
+			days: 14,
+			want: time.Date(2026, time.March, 18, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "closed dates and weekdays",
+			cal: Calendar{
+				Hours: map[time.Weekday]Hours{
+					time.Tuesday:   {Closed: true},
+					time.Wednesday: {Opens: "09:00", Closes: "18:00"},
+				},
+				Closed: map[string]bool{"2026-03-16": true},
+			},
+			from: monday,
+			days: 14,
+			want: time.Date(2026, time.March, 18, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "across a year",
+			cal:  Calendar{Closed: map[string]bool{"2027-01-01": true}},
+			from: time.Date(2026, time.December, 18, 10, 0, 0, 0, time.UTC),
+			days: 14,
+			want: time.Date(2027, time.January, 2, 23, 59, 59, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cal.Location = time.UTC
+			got, err := tt.cal.DueDate(tt.from, tt.days)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("DueDate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueDateTimeZone(t *testing.T) {
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	cal := Calendar{
+		Location: shanghai,
+		Hours:    map[time.Weekday]Hours{time.Tuesday: {Opens: "08:00", Closes: "22:00"}},
+	}
+
+	// It's Monday night in UTC, but already Tuesday in Shanghai, so that the
+	// days are counted from Tuesday
+	from := time.Date(2026, time.March, 2, 20, 0, 0, 0, time.UTC)
+	got, err := cal.DueDate(from, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, time.March, 10, 22, 0, 0, 0, shanghai); !got.Equal(want) {
+		t.Errorf("DueDate = %v, want %v", got, want)
+	}
+	if want := time.Date(2026, time.March, 10, 14, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("DueDate = %v in UTC, want %v", got.UTC(), want)
+	}
+}
+
+func TestDueDateDaylightSaving(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	cal := Calendar{
+		Location: newYork,
+		Hours:    map[time.Weekday]Hours{time.Sunday: {Opens: "12:00", Closes: "17:00"}},
+	}
+
+	// Clocks go forward on 2026-03-08 and back on 2026-11-01, both Sundays,
+	// which must not shift the closing time by an hour
+	tests := []struct {
+		from time.Time
+		want time.Time
+	}{
+		{
+			from: time.Date(2026, time.March, 1, 23, 30, 0, 0, newYork),
+			want: time.Date(2026, time.March, 8, 17, 0, 0, 0, newYork),
+		},
+		{
+			from: time.Date(2026, time.October, 25, 0, 30, 0, 0, newYork),
+			want: time.Date(2026, time.November, 1, 17, 0, 0, 0, newYork),
+		},
+	}
+	for _, tt := range tests {
+		got, err := cal.DueDate(tt.from, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("DueDate(%v) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestDueDateNoOpenDay(t *testing.T) {
+	cal := Calendar{Location: time.UTC, Hours: make(map[time.Weekday]Hours)}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		cal.Hours[day] = Hours{Closed: true}
+	}
+	if _, err := cal.DueDate(time.Now(), 14); err != ErrNoOpenDay {
+		t.Errorf("DueDate = %v, want %v", err, ErrNoOpenDay)
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	cal := Calendar{Location: shanghai, Closed: map[string]bool{"2026-10-01": true}}
+
+	// The holiday begins at 16:00 UTC the day before
+	if cal.IsOpen(time.Date(2026, time.September, 30, 16, 0, 0, 0, time.UTC)) {
+		t.Error("IsOpen on the holiday in Shanghai = true")
+	}
+	if !cal.IsOpen(time.Date(2026, time.September, 30, 15, 59, 0, 0, time.UTC)) {
+		t.Error("IsOpen before the holiday in Shanghai = false")
+	}
+}
@@ -0,0 +1,353 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotICalendar occurs when the file doesn't begin with a VCALENDAR
+var ErrNotICalendar = errors.New("calendar: not an iCalendar file")
+
+// ErrUnterminatedEvent occurs when the file ends inside an event
+var ErrUnterminatedEvent = errors.New("calendar: unterminated event")
+
+// ErrMissingStart occurs when an event has no DTSTART
+var ErrMissingStart = errors.New("missing DTSTART")
+
+// ErrInvalidDateTime occurs when a date or date-time value is malformed
+var ErrInvalidDateTime = errors.New("invalid date or date-time")
+
+// ErrInvalidDuration occurs when a DURATION is malformed or not in whole days
+var ErrInvalidDuration = errors.New("invalid duration, expected whole days or weeks")
+
+// ErrRecurringEvent occurs when an event repeats, which is not supported
+var ErrRecurringEvent = errors.New("recurring events not supported")
+
+// ErrEventTooLong occurs when an event lasts longer than MaxRollDays
+var ErrEventTooLong = errors.New("event longer than a year")
+
+// maxLineSize is the maximum size of a line in an iCalendar file
+const maxLineSize = 1 << 20
+
+// icalDuration matches a DURATION of whole days or weeks, e.g. P1D or P2W
+var icalDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?$`)
+
+// Event is an event in an iCalendar file, during which the library is closed
+// Start and End are the first and the last dates of the event in the location
+// of the reader, in the format 2006-01-02
+type Event struct {
+	UID     string
+	Summary string
+	Start   string
+	End     string
+}
+
+// Dates returns the dates from the start to the end of the event inclusive
+func (e Event) Dates() []string {
+	var dates []string
+	start, err := time.Parse(DateLayout, e.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(DateLayout, e.End)
+	if err != nil {
+		return nil
+	}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(DateLayout))
+	}
+	return dates
+}
+
+// EventError occurs when an event in the iCalendar file is malformed or
+// unsupported, where Line is the line the event begins
+type EventError struct {
+	Line    int
+	Summary string
+	Err     error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("calendar: line %d: %v", e.Line, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// ICalReader reads the events from an iCalendar file (RFC 5545) one by one
+// Read returns io.EOF when there're no more events. If an event is malformed,
+// a *EventError is returned, and the next event can still be read
+// Cancelled events are skipped. Date-times without a time zone are in the
+// location of the reader
+type ICalReader struct {
+	s   *bufio.Scanner
+	loc *time.Location
+	// line is the number of lines scanned, where the line after a content line
+	// is read ahead to find the folded lines
+	line      int
+	ahead     string
+	aheadLine int
+	hasAhead  bool
+	begun     bool
+}
+
+// NewICalReader creates a reader of an iCalendar file, where the dates of the
+// events are in the location
+func NewICalReader(r io.Reader, loc *time.Location) *ICalReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineSize)
+	return &ICalReader{s: s, loc: loc}
+}
+
+// readLine reads a content line, where the folded lines are joined, and
+// returns it along with the number of its first line
+func (r *ICalReader) readLine() (string, int, error) {
+	text, first := r.ahead, r.aheadLine
+	if !r.hasAhead {
+		if !r.s.Scan() {
+			if err := r.s.Err(); err != nil {
+				return "", r.line, err
+			}
+			return "", r.line, io.EOF
+		}
+		r.line++
+		text, first = r.s.Text(), r.line
+	}
+	r.hasAhead = false
+	text = strings.TrimRight(text, "\r")
+	for r.s.Scan() {
+		r.line++
+		next := strings.TrimRight(r.s.Text(), "\r")
+		if next != "" && (next[0] == ' ' || next[0] == '\t') {
+			text += next[1:]
+			continue
+		}
+		r.ahead, r.aheadLine, r.hasAhead = next, r.line, true
+		break
+	}
+	return text, first, r.s.Err()
+}
+
+// property is a content line split into its name, parameters and value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseProperty(text string) property {
+	var p property
+	p.params = make(map[string]string)
+	// The value begins after the first colon outside quoted parameters
+	quoted := false
+	colon := len(text)
+	for i, ch := range text {
+		if ch == '"' {
+			quoted = !quoted
+		} else if ch == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	head := text[:colon]
+	if colon < len(text) {
+		p.value = text[colon+1:]
+	}
+	parts := strings.Split(head, ";")
+	p.name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	for _, param := range parts[1:] {
+		if eq := strings.IndexByte(param, '='); eq >= 0 {
+			p.params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+	return p
+}
+
+// Read reads the next event
+func (r *ICalReader) Read() (Event, error) {
+	for {
+		text, line, err := r.readLine()
+		if err == io.EOF && !r.begun {
+			return Event{}, ErrNotICalendar
+		}
+		if err != nil {
+			return Event{}, err
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		p := parseProperty(text)
+		if !r.begun {
+			if p.name != "BEGIN" || !strings.EqualFold(p.value, "VCALENDAR") {
+				return Event{}, ErrNotICalendar
+			}
+			r.begun = true
+			continue
+		}
+		if p.name != "BEGIN" || !strings.EqualFold(p.value, "VEVENT") {
+			continue
+		}
+		props, err := r.readEvent()
+		if err != nil {
+			return Event{}, err
+		}
+		if strings.EqualFold(props["STATUS"].value, "CANCELLED") {
+			continue
+		}
+		event, err := r.parseEvent(props)
+		if err != nil {
+			return event, &EventError{Line: line, Summary: event.Summary, Err: err}
+		}
+		return event, nil
+	}
+}
+
+// readEvent reads the properties of an event until END:VEVENT, where the
+// nested components such as alarms are skipped
+func (r *ICalReader) readEvent() (map[string]property, error) {
+	props := make(map[string]property)
+	depth := 0
+	for {
+		text, _, err := r.readLine()
+		if err == io.EOF {
+			return nil, ErrUnterminatedEvent
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := parseProperty(text)
+		switch {
+		case p.name == "BEGIN":
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case p.name == "END":
+			return props, nil
+		case depth == 0:
+			if _, ok := props[p.name]; !ok {
+				props[p.name] = p
+			}
+		}
+	}
+}
+
+// parseEvent converts the properties of an event to the dates it lasts, where
+// the end of an event is exclusive
+func (r *ICalReader) parseEvent(props map[string]property) (Event, error) {
+	event := Event{
+		UID:     strings.TrimSpace(props["UID"].value),
+		Summary: unescapeText(props["SUMMARY"].value),
+	}
+	if _, ok := props["RRULE"]; ok {
+		return event, ErrRecurringEvent
+	}
+	if _, ok := props["RDATE"]; ok {
+		return event, ErrRecurringEvent
+	}
+	dtstart, ok := props["DTSTART"]
+	if !ok {
+		return event, ErrMissingStart
+	}
+	start, allDay, err := r.parseDateTime(dtstart)
+	if err != nil {
+		return event, err
+	}
+
+	// Finds the last moment of the event, which lasts a day if all-day, and
+	// is instantaneous otherwise if the end is not given
+	end := start
+	if allDay {
+		end = start.AddDate(0, 0, 1)
+	}
+	if dtend, ok := props["DTEND"]; ok {
+		if end, _, err = r.parseDateTime(dtend); err != nil {
+			return event, err
+		}
+	} else if duration, ok := props["DURATION"]; ok {
+		match := icalDuration.FindStringSubmatch(strings.TrimPrefix(duration.value, "+"))
+		if match == nil || (match[1] == "" && match[2] == "") {
+			return event, ErrInvalidDuration
+		}
+		weeks, _ := strconv.Atoi("0" + match[1])
+		days, _ := strconv.Atoi("0" + match[2])
+		end = start.AddDate(0, 0, weeks*7+days)
+	}
+	if end.After(start) {
+		end = end.Add(-time.Nanosecond)
+	} else {
+		end = start
+	}
+
+	first := dateOf(start)
+	last := dateOf(end)
+	if last.Sub(first) > MaxRollDays*24*time.Hour {
+		return event, ErrEventTooLong
+	}
+	event.Start = first.Format(DateLayout)
+	event.End = last.Format(DateLayout)
+	return event, nil
+}
+
+// parseDateTime parses a DATE or DATE-TIME value in the location of the
+// reader, and reports if it's a DATE
+func (r *ICalReader) parseDateTime(p property) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, r.loc)
+		if err != nil {
+			return t, true, ErrInvalidDateTime
+		}
+		return t, true, nil
+	}
+
+	loc := r.loc
+	if strings.HasSuffix(value, "Z") {
+		value, loc = strings.TrimSuffix(value, "Z"), time.UTC
+	} else if tzid := p.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %v", tzid)
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return t, false, ErrInvalidDateTime
+	}
+	return t.In(r.loc), false, nil
+}
+
+// dateOf returns the date of t in UTC, so that the dates can be compared
+// without time zones
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// unescapeText replaces the escaped characters in a TEXT value
+func unescapeText(text string) string {
+	var b strings.Builder
+	escaped := false
+	for _, ch := range text {
+		if escaped {
+			switch ch {
+			case 'n', 'N':
+				b.WriteRune(' ')
+			default:
+				b.WriteRune(ch)
+			}
+			escaped = false
+		} else if ch == '\\' {
+			escaped = true
+		} else {
+			b.WriteRune(ch)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,218 @@
+package calendar
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ical wraps the lines in a VCALENDAR, with CRLF line endings
+func ical(lines ...string) string {
+	lines = append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// readEvents reads all the events, and the errors of the malformed ones
+func readEvents(t *testing.T, input string, loc *time.Location) ([]Event, []error) {
+	t.Helper()
+	r := NewICalReader(strings.NewReader(input), loc)
+	var events []Event
+	var errs []error
+	for {
+		event, err := r.Read()
+		if err == io.EOF {
+			return events, errs
+		}
+		var eventErr *EventError
+		if errors.As(err, &eventErr) {
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestICalReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  Event
+	}{
+		{
+			name:  "all-day",
+			lines: []string{"UID:1", "SUMMARY:National Day", "DTSTART;VALUE=DATE:20261001"},
+			want:  Event{"1", "National Day", "2026-10-01", "2026-10-01"},
+		},
+		{
+			name:  "all-day without VALUE",
+			lines: []string{"DTSTART:20261001"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name:  "all-day with exclusive end",
+			lines: []string{"DTSTART;VALUE=DATE:20261001", "DTEND;VALUE=DATE:20261008"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-07"},
+		},
+		{
+			name:  "all-day with duration",
+			lines: []string{"DTSTART;VALUE=DATE:20261001", "DURATION:P1W"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-07"},
+		},
+		{
+			name:  "all-day with duration in days",
+			lines: []string{"DTSTART;VALUE=DATE:20261230", "DURATION:+P3D"},
+			want:  Event{Start: "2026-12-30", End: "2027-01-01"},
+		},
+		{
+			name:  "timed",
+			lines: []string{"DTSTART:20261001T090000", "DTEND:20261001T120000"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name:  "timed without end",
+			lines: []string{"DTSTART:20261001T090000"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name:  "timed across midnight",
+			lines: []string{"DTSTART:20261001T200000", "DTEND:20261002T020000"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-02"},
+		},
+		{
+			name:  "timed ending at midnight",
+			lines: []string{"DTSTART:20261001T200000", "DTEND:20261002T000000"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name:  "in UTC",
+			lines: []string{"DTSTART:20260930T200000Z", "DTEND:20260930T230000Z"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name:  "in another time zone",
+			lines: []string{"DTSTART;TZID=America/New_York:20261001T090000", "DTEND;TZID=America/New_York:20261001T130000"},
+			want:  Event{Start: "2026-10-01", End: "2026-10-02"},
+		},
+		{
+			name:  "folded and escaped",
+			lines: []string{"SUMMARY:Mid-Autumn\\, and\\nNational", "  Day \\; closed", "DTSTART;VALUE=DATE:20261001"},
+			want:  Event{Summary: "Mid-Autumn, and National Day ; closed", Start: "2026-10-01", End: "2026-10-01"},
+		},
+		{
+			name: "with an alarm",
+			lines: []string{
+				"DTSTART;VALUE=DATE:20261001",
+				"BEGIN:VALARM", "TRIGGER:-PT15M", "DESCRIPTION:Reminder", "END:VALARM",
+				"SUMMARY:Holiday",
+			},
+			want: Event{Summary: "Holiday", Start: "2026-10-01", End: "2026-10-01"},
+		},
+	}
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	loadLocation(t, "America/New_York")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append([]string{"BEGIN:VEVENT"}, tt.lines...), "END:VEVENT")
+			events, errs := readEvents(t, ical(lines...), shanghai)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if len(events) != 1 || !reflect.DeepEqual(events[0], tt.want) {
+				t.Errorf("events = %+v, want %+v", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestICalReaderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  error
+	}{
+		{"RRULE", []string{"DTSTART;VALUE=DATE:20260101", "RRULE:FREQ=YEARLY"}, ErrRecurringEvent},
+		{"weekly RRULE", []string{"DTSTART:20260105T090000", "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"}, ErrRecurringEvent},
+		{"RDATE", []string{"DTSTART;VALUE=DATE:20260101", "RDATE;VALUE=DATE:20270101"}, ErrRecurringEvent},
+		{"missing DTSTART", []string{"SUMMARY:Holiday"}, ErrMissingStart},
+		{"malformed date", []string{"DTSTART;VALUE=DATE:2026-01-01"}, ErrInvalidDateTime},
+		{"malformed date-time", []string{"DTSTART:20260101T25"}, ErrInvalidDateTime},
+		{"duration in hours", []string{"DTSTART:20260101T090000", "DURATION:PT8H"}, ErrInvalidDuration},
+		{"empty duration", []string{"DTSTART;VALUE=DATE:20260101", "DURATION:P"}, ErrInvalidDuration},
+		{"too long", []string{"DTSTART;VALUE=DATE:20260101", "DTEND;VALUE=DATE:20280101"}, ErrEventTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The malformed event is followed by a valid one, which is still read
+			lines := []string{"BEGIN:VEVENT", "SUMMARY:Bad"}
+			lines = append(append(lines, tt.lines...), "END:VEVENT")
+			lines = append(lines, "BEGIN:VEVENT", "DTSTART;VALUE=DATE:20261001", "END:VEVENT")
+			events, errs := readEvents(t, ical(lines...), time.UTC)
+			if len(errs) != 1 || !errors.Is(errs[0], tt.want) {
+				t.Fatalf("errors = %v, want %v", errs, tt.want)
+			}
+			if eventErr := errs[0].(*EventError); eventErr.Line != 3 || eventErr.Summary != "Bad" {
+				t.Errorf("error = %+v, want the event at line 3", eventErr)
+			}
+			if len(events) != 1 || events[0].Start != "2026-10-01" {
+				t.Errorf("events = %+v, want the valid event", events)
+			}
+		})
+	}
+}
+
+func TestICalReaderCancelled(t *testing.T) {
+	input := ical(
+		"BEGIN:VEVENT", "DTSTART;VALUE=DATE:20261001", "STATUS:CANCELLED", "RRULE:FREQ=DAILY", "END:VEVENT",
+		"BEGIN:VEVENT", "DTSTART;VALUE=DATE:20261002", "STATUS:CONFIRMED", "END:VEVENT",
+	)
+	events, errs := readEvents(t, input, time.UTC)
+	if len(errs) != 0 || len(events) != 1 || events[0].Start != "2026-10-02" {
+		t.Errorf("events = %+v, errors = %v, want the confirmed event only", events, errs)
+	}
+}
+
+func TestICalReaderMalformedFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"empty", "", ErrNotICalendar},
+		{"not a calendar", "BEGIN:VCARD\r\nEND:VCARD\r\n", ErrNotICalendar},
+		{"unterminated event", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20261001\r\n", ErrUnterminatedEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewICalReader(strings.NewReader(tt.input), time.UTC)
+			if _, err := r.Read(); err != tt.want {
+				t.Errorf("Read = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// A calendar without events
+	r := NewICalReader(strings.NewReader(ical()), time.UTC)
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read = %v, want EOF", err)
+	}
+}
+
+func TestEventDates(t *testing.T) {
+	event := Event{Start: "2026-12-30", End: "2027-01-02"}
+	want := []string{"2026-12-30", "2026-12-31", "2027-01-01", "2027-01-02"}
+	if got := event.Dates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dates = %v, want %v", got, want)
+	}
+	if got := (Event{Start: "2026-10-01", End: "2026-10-01"}).Dates(); !reflect.DeepEqual(got, []string{"2026-10-01"}) {
+		t.Errorf("Dates = %v, want a single date", got)
+	}
+	if got := (Event{Start: "20261001"}).Dates(); got != nil {
+		t.Errorf("Dates = %v, want none", got)
+	}
+}
@@ -0,0 +1,155 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingTitleColumn occurs when the header of a CSV file has no title
+var ErrMissingTitleColumn = errors.New("catalog: missing title column in CSV header")
+
+// csvColumns are the columns of a CSV file, where authors and subjects are
+// lists separated by ";"
+var csvColumns = []string{
+	"title", "authors", "publisher", "isbn", "year", "edition", "language",
+	"pages", "series", "volume", "subjects", "additional_copy", "barcode", "location",
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	// Reads the header, where the columns may be in any order, and a UTF-8 BOM
+	// may be present
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "author" {
+			name = "authors"
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, ErrMissingTitleColumn
+	}
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (r *csvReader) Read() (Record, error) {
+	fields, err := r.r.Read()
+	if err == io.EOF {
+		return Record{}, err
+	}
+	r.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, &RowError{r.row, parseErr.Err}
+		}
+		return Record{}, err
+	}
+
+	get := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	record := Record{
+		Title:     get("title"),
+		Authors:   splitList(get("authors")),
+		Publisher: get("publisher"),
+		ISBN:      get("isbn"),
+		Edition:   get("edition"),
+		Language:  get("language"),
+		Series:    get("series"),
+		Subjects:  splitList(get("subjects")),
+		Barcode:   get("barcode"),
+		Location:  get("location"),
+	}
+	numbers := []struct {
+		name  string
+		value *uint
+	}{
+		{"year", &record.Year},
+		{"pages", &record.Pages},
+		{"volume", &record.Volume},
+	}
+	for _, number := range numbers {
+		if text := get(number.name); text != "" {
+			n, err := strconv.ParseUint(text, 10, 32)
+			if err != nil {
+				return record, &RowError{r.row, errors.New("invalid " + number.name)}
+			}
+			*number.value = uint(n)
+		}
+	}
+	if text := get("additional_copy"); text != "" {
+		flag, err := strconv.ParseBool(text)
+		if err != nil {
+			return record, &RowError{r.row, errors.New("invalid additional_copy")}
+		}
+		record.AdditionalCopy = flag
+	}
+	return record, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{cw}, nil
+}
+
+func (w *csvWriter) Write(record Record) error {
+	number := func(n uint) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(int(n))
+	}
+	additionalCopy := ""
+	if record.AdditionalCopy {
+		additionalCopy = "true"
+	}
+	return w.w.Write([]string{
+		record.Title,
+		strings.Join(record.Authors, "; "),
+		record.Publisher,
+		record.ISBN,
+		number(record.Year),
+		record.Edition,
+		record.Language,
+		number(record.Pages),
+		record.Series,
+		number(record.Volume),
+		strings.Join(record.Subjects, "; "),
+		additionalCopy,
+		record.Barcode,
+		record.Location,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,162 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Delimiters in ISO 2709
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+)
+
+// ErrInvalidMARC occurs when a record in an ISO 2709 file is malformed
+var ErrInvalidMARC = errors.New("invalid MARC record")
+
+type iso2709Reader struct {
+	r   *bufio.Reader
+	row int
+}
+
+func newISO2709Reader(r io.Reader) *iso2709Reader {
+	return &iso2709Reader{r: bufio.NewReader(r)}
+}
+
+func (r *iso2709Reader) Read() (Record, error) {
+	var raw []byte
+	for {
+		var err error
+		raw, err = r.r.ReadBytes(recordTerminator)
+		if err == io.EOF && len(bytes.TrimSpace(raw)) == 0 {
+			return Record{}, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return Record{}, err
+		}
+		// Skips the line breaks between records, which some tools add
+		if raw = bytes.TrimLeft(raw, "\r\n"); len(raw) != 0 {
+			break
+		}
+	}
+	r.row++
+	m, err := decodeISO2709(raw)
+	if err != nil {
+		return Record{}, &RowError{r.row, err}
+	}
+	return m.toRecord(), nil
+}
+
+// decodeISO2709 decodes a record, which ends with the record terminator
+func decodeISO2709(raw []byte) (marcRecord, error) {
+	var m marcRecord
+	if len(raw) < 25 || raw[len(raw)-1] != recordTerminator {
+		return m, ErrInvalidMARC
+	}
+	m.leader = string(raw[:24])
+	base, err := strconv.Atoi(string(raw[12:17]))
+	if err != nil || base < 25 || base > len(raw) {
+		return m, ErrInvalidMARC
+	}
+
+	// Each directory entry consists of the tag, the length and the starting
+	// position of a field
+	directory := raw[24 : base-1]
+	if len(directory)%12 != 0 {
+		return m, ErrInvalidMARC
+	}
+	for i := 0; i < len(directory); i += 12 {
+		entry := directory[i : i+12]
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		if err1 != nil || err2 != nil || length < 1 || base+start+length > len(raw) {
+			return m, ErrInvalidMARC
+		}
+		value := raw[base+start : base+start+length-1]
+		field := marcField{tag: string(entry[:3])}
+		if field.isControl() {
+			field.value = string(value)
+		} else {
+			if len(value) < 2 {
+				return m, ErrInvalidMARC
+			}
+			field.ind1, field.ind2 = value[0], value[1]
+			for _, sub := range bytes.Split(value[2:], []byte{subfieldDelimiter}) {
+				if len(sub) != 0 {
+					field.subfields = append(field.subfields, marcSubfield{sub[0], string(sub[1:])})
+				}
+			}
+		}
+		m.fields = append(m.fields, field)
+	}
+	return m, nil
+}
+
+type iso2709Writer struct {
+	w io.Writer
+}
+
+func newISO2709Writer(w io.Writer) *iso2709Writer {
+	return &iso2709Writer{w}
+}
+
+func (w *iso2709Writer) Write(record Record) error {
+	raw, err := encodeISO2709(fromRecord(record))
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(raw)
+	return err
+}
+
+func (w *iso2709Writer) Close() error {
+	return nil
+}
+
+// encodeISO2709 encodes a record, filling in the record length and the base
+// address in the leader
+func encodeISO2709(m marcRecord) ([]byte, error) {
+	var directory, data bytes.Buffer
+	for _, field := range m.fields {
+		start := data.Len()
+		if field.isControl() {
+			data.WriteString(field.value)
+		} else {
+			data.WriteByte(field.ind1)
+			data.WriteByte(field.ind2)
+			for _, sub := range field.subfields {
+				data.WriteByte(subfieldDelimiter)
+				data.WriteByte(sub.code)
+				data.WriteString(sub.value)
+			}
+		}
+		data.WriteByte(fieldTerminator)
+		length := data.Len() - start
+		if length > 9999 || start > 99999 {
+			return nil, fmt.Errorf("catalog: field %s too long for MARC", field.tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", field.tag, length, start)
+	}
+	directory.WriteByte(fieldTerminator)
+	data.WriteByte(recordTerminator)
+
+	base := 24 + directory.Len()
+	total := base + data.Len()
+	if total > 99999 {
+		return nil, errors.New("catalog: record too long for MARC")
+	}
+	leader := []byte(m.leader)
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+
+	raw := make([]byte, 0, total)
+	raw = append(raw, leader...)
+	raw = append(raw, directory.Bytes()...)
+	raw = append(raw, data.Bytes()...)
+	return raw, nil
+}
@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// encodeTestRecord encodes a record of a few fields in ISO 2709
+func encodeTestRecord(t *testing.T, title string) []byte {
+	t.Helper()
+	raw, err := encodeISO2709(fromRecord(Record{Title: title, Authors: []string{"Randal E. Bryant"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDecodeISO2709Malformed(t *testing.T) {
+	// The leader is 24 bytes, followed by 3 directory entries of 12 bytes, so
+	// that the base address is 24 + 36 + 1 = 61
+	valid := func() []byte { return encodeTestRecord(t, "CS:APP") }
+	tests := []struct {
+		name   string
+		modify func(raw []byte) []byte
+	}{
+		{"empty", func(raw []byte) []byte { return []byte{recordTerminator} }},
+		{"truncated leader", func(raw []byte) []byte { return append(raw[:20:20], recordTerminator) }},
+		{"truncated data", func(raw []byte) []byte { return append(raw[:70:70], recordTerminator) }},
+		{"no record terminator", func(raw []byte) []byte { return raw[:len(raw)-1] }},
+		{"non-numeric base address", func(raw []byte) []byte { copy(raw[12:17], "00x61"); return raw }},
+		{"base address in the leader", func(raw []byte) []byte { copy(raw[12:17], "00010"); return raw }},
+		{"base address past the end", func(raw []byte) []byte { copy(raw[12:17], "99999"); return raw }},
+		{"partial directory entry", func(raw []byte) []byte { copy(raw[12:17], "00055"); return raw }},
+		{"non-numeric field length", func(raw []byte) []byte { copy(raw[27:31], "00?9"); return raw }},
+		{"zero field length", func(raw []byte) []byte { copy(raw[27:31], "0000"); return raw }},
+		{"field past the end", func(raw []byte) []byte { copy(raw[31:36], "09999"); return raw }},
+		{"data field without indicators", func(raw []byte) []byte { copy(raw[27:31], "0001"); return raw }},
+	}
+	if _, err := decodeISO2709(valid()); err != nil {
+		t.Fatalf("decodeISO2709(valid) = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeISO2709(tt.modify(valid())); err != ErrInvalidMARC {
+				t.Errorf("decodeISO2709 = %v, want %v", err, ErrInvalidMARC)
+			}
+		})
+	}
+}
+
+func TestISO2709Reader(t *testing.T) {
+	// A malformed record between two valid ones, separated by line breaks
+	var input bytes.Buffer
+	input.Write(encodeTestRecord(t, "First"))
+	input.WriteString("\r\n")
+	bad := encodeTestRecord(t, "Bad")
+	copy(bad[12:17], "99999")
+	input.Write(bad)
+	input.WriteString("\n")
+	input.Write(encodeTestRecord(t, "Last"))
+	input.WriteString("\n")
+
+	r, err := NewReader(FormatMARC, &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Read(); err != nil || got.Title != "First" {
+		t.Errorf("Read = %+v, %v, want the first record", got, err)
+	}
+	var rowErr *RowError
+	if _, err := r.Read(); !errors.As(err, &rowErr) || rowErr.Row != 2 || !errors.Is(err, ErrInvalidMARC) {
+		t.Errorf("Read = %v, want an invalid record in row 2", err)
+	}
+	if got, err := r.Read(); err != nil || got.Title != "Last" {
+		t.Errorf("Read after an error = %+v, %v, want the last record", got, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read = %v, want EOF", err)
+	}
+}
+
+func TestISO2709ReaderTruncatedFile(t *testing.T) {
+	raw := encodeTestRecord(t, "Truncated")
+	r, err := NewReader(FormatMARC, bytes.NewReader(raw[:len(raw)/2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rowErr *RowError
+	if _, err := r.Read(); !errors.As(err, &rowErr) {
+		t.Errorf("Read = %v, want a row error", err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read = %v, want EOF", err)
+	}
+}
+
+func TestMARCToRecord(t *testing.T) {
+	// A record as catalogued elsewhere, with ISBD punctuation, a qualified
+	// ISBN, the language in 008 and publication in 260
+	m := marcRecord{leader: marcLeader, fields: []marcField{
+		{tag: "008", value: "150101s2015    nju           000 0 eng d"},
+		{tag: "020", subfields: []marcSubfield{{'a', "9780134092669 (hardcover)"}}},
+		{tag: "100", subfields: []marcSubfield{{'a', "Bryant, Randal E.,"}}},
+		{tag: "245", subfields: []marcSubfield{{'a', "Computer systems :"}, {'b', "a programmer's perspective /"}}},
+		{tag: "260", subfields: []marcSubfield{{'b', "Pearson,"}, {'c', "c2015."}}},
+		{tag: "300", subfields: []marcSubfield{{'a', "xxix, 1120 pages :"}}},
+		{tag: "700", subfields: []marcSubfield{{'a', "O'Hallaron, David R."}}},
+	}}
+	got := m.toRecord()
+	if got.Title != "Computer systems: a programmer's perspective" {
+		t.Errorf("Title = %q", got.Title)
+	}
+	if got.ISBN != "9780134092669" || got.Language != "eng" || got.Publisher != "Pearson" || got.Year != 2015 {
+		t.Errorf("record = %+v", got)
+	}
+	if len(got.Authors) != 2 || got.Authors[0] != "Bryant, Randal E" || got.Authors[1] != "O'Hallaron, David R" {
+		t.Errorf("Authors = %q", got.Authors)
+	}
+}
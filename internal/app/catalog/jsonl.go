@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// maxLineSize is the maximum size of a line in a JSON Lines file
+const maxLineSize = 1 << 20
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (r *jsonlReader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		r.row++
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return record, &RowError{r.row, err}
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &jsonlWriter{encoder}
+}
+
+func (w *jsonlWriter) Write(record Record) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package catalog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MARC 21 fields mapped to records
+//   008/35-37  language code, if 041 is absent
+//   020 $a     ISBN
+//   041 $a     language
+//   100 $a     main author
+//   245 $a $b  title and subtitle
+//   250 $a     edition
+//   260 / 264  $b publisher, $c year
+//   300 $a     pages
+//   490 $a $v  series and volume
+//   650 $a     subjects
+//   700 $a     other authors
+//   852 $p $c  barcode and location of the copy
+
+// marcLeader is the leader of exported records, where the record length and
+// the base address are filled in when encoding
+// n: new record, a: language material, m: monograph, a: UTF-8
+const marcLeader = "00000nam a2200000 i 4500"
+
+type marcRecord struct {
+	leader string
+	fields []marcField
+}
+
+// marcField is either a control field of a value, or a data field of
+// indicators and subfields
+type marcField struct {
+	tag       string
+	value     string
+	ind1      byte
+	ind2      byte
+	subfields []marcSubfield
+}
+
+type marcSubfield struct {
+	code  byte
+	value string
+}
+
+func (f *marcField) isControl() bool {
+	return strings.HasPrefix(f.tag, "00")
+}
+
+// subfield returns the first subfield of the code
+func (f *marcField) subfield(code byte) string {
+	for _, sub := range f.subfields {
+		if sub.code == code {
+			return sub.value
+		}
+	}
+	return ""
+}
+
+var (
+	digitsPattern = regexp.MustCompile(`\d+`)
+	yearPattern   = regexp.MustCompile(`\d{4}`)
+	isbnPattern   = regexp.MustCompile(`^[0-9Xx-]+`)
+)
+
+// trimPunctuation removes the ISBD punctuation at the end of a subfield
+func trimPunctuation(s string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), " /:;,."))
+}
+
+// toRecord converts a MARC record to a record
+func (m marcRecord) toRecord() Record {
+	var record Record
+	var mainAuthor string
+	var otherAuthors []string
+	for i := range m.fields {
+		f := &m.fields[i]
+		switch f.tag {
+		case "008":
+			if len(f.value) >= 38 && record.Language == "" {
+				record.Language = strings.TrimSpace(f.value[35:38])
+			}
+		case "020":
+			if record.ISBN == "" {
+				record.ISBN = isbnPattern.FindString(strings.TrimSpace(f.subfield('a')))
+			}
+		case "041":
+			if lang := f.subfield('a'); lang != "" {
+				record.Language = strings.TrimSpace(lang)
+			}
+		case "100":
+			mainAuthor = trimPunctuation(f.subfield('a'))
+		case "245":
+			record.Title = trimPunctuation(f.subfield('a'))
+			if subtitle := trimPunctuation(f.subfield('b')); subtitle != "" {
+				record.Title += ": " + subtitle
+			}
+		case "250":
+			record.Edition = trimPunctuation(f.subfield('a'))
+		case "260", "264":
+			if publisher := trimPunctuation(f.subfield('b')); publisher != "" {
+				record.Publisher = publisher
+			}
+			if year := yearPattern.FindString(f.subfield('c')); year != "" {
+				n, _ := strconv.Atoi(year)
+				record.Year = uint(n)
+			}
+		case "300":
+			if pages := digitsPattern.FindString(f.subfield('a')); pages != "" {
+				n, _ := strconv.Atoi(pages)
+				record.Pages = uint(n)
+			}
+		case "490":
+			record.Series = trimPunctuation(f.subfield('a'))
+			if volume := digitsPattern.FindString(f.subfield('v')); volume != "" {
+				n, _ := strconv.Atoi(volume)
+				record.Volume = uint(n)
+			}
+		case "650":
+			if subject := trimPunctuation(f.subfield('a')); subject != "" {
+				record.Subjects = append(record.Subjects, subject)
+			}
+		case "700":
+			if author := trimPunctuation(f.subfield('a')); author != "" {
+				otherAuthors = append(otherAuthors, author)
+			}
+		case "852":
+			record.Barcode = strings.TrimSpace(f.subfield('p'))
+			record.Location = strings.TrimSpace(f.subfield('c'))
+		}
+	}
+	if mainAuthor != "" {
+		record.Authors = append(record.Authors, mainAuthor)
+	}
+	record.Authors = append(record.Authors, otherAuthors...)
+	return record
+}
+
+// fromRecord converts a record to a MARC record
+func fromRecord(record Record) marcRecord {
+	m := marcRecord{leader: marcLeader}
+	data := func(tag string, ind1, ind2 byte, subfields ...marcSubfield) {
+		var nonEmpty []marcSubfield
+		for _, sub := range subfields {
+			if sub.value != "" {
+				nonEmpty = append(nonEmpty, sub)
+			}
+		}
+		if len(nonEmpty) != 0 {
+			m.fields = append(m.fields, marcField{tag: tag, ind1: ind1, ind2: ind2, subfields: nonEmpty})
+		}
+	}
+	number := func(n uint) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(int(n))
+	}
+
+	data("020", ' ', ' ', marcSubfield{'a', record.ISBN})
+	data("041", '0', ' ', marcSubfield{'a', record.Language})
+	if len(record.Authors) != 0 {
+		data("100", '1', ' ', marcSubfield{'a', record.Authors[0]})
+	}
+	data("245", '1', '0', marcSubfield{'a', record.Title})
+	data("250", ' ', ' ', marcSubfield{'a', record.Edition})
+	data("264", ' ', '1', marcSubfield{'b', record.Publisher}, marcSubfield{'c', number(record.Year)})
+	if record.Pages != 0 {
+		data("300", ' ', ' ', marcSubfield{'a', number(record.Pages) + " pages"})
+	}
+	data("490", '0', ' ', marcSubfield{'a', record.Series}, marcSubfield{'v', number(record.Volume)})
+	for _, subject := range record.Subjects {
+		data("650", ' ', '4', marcSubfield{'a', subject})
+	}
+	if len(record.Authors) > 1 {
+		for _, author := range record.Authors[1:] {
+			data("700", '1', ' ', marcSubfield{'a', author})
+		}
+	}
+	data("852", ' ', ' ', marcSubfield{'c', record.Location}, marcSubfield{'p', record.Barcode})
+	return m
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// marcxmlNamespace is the namespace of MARCXML documents
+const marcxmlNamespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcxmlReader struct {
+	decoder *xml.Decoder
+}
+
+func newMARCXMLReader(r io.Reader) *marcxmlReader {
+	return &marcxmlReader{xml.NewDecoder(r)}
+}
+
+// Read reads the next record element, which may be the root element or inside
+// a collection
+// A malformed XML document can't be recovered, so its error isn't a *RowError
+func (r *marcxmlReader) Read() (Record, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return Record{}, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var x xmlRecord
+		if err := r.decoder.DecodeElement(&x, &start); err != nil {
+			return Record{}, err
+		}
+		return x.toMARC().toRecord(), nil
+	}
+}
+
+func (x *xmlRecord) toMARC() marcRecord {
+	m := marcRecord{leader: x.Leader}
+	for _, cf := range x.ControlFields {
+		m.fields = append(m.fields, marcField{tag: cf.Tag, value: cf.Value})
+	}
+	for _, df := range x.DataFields {
+		field := marcField{tag: df.Tag, ind1: firstByte(df.Ind1), ind2: firstByte(df.Ind2)}
+		for _, sub := range df.Subfields {
+			field.subfields = append(field.subfields, marcSubfield{firstByte(sub.Code), sub.Value})
+		}
+		m.fields = append(m.fields, field)
+	}
+	return m
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+type marcxmlWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+}
+
+func newMARCXMLWriter(w io.Writer) (*marcxmlWriter, error) {
+	_, err := io.WriteString(w, xml.Header+`<collection xmlns="`+marcxmlNamespace+`">`+"\n")
+	if err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("  ", "  ")
+	return &marcxmlWriter{w, encoder}, nil
+}
+
+func (w *marcxmlWriter) Write(record Record) error {
+	m := fromRecord(record)
+	x := xmlRecord{Leader: m.leader}
+	for _, field := range m.fields {
+		if field.isControl() {
+			x.ControlFields = append(x.ControlFields, xmlControlField{field.tag, field.value})
+			continue
+		}
+		df := xmlDataField{Tag: field.tag, Ind1: string(field.ind1), Ind2: string(field.ind2)}
+		for _, sub := range field.subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{string(sub.code), sub.value})
+		}
+		x.DataFields = append(x.DataFields, df)
+	}
+	return w.encoder.Encode(x)
+}
+
+func (w *marcxmlWriter) Close() error {
+	if err := w.encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "\n</collection>\n")
+	return err
+}
@@ -0,0 +1,154 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats of catalog files
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatMARC    = "marc"
+	FormatMARCXML = "marcxml"
+)
+
+// ErrInvalidFormat occurs when the format of a catalog file is unknown
+var ErrInvalidFormat = errors.New("catalog: invalid format, expected csv / jsonl / marc / marcxml")
+
+// Record is a book in a catalog file
+// Barcode and Location describe a copy of the book, which is only used when
+// importing. AdditionalCopy marks the copy to be added to an existing book of
+// the same ISBN and edition
+type Record struct {
+	Title     string   `json:"title"`
+	Authors   []string `json:"authors,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+	ISBN      string   `json:"isbn,omitempty"`
+	Year      uint     `json:"year,omitempty"`
+	Edition   string   `json:"edition,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Pages     uint     `json:"pages,omitempty"`
+	Series    string   `json:"series,omitempty"`
+	Volume    uint     `json:"volume,omitempty"`
+	Subjects  []string `json:"subjects,omitempty"`
+
+	AdditionalCopy bool   `json:"additional_copy,omitempty"`
+	Barcode        string `json:"barcode,omitempty"`
+	Location       string `json:"location,omitempty"`
+}
+
+// Reader reads records from a catalog file one by one
+// Read returns io.EOF when there're no more records. If a record is
+// malformed, a *RowError is returned, and the next record can still be read
+type Reader interface {
+	Read() (Record, error)
+}
+
+// Writer writes records to a catalog file one by one
+// Close must be called to finish the file, which doesn't close the
+// underlying writer
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+// RowError occurs when a record in the catalog file is malformed
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("catalog: row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateFormat checks if the format is supported
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatCSV, FormatJSONL, FormatMARC, FormatMARCXML:
+		return nil
+	}
+	return ErrInvalidFormat
+}
+
+// NewReader creates a reader of records in the format
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatMARC:
+		return newISO2709Reader(r), nil
+	case FormatMARCXML:
+		return newMARCXMLReader(r), nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+// NewWriter creates a writer of records in the format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatMARC:
+		return newISO2709Writer(w), nil
+	case FormatMARCXML:
+		return newMARCXMLWriter(w)
+	}
+	return nil, ErrInvalidFormat
+}
+
+// FormatOf guesses the format of a catalog file by its extension
+func FormatOf(filename string) (string, error) {
+	i := strings.LastIndex(filename, ".")
+	if i < 0 {
+		return "", ErrInvalidFormat
+	}
+	switch strings.ToLower(filename[i+1:]) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "mrc", "marc":
+		return FormatMARC, nil
+	case "xml", "marcxml":
+		return FormatMARCXML, nil
+	}
+	return "", ErrInvalidFormat
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatMARC:
+		return "application/marc"
+	case FormatMARCXML:
+		return "application/marcxml+xml"
+	}
+	return "application/octet-stream"
+}
+
+// splitList splits a list of items separated by ";"
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRecords cover every field, along with a record of the title only
+var testRecords = []Record{
+	{
+		Title:     "Computer Systems: A Programmer's Perspective",
+		Authors:   []string{"Randal E. Bryant", "David R. O'Hallaron"},
+		Publisher: "Pearson",
+		ISBN:      "9780134092669",
+		Year:      2015,
+		Edition:   "3rd",
+		Language:  "English",
+		Pages:     1120,
+		Series:    "Computer Science",
+		Volume:    2,
+		Subjects:  []string{"Computer Systems", "Programming"},
+		Barcode:   "31000001",
+		Location:  "Floor 3, Shelf A2",
+	},
+	{
+		Title:          "Operating Systems: Three Easy Pieces",
+		Authors:        []string{"Remzi H. Arpaci-Dusseau", "Andrea C. Arpaci-Dusseau"},
+		ISBN:           "9781985086593",
+		Subjects:       []string{"Operating Systems"},
+		AdditionalCopy: true,
+		Barcode:        "31000002",
+	},
+	{
+		Title:    "計算機程序的構造和解釋, \"SICP\" <2nd> & more",
+		Authors:  []string{"Harold Abelson"},
+		Language: "中文",
+	},
+	{Title: "Untitled"},
+}
+
+// roundTrip writes the records in the format, and reads them back
+func roundTrip(t *testing.T, format string, records []Record) []Record {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Record
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record)
+	}
+	return got
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatJSONL, FormatMARC, FormatMARCXML} {
+		t.Run(format, func(t *testing.T) {
+			want := make([]Record, len(testRecords))
+			copy(want, testRecords)
+			// MARC has no field for additional copies
+			if format == FormatMARC || format == FormatMARCXML {
+				for i := range want {
+					want[i].AdditionalCopy = false
+				}
+			}
+
+			got := roundTrip(t, format, testRecords)
+			if len(got) != len(want) {
+				t.Fatalf("read %v records, want %v", len(got), len(want))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("record %v = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"books.csv", FormatCSV},
+		{"books.JSONL", FormatJSONL},
+		{"books.ndjson", FormatJSONL},
+		{"books.mrc", FormatMARC},
+		{"books.marc", FormatMARC},
+		{"export.2020.xml", FormatMARCXML},
+	}
+	for _, tt := range tests {
+		if got, err := FormatOf(tt.filename); err != nil || got != tt.want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", tt.filename, got, err, tt.want)
+		}
+	}
+	for _, filename := range []string{"books", "books.txt", "books."} {
+		if _, err := FormatOf(filename); err != ErrInvalidFormat {
+			t.Errorf("FormatOf(%q) = %v, want %v", filename, err, ErrInvalidFormat)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	// Columns in any order, with a BOM, the author alias and missing columns
+	input := "\ufeffISBN, Author ,title,year\n" +
+		"978-0134092669,Randal E. Bryant; David R. O'Hallaron,CS:APP,2015\n" +
+		"0-13-409266-X,,Short row\n" +
+		",,Bad year,20xx\n" +
+		",,After,\n"
+	r, err := NewReader(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []Record{
+		{Title: "CS:APP", Authors: []string{"Randal E. Bryant", "David R. O'Hallaron"}, ISBN: "978-0134092669", Year: 2015},
+		{Title: "Short row", ISBN: "0-13-409266-X"},
+	} {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Read = %+v, want %+v", got, want)
+		}
+	}
+	var rowErr *RowError
+	if _, err := r.Read(); !errors.As(err, &rowErr) || rowErr.Row != 3 {
+		t.Errorf("Read = %v, want an error in row 3", err)
+	}
+	if got, err := r.Read(); err != nil || got.Title != "After" {
+		t.Errorf("Read after an error = %+v, %v, want the next row", got, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read = %v, want EOF", err)
+	}
+
+	if _, err := NewReader(FormatCSV, strings.NewReader("isbn,authors\n")); err != ErrMissingTitleColumn {
+		t.Errorf("NewReader = %v, want %v", err, ErrMissingTitleColumn)
+	}
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"title": "First"}` + "\n\n" + `{"title": ` + "\n" + `{"title": "Last", "year": 2020}` + "\n"
+	r, err := NewReader(FormatJSONL, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Read(); err != nil || got.Title != "First" {
+		t.Errorf("Read = %+v, %v, want the first row", got, err)
+	}
+	var rowErr *RowError
+	if _, err := r.Read(); !errors.As(err, &rowErr) || rowErr.Row != 2 {
+		t.Errorf("Read = %v, want an error in row 2", err)
+	}
+	if got, err := r.Read(); err != nil || got.Title != "Last" || got.Year != 2020 {
+		t.Errorf("Read after an error = %+v, %v, want the last row", got, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read = %v, want EOF", err)
+	}
+}
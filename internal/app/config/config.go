@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // DbConfig specifies the database connection settings
+// Type is one of mysql, postgres and sqlite3
+// Path is the file of a SQLite database, or :memory: for an in-memory one,
+// which is <Database>.db if left blank
+// SSLMode is the sslmode of PostgreSQL, which is disable if left blank
 type DbConfig struct {
 	Type     string `json:"type"`
 	Protocol string `json:"protocol"`
@@ -18,14 +24,168 @@ type DbConfig struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Database string `json:"database"`
+	Path     string `json:"path,omitempty"`
+	SSLMode  string `json:"sslmode,omitempty"`
 }
 
 // LibraryConfig specifies the library settings
+// A fine of FinePerDay is charged for each overdue day after FineGraceDays,
+// which is at most FineCap (no limit if set to 0)
+// Users are not allowed to borrow books when their unpaid fines in total
+// exceed MaxUnpaidFines
+// MaxActiveLoans is the maximum number of books borrowed by a user at a time,
+// which can be overridden for each user (no limit if set to 0)
+// TimeZone is the IANA name of the time zone of the library, e.g.
+// Asia/Shanghai, where the books fall due on the calendar. The local time zone
+// of the server is used if left blank
 type LibraryConfig struct {
-	BorrowExpireDays uint `json:"borrow_expire_days"`
-	DdlExtendDays    uint `json:"ddl_extend_days"`
-	MaxExtendTimes   uint `json:"max_extend_times"`
-	MaxOverdueBooks  uint `json:"max_overdue_books"`
+	BorrowExpireDays uint    `json:"borrow_expire_days"`
+	DdlExtendDays    uint    `json:"ddl_extend_days"`
+	MaxExtendTimes   uint    `json:"max_extend_times"`
+	MaxOverdueBooks  uint    `json:"max_overdue_books"`
+	MaxActiveLoans   uint    `json:"max_active_loans"`
+	HoldPickupDays   uint    `json:"hold_pickup_days"`
+	FinePerDay       float64 `json:"fine_per_day"`
+	FineGraceDays    uint    `json:"fine_grace_days"`
+	FineCap          float64 `json:"fine_cap"`
+	MaxUnpaidFines   float64 `json:"max_unpaid_fines"`
+	TimeZone         string  `json:"time_zone"`
+}
+
+// Location returns the time zone of the library
+func (cfg LibraryConfig) Location() (*time.Location, error) {
+	if cfg.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(cfg.TimeZone)
+}
+
+// NotifyConfig specifies the due-date reminders and overdue notices
+// The loans due within DueSoonDays and the overdue loans are checked every
+// IntervalMinutes, and the notices are sent through each of the Sinks, namely,
+// smtp, webhook and log. Nothing is sent if Enabled is not set
+type NotifyConfig struct {
+	Enabled         bool          `json:"enabled"`
+	IntervalMinutes uint          `json:"interval_minutes"`
+	DueSoonDays     uint          `json:"due_soon_days"`
+	Sinks           []string      `json:"sinks"`
+	SMTP            SMTPConfig    `json:"smtp"`
+	Webhook         WebhookConfig `json:"webhook"`
+	Log             LogSinkConfig `json:"log"`
+}
+
+// SMTPConfig specifies the mail server to send notices by email
+// No authentication is used if Username is left blank
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// WebhookConfig specifies the URL to post notices to as JSON
+// The body is signed using HMAC-SHA256 with Secret if not blank
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// LogSinkConfig specifies the file to append notices to as JSON lines
+// Notices are written to the server log if Path is left blank
+type LogSinkConfig struct {
+	Path string `json:"path"`
+}
+
+// SessionSecretEnv is the environment variable overriding the session secret
+const SessionSecretEnv = "REALMS_SESSION_SECRET"
+
+// AuthConfig specifies how users sign up and sign in
+type AuthConfig struct {
+	Registration RegistrationConfig `json:"registration"`
+	Session      SessionConfig      `json:"session"`
+	Lockout      LockoutConfig      `json:"lockout"`
+	Password     PasswordConfig     `json:"password"`
+}
+
+// RegistrationConfig specifies the self-service registration
+// Users are only allowed to register if Enabled is set, and the accounts
+// registered have to be approved by an admin before use if Approval is set
+type RegistrationConfig struct {
+	Enabled  bool `json:"enabled"`
+	Approval bool `json:"approval"`
+}
+
+// SessionConfig specifies the login sessions kept on the server
+// Secret is the key signing the session cookies, which is overridden by the
+// environment variable REALMS_SESSION_SECRET if set. A session expires when
+// idle for IdleMinutes, or AbsoluteMinutes after login, where 0 is unlimited
+type SessionConfig struct {
+	Secret          string `json:"secret"`
+	IdleMinutes     uint   `json:"idle_minutes"`
+	AbsoluteMinutes uint   `json:"absolute_minutes"`
+}
+
+// IdleTimeout returns how long a session may be idle
+func (cfg SessionConfig) IdleTimeout() time.Duration {
+	return time.Duration(cfg.IdleMinutes) * time.Minute
+}
+
+// AbsoluteTimeout returns how long a session may last since login
+func (cfg SessionConfig) AbsoluteTimeout() time.Duration {
+	return time.Duration(cfg.AbsoluteMinutes) * time.Minute
+}
+
+// LockoutConfig specifies how failed logins are throttled, counted for each
+// username and for each IP address
+// After a failed login, the username has to wait for BackoffSeconds, doubled
+// on each failure in a row, before trying again. The username or IP address is
+// locked out for LockoutMinutes after MaxFailures or MaxIPFailures failures,
+// where 0 disables the lockout. The failures are forgotten when none occurs
+// for LockoutMinutes, or on a successful login of the username
+type LockoutConfig struct {
+	MaxFailures    uint `json:"max_failures"`
+	MaxIPFailures  uint `json:"max_ip_failures"`
+	BackoffSeconds uint `json:"backoff_seconds"`
+	LockoutMinutes uint `json:"lockout_minutes"`
+}
+
+// DefaultLockoutConfig is used when the lockout is not configured
+var DefaultLockoutConfig = LockoutConfig{
+	MaxFailures:    5,
+	MaxIPFailures:  20,
+	BackoffSeconds: 1,
+	LockoutMinutes: 15,
+}
+
+// Backoff returns how long to wait after the first failure
+func (cfg LockoutConfig) Backoff() time.Duration {
+	return time.Duration(cfg.BackoffSeconds) * time.Second
+}
+
+// Lockout returns how long a username or IP address is locked out
+func (cfg LockoutConfig) Lockout() time.Duration {
+	return time.Duration(cfg.LockoutMinutes) * time.Minute
+}
+
+// PasswordConfig specifies the password policy, which is checked whenever a
+// password is set
+// A password has at least MinLength characters, of at least MinClasses kinds
+// among lowercase letters, uppercase letters, digits and symbols. It can't be
+// the username, or one of the common passwords in BannedFile, one per line,
+// compared regardless of case
+type PasswordConfig struct {
+	MinLength  uint   `json:"min_length"`
+	MinClasses uint   `json:"min_classes"`
+	BannedFile string `json:"banned_file"`
+
+	Banned map[string]bool `json:"-"`
+}
+
+// DefaultPasswordConfig is used when the password policy is not configured
+var DefaultPasswordConfig = PasswordConfig{
+	MinLength:  8,
+	MinClasses: 2,
 }
 
 // LoadDbConfig reads the database connection settings from the file
@@ -59,9 +219,75 @@ func LoadLibraryConfig(file string) (LibraryConfig, error) {
 		fmt.Println("[error] LoadLibraryConfig: invalid configuration.")
 		return cfg, err
 	}
+	if _, err := cfg.Location(); err != nil {
+		fmt.Println("[error] LoadLibraryConfig: invalid time zone.")
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// LoadNotifyConfig reads the notification settings from the file
+func LoadNotifyConfig(file string) (NotifyConfig, error) {
+	var cfg NotifyConfig
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println("[error] LoadNotifyConfig: unable to open the config file.")
+		return cfg, err
+	}
+	if err := json.Unmarshal(dat, &cfg); err != nil {
+		fmt.Println("[error] LoadNotifyConfig: invalid configuration.")
+		return cfg, err
+	}
 	return cfg, nil
 }
 
+// LoadAuthConfig reads the authentication settings from the file
+func LoadAuthConfig(file string) (AuthConfig, error) {
+	cfg := AuthConfig{
+		Lockout:  DefaultLockoutConfig,
+		Password: DefaultPasswordConfig,
+	}
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println("[error] LoadAuthConfig: unable to open the config file.")
+		return cfg, err
+	}
+	if err := json.Unmarshal(dat, &cfg); err != nil {
+		fmt.Println("[error] LoadAuthConfig: invalid configuration.")
+		return cfg, err
+	}
+	if secret := os.Getenv(SessionSecretEnv); secret != "" {
+		cfg.Session.Secret = secret
+	}
+	if cfg.Password.BannedFile != "" {
+		banned, err := loadBannedPasswords(cfg.Password.BannedFile)
+		if err != nil {
+			fmt.Println("[error] LoadAuthConfig: unable to open the banned password list.")
+			return cfg, err
+		}
+		cfg.Password.Banned = banned
+	}
+	return cfg, nil
+}
+
+// loadBannedPasswords reads the passwords banned from the file, one per line,
+// where blank lines and lines starting with # are skipped
+func loadBannedPasswords(file string) (map[string]bool, error) {
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	banned := make(map[string]bool)
+	for _, line := range strings.Split(string(dat), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned[strings.ToLower(line)] = true
+	}
+	return banned, nil
+}
+
 // LoadLogConfig reads the log settings from the file
 func LoadLogConfig(file string) (zap.Config, error) {
 	var cfg zap.Config
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/sessionstore"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrRegistrationClosed occurs when the self-service registration is disabled
+var ErrRegistrationClosed = errors.New("auth: registration closed, please ask an admin for an account")
+
+// RegisterInput is a schema that validates input to prevent invalid requests
+// Users registered have the user role, and are in the student category
+type RegisterInput struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+}
+
+// UpdateProfileInput is a schema that validates input to prevent invalid
+// requests
+// CurrentPassword is required to confirm the changes. The other fields are
+// left as is if not given, and DisplayName, Email and Phone are set to blank
+// to remove them
+type UpdateProfileInput struct {
+	CurrentPassword string  `json:"current_password" binding:"required"`
+	Password        string  `json:"password"`
+	DisplayName     *string `json:"display_name"`
+	Email           *string `json:"email"`
+	Phone           *string `json:"phone"`
+}
+
+// Register creates an account for a new user, which has to be approved by an
+// admin before use if required in the config
+// POST /register
+func Register(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	authcfg := c.MustGet("authcfg").(config.AuthConfig)
+
+	if !authcfg.Registration.Enabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrRegistrationClosed.Error()})
+		return
+	}
+
+	// Validates input
+	var input RegisterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user := models.User{
+		Username:    input.Username,
+		Password:    input.Password,
+		Role:        models.RoleUser,
+		Category:    models.PatronStudent,
+		Notify:      models.NotifyAll,
+		DisplayName: strings.TrimSpace(input.DisplayName),
+		Email:       strings.TrimSpace(input.Email),
+		Phone:       strings.TrimSpace(input.Phone),
+		State:       models.UserActive,
+	}
+	if authcfg.Registration.Approval {
+		user.State = models.UserPending
+	}
+	if err := user.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidatePassword(user.Password, user.Username, authcfg.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateEmail(user.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidatePhone(user.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUsernameExists.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	if user.IsPending() {
+		logger.Infof("Registered user %v, pending approval", user.ID)
+	} else {
+		logger.Infof("Registered user %v", user.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// UpdateProfile changes the password, display name or contact details of the
+// current logged-in user, after confirming the current password
+// Changing the password ends the other sessions, and revokes the API tokens
+// PATCH /user/me
+func UpdateProfile(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	authcfg := c.MustGet("authcfg").(config.AuthConfig)
+
+	var user models.User
+	if err := db.Where("id = ?", currentUserID(c)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+
+	// Validates input
+	var input UpdateProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Throttles guessing the current password like logins, counted for the
+	// username and the IP address as well
+	now := time.Now().Local()
+	ip := sessionstore.RemoteIP(c.Request)
+	if failure, blocked := loginBlocked(db, user.Username, ip, now); blocked {
+		tooManyLogins(c, user.Username, ip, failure, now)
+		return
+	}
+	if err := models.VerifyPassword(user.Password, input.CurrentPassword); err != nil {
+		if failure, blocked := failLogin(c, db, user.Username, ip, now); blocked {
+			c.Header("Retry-After", retryAfter(failure, now))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrAuthFailed.Error()})
+		return
+	}
+	if failure, blocked := loginBlocked(db, user.Username, ip, now); blocked {
+		tooManyLogins(c, user.Username, ip, failure, now)
+		return
+	}
+	db.Where("kind = ? AND target = ?", models.LoginFailureUsername, user.Username).Delete(models.LoginFailure{})
+
+	changes := make(map[string]interface{})
+	if input.Password != "" {
+		if err := models.ValidatePassword(input.Password, user.Username, authcfg.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := models.VerifyPassword(user.Password, input.Password); err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrPasswordUnchanged.Error()})
+			return
+		}
+		if err := models.EncryptPassword(&input.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changes["password"] = input.Password
+		changes["must_change_password"] = false
+	}
+	if input.DisplayName != nil {
+		changes["display_name"] = strings.TrimSpace(*input.DisplayName)
+	}
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+		if err := models.ValidateEmail(email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changes["email"] = email
+	}
+	if input.Phone != nil {
+		phone := strings.TrimSpace(*input.Phone)
+		if err := models.ValidatePhone(phone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changes["phone"] = phone
+	}
+
+	// Saves the fields changed only, where a new password revokes the API
+	// tokens along with it
+	passwordChanged := input.Password != ""
+	if len(changes) > 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).UpdateColumns(changes).Error; err != nil {
+				return err
+			}
+			if passwordChanged {
+				return tx.Where("user_id = ?", user.ID).Delete(&models.APIToken{}).Error
+			}
+			return nil
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Logs the user out everywhere else, so that the old password no longer
+	// works in the sessions already started with it
+	if passwordChanged {
+		if ok := revokeOtherSessions(c, user.ID); !ok {
+			return
+		}
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v updated the profile", user.ID)
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
@@ -3,14 +3,19 @@ package controllers
 import (
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/sessionstore"
 	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
 )
 
-const userkey = "user"
+// UserKey is the key of the ID of the logged-in user in the session
+const UserKey = "user"
 
 // ErrUnauthorized occurs when the user is unauthorized to perform the operation
 var ErrUnauthorized = errors.New("auth: unauthorized")
@@ -21,6 +26,27 @@ var ErrUserNotExist = errors.New("auth: user not exist")
 // ErrAuthFailed occurs when the password is incorrect
 var ErrAuthFailed = errors.New("auth: incorrect password")
 
+// ErrLoginFailed occurs when the username is not found or the password is
+// incorrect, which are not told apart so that usernames can't be guessed
+var ErrLoginFailed = errors.New("auth: incorrect username or password")
+
+// ErrTooManyLogins occurs when the username or the IP address has to wait
+// after failed logins
+var ErrTooManyLogins = errors.New("auth: too many failed logins, please try again later")
+
+// ErrPasswordChangeRequired occurs when the password has been reset by an
+// admin, and the user hasn't set a new one yet
+var ErrPasswordChangeRequired = errors.New("auth: password reset by an admin, please set a new one using passwd")
+
+// ErrAccountPending occurs when the user registered has not been approved yet
+var ErrAccountPending = errors.New("auth: account pending approval")
+
+// ErrAccountSuspended occurs when the user is suspended by an admin
+var ErrAccountSuspended = errors.New("auth: account suspended")
+
+// ErrAccountBanned occurs when the user is banned by an admin
+var ErrAccountBanned = errors.New("auth: account banned")
+
 // ErrAlreadyLoggedIn occurs when the user has already logged in
 var ErrAlreadyLoggedIn = errors.New("auth: already logged in")
 
@@ -30,50 +56,65 @@ var ErrSaveSessionFailed = errors.New("auth: failed to save session")
 // ErrInvalidSession occurs when the session token is not found or invalid
 var ErrInvalidSession = errors.New("auth: invalid session token, have you logged in?")
 
-// AuthRequired is a middleware that validates the session
+// ErrRevokeSessionsFailed occurs when failed to remove the sessions of a user
+var ErrRevokeSessionsFailed = errors.New("auth: failed to revoke sessions")
+
+// ErrInvalidToken occurs when the API token is not found, revoked or expired
+var ErrInvalidToken = errors.New("auth: invalid or expired token")
+
+// AuthRequired is a middleware that validates the session, or the API token
+// in the header Authorization: Bearer <token>, and checks if the account is
+// active
+// Users whose passwords have been reset are only allowed to set new ones
 // User privilege required
 func AuthRequired(c *gin.Context) {
-	session := sessions.Default(c)
-	uid := session.Get(userkey)
-	if uid == nil {
+	user, err := authenticate(c)
+	if err == nil && passwordChangeRequired(c, user) {
+		err = ErrPasswordChangeRequired
+	}
+	if err != nil {
 		// Aborts the request
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrUnauthorized.Error()})
+		c.AbortWithStatusJSON(authStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Next()
 }
 
-// AdminRequired is a middleware that validates the session
-// Admin privilege required
-func AdminRequired(c *gin.Context) {
-	session := sessions.Default(c)
-	uid := session.Get(userkey)
-	if uid == nil {
-		// Aborts the request
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrUnauthorized.Error()})
-		return
-	}
+// PermissionRequired returns a middleware that validates the session or the
+// API token, and checks if the role of the user grants the permission, within
+// the scopes of the token if any
+// The role is saved in the context for the handlers to check what the user is
+// allowed to grant
+func PermissionRequired(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := authenticate(c)
+		if err == nil && passwordChangeRequired(c, user) {
+			err = ErrPasswordChangeRequired
+		}
+		if err != nil {
+			// Aborts the request
+			c.AbortWithStatusJSON(authStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 
-	// Checks if the user has admin privilege
-	db := c.MustGet("db").(*gorm.DB)
-	var user models.User
-	if err := db.Where("id = ?", uid).First(&user).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrUserNotExist.Error()})
-		return
-	}
-	if !user.IsAdmin() {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrUnauthorized.Error()})
-		return
-	}
+		// Checks if the user holds the permission
+		db := c.MustGet("db").(*gorm.DB)
+		role := userRole(c, db, user)
+		if !role.Has(permission) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrUnauthorized.Error()})
+			return
+		}
 
-	c.Next()
+		c.Set("role", role)
+		c.Next()
+	}
 }
 
 // Login verifies user identity and saves the session token
 // POST /login
 func Login(c *gin.Context) {
 	session := sessions.Default(c)
-	if uid := session.Get(userkey); uid != nil {
+	if uid := session.Get(UserKey); uid != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": ErrAlreadyLoggedIn.Error()})
 		return
 	}
@@ -87,37 +128,78 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Verifies username and password
+	// Refuses logins during the backoff or lockout after failures
 	db := c.MustGet("db").(*gorm.DB)
+	now := time.Now().Local()
+	ip := sessionstore.RemoteIP(c.Request)
+	if failure, blocked := loginBlocked(db, username, ip, now); blocked {
+		tooManyLogins(c, username, ip, failure, now)
+		return
+	}
+
+	// Verifies username and password
 	var user models.User
 	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotExist.Error()})
+		models.VerifyDecoyPassword(password)
+		loginFailed(c, db, username, ip, now)
 		return
 	}
 	if err := models.VerifyPassword(user.Password, password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrAuthFailed.Error()})
+		loginFailed(c, db, username, ip, now)
+		return
+	}
+	// Checks again for the failures counted since, so that guesses in
+	// parallel can't get past the backoff
+	if failure, blocked := loginBlocked(db, username, ip, now); blocked {
+		tooManyLogins(c, username, ip, failure, now)
+		return
+	}
+	db.Where("kind = ? AND target = ?", models.LoginFailureUsername, username).Delete(models.LoginFailure{})
+	if err := accountError(user, now); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Saves the user ID in the session
-	session.Set(userkey, user.ID)
+	session.Set(UserKey, user.ID)
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSaveSessionFailed.Error()})
 		return
 	}
 
+	if user.MustChangePassword {
+		c.JSON(http.StatusOK, gin.H{"data": true, "must_change_password": true})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
+// loginFailed counts the failed login, and tells when to try again if logins
+// are refused from now on
+func loginFailed(c *gin.Context, db *gorm.DB, username, ip string, now time.Time) {
+	if failure, blocked := failLogin(c, db, username, ip, now); blocked {
+		c.Header("Retry-After", retryAfter(failure, now))
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": ErrLoginFailed.Error()})
+}
+
+// tooManyLogins refuses the login during the backoff or lockout
+func tooManyLogins(c *gin.Context, username, ip string, failure models.LoginFailure, now time.Time) {
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Warnf("Refused login of %q from %v, %v %q blocked", username, ip, failure.Kind, failure.Target)
+	c.Header("Retry-After", retryAfter(failure, now))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": ErrTooManyLogins.Error()})
+}
+
 // Logout removes the session token
 // GET /logout
 func Logout(c *gin.Context) {
 	session := sessions.Default(c)
-	if uid := session.Get(userkey); uid == nil {
+	if uid := session.Get(UserKey); uid == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidSession.Error()})
 		return
 	}
-	session.Delete(userkey)
+	session.Delete(UserKey)
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSaveSessionFailed.Error()})
 		return
@@ -125,21 +207,196 @@ func Logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
-// Me shows the current logged-in user
+var sessionListQuery = listQuery{
+	sortKeys:     []string{"id", "ip", "created_at", "last_seen_at"},
+	defaultSort:  "last_seen_at",
+	defaultOrder: "desc",
+}
+
+// ShowSessions shows all sessions of the user, the latest first
+// GET /user/sessions
+func ShowSessions(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db.Where("user_id = ?", currentUserID(c))
+	chain, paging, ok := paginate(c, chain, &models.Session{}, sessionListQuery)
+	if !ok {
+		return
+	}
+	var found []models.Session
+	if err := chain.Find(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, found, paging)
+}
+
+// LogoutAll logs the user out everywhere, including the current session, and
+// returns the number of sessions ended
+// DELETE /user/sessions
+func LogoutAll(c *gin.Context) {
+	store := c.MustGet("sessions").(*sessionstore.Store)
+	userID := currentUserID(c)
+	count, err := store.RevokeUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrRevokeSessionsFailed.Error()})
+		return
+	}
+
+	// Expires the cookie of the current session
+	session := sessions.Default(c)
+	session.Delete(UserKey)
+	if err := session.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSaveSessionFailed.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v logged out of %v sessions", userID, count)
+
+	c.JSON(http.StatusOK, gin.H{"data": count})
+}
+
+// Me shows the current logged-in user, along with the number of books the
+// user has borrowed and may borrow in the quota field, and the role of the
+// user in the role field
 // GET /user/me
 func Me(c *gin.Context) {
-	session := sessions.Default(c)
-	uid := session.Get(userkey)
-	c.JSON(http.StatusOK, gin.H{"data": uid})
+	db := c.MustGet("db").(*gorm.DB)
+
+	userID := currentUserID(c)
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+	quota, err := circulation(c).Quota(userID)
+	if err != nil {
+		circulationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": userID, "quota": quota, "role": userRole(c, db, user)})
 }
 
 // Status shows the current login status
 // GET /status
 func Status(c *gin.Context) {
-	session := sessions.Default(c)
-	if uid := session.Get(userkey); uid == nil {
+	if _, err := authenticate(c); err != nil {
 		c.JSON(http.StatusOK, gin.H{"data": false})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
+
+// authenticate finds the current user by the API token in the header if any,
+// or by the session otherwise, and checks if the account is active
+// The user of a valid token is set in the session without saving it, so that
+// the handlers find the user as if logged in, and the token is saved in the
+// context
+func authenticate(c *gin.Context) (models.User, error) {
+	db := c.MustGet("db").(*gorm.DB)
+	session := sessions.Default(c)
+	now := time.Now().Local()
+
+	var user models.User
+	uid, ok := session.Get(UserKey).(uint)
+	if header := c.GetHeader("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return user, ErrInvalidToken
+		}
+		var token models.APIToken
+		hash := models.HashToken(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+		if err := db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+			return user, ErrInvalidToken
+		}
+		if token.Expired(now) {
+			return user, ErrInvalidToken
+		}
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= time.Minute {
+			db.Model(&token).UpdateColumn("last_used_at", now)
+		}
+		session.Set(UserKey, token.UserID)
+		c.Set("token", token)
+		uid, ok = token.UserID, true
+	}
+	if !ok {
+		return user, ErrUnauthorized
+	}
+
+	if err := db.Where("id = ?", uid).First(&user).Error; err != nil {
+		return user, ErrUserNotExist
+	}
+	return user, accountError(user, now)
+}
+
+// accountError returns why the user is unable to log in at the time, or nil
+// if the account is active
+func accountError(user models.User, now time.Time) error {
+	switch user.AccountState(now) {
+	case models.UserPending:
+		return ErrAccountPending
+	case models.UserSuspended:
+		return ErrAccountSuspended
+	case models.UserBanned:
+		return ErrAccountBanned
+	}
+	return nil
+}
+
+// passwordChangeRequired checks if the user has to set a new password before
+// the request, which is only allowed to /user/me for the change
+func passwordChangeRequired(c *gin.Context, user models.User) bool {
+	return user.MustChangePassword && c.FullPath() != "/user/me"
+}
+
+// authStatus returns the status of the error from authenticate, which is
+// forbidden if the account isn't active or the password has to be changed,
+// and unauthorized otherwise
+func authStatus(err error) int {
+	switch err {
+	case ErrAccountPending, ErrAccountSuspended, ErrAccountBanned, ErrPasswordChangeRequired:
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// userRole finds the role of the user, whose permissions are restricted to
+// the scopes of the API token used if any
+func userRole(c *gin.Context, db *gorm.DB, user models.User) models.Role {
+	role := findRole(db, user.RoleName())
+	if token, ok := c.Get("token"); ok {
+		role = token.(models.APIToken).Restrict(role)
+	}
+	return role
+}
+
+// revokeSessions logs the user out everywhere, e.g. when removed, and sends
+// an error if failed
+func revokeSessions(c *gin.Context, userID uint) bool {
+	store := c.MustGet("sessions").(*sessionstore.Store)
+	if _, err := store.RevokeUser(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrRevokeSessionsFailed.Error()})
+		return false
+	}
+	return true
+}
+
+// revokeOtherSessions logs the user out everywhere except the current session,
+// which is none if authenticated by an API token, and sends an error if failed
+func revokeOtherSessions(c *gin.Context, userID uint) bool {
+	store := c.MustGet("sessions").(*sessionstore.Store)
+	current, _ := sessions.Default(c).Get(sessionstore.IDKey).(uint)
+	if _, err := store.RevokeOthers(userID, current); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrRevokeSessionsFailed.Error()})
+		return false
+	}
+	return true
+}
+
+// currentUserID gets the ID of the logged-in user from the session
+func currentUserID(c *gin.Context) uint {
+	session := sessions.Default(c)
+	userID, _ := session.Get(UserKey).(uint)
+	return userID
+}
@@ -3,32 +3,63 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/service"
 	"github.com/jinzhu/gorm"
 	"go.uber.org/zap"
 )
 
 // ErrBookNotFound occurs when the queried book is not found
-var ErrBookNotFound = errors.New("database: book not found")
+var ErrBookNotFound = service.ErrBookNotFound
+
+// ErrBookExists occurs when a book of the same ISBN and edition already exists
+var ErrBookExists = errors.New("database: book of the same ISBN and edition already exists")
 
 // AddBookInput is a schema that validates input to prevent invalid requests
 // ID will be generated automatically
+// Author is a list of names separated by ";" or ",", which is only used if
+// Authors is left blank
+// The first copy of the book is added as well, labeled by Barcode, or by a
+// generated one if not specified.
+// If a book of the same ISBN and edition already exists, the request is
+// rejected unless AdditionalCopy is set, in which case only the copy is added
+// to the existing book
 type AddBookInput struct {
-	Title     string `json:"title" binding:"required"`
-	Author    string `json:"author"`
-	Publisher string `json:"publisher"`
-	ISBN      string `json:"isbn"`
+	Title     string   `json:"title" binding:"required"`
+	Author    string   `json:"author"`
+	Authors   []string `json:"authors"`
+	Publisher string   `json:"publisher"`
+	ISBN      string   `json:"isbn"`
+	Year      uint     `json:"year"`
+	Edition   string   `json:"edition"`
+	Language  string   `json:"language"`
+	Pages     uint     `json:"pages"`
+	Series    string   `json:"series"`
+	Volume    uint     `json:"volume"`
+	Subjects  []string `json:"subjects"`
+
+	AdditionalCopy bool   `json:"additional_copy"`
+	Barcode        string `json:"barcode"`
+	Location       string `json:"location"`
 }
 
 // UpdateBookInput is a schema that validates input to prevent invalid requests
+// Authors and Subjects are replaced if specified, and cleared if empty
 type UpdateBookInput struct {
-	Title     string `json:"title"`
-	Author    string `json:"author"`
-	Publisher string `json:"publisher"`
-	ISBN      string `json:"isbn"`
+	Title     string   `json:"title"`
+	Author    string   `json:"author"`
+	Authors   []string `json:"authors" gorm:"-"`
+	Publisher string   `json:"publisher"`
+	ISBN      string   `json:"isbn"`
+	Year      uint     `json:"year"`
+	Edition   string   `json:"edition"`
+	Language  string   `json:"language"`
+	Pages     uint     `json:"pages"`
+	Series    string   `json:"series"`
+	Volume    uint     `json:"volume"`
+	Subjects  []string `json:"subjects" gorm:"-"`
 }
 
 // RemoveBookInput is a schema that validates input to prevent invalid requests
@@ -38,9 +69,14 @@ type RemoveBookInput struct {
 
 // FindBookInput is a schema that validates input to prevent invalid requests
 type FindBookInput struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
-	ISBN   string `json:"isbn"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Publisher string `json:"publisher"`
+	ISBN      string `json:"isbn"`
+	Subject   string `json:"subject"`
+	Series    string `json:"series"`
+	Language  string `json:"language"`
+	Year      uint   `json:"year"`
 }
 
 // AddBook adds a new book to the library
@@ -55,18 +91,63 @@ func AddBook(c *gin.Context) {
 		return
 	}
 
-	book := models.Book{
-		Title:     input.Title,
-		Author:    input.Author,
-		Publisher: input.Publisher,
-		ISBN:      input.ISBN,
+	ISBN, err := models.NormalizeISBN(input.ISBN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	copyInput := AddCopyInput{Barcode: input.Barcode, Location: input.Location}
+	if input.Barcode != "" && barcodeExists(db, input.Barcode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBarcodeExists.Error()})
+		return
 	}
-	db.Create(&book)
 
-	logger := c.MustGet("logger").(*zap.SugaredLogger)
-	logger.Infof("Added book %v", book.ID)
+	// Adds a copy to the existing book if flagged
+	if book, ok := findDuplicate(db, ISBN, input.Edition, 0); ok {
+		if !input.AdditionalCopy {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookExists.Error(), "book_id": book.ID})
+			return
+		}
+		if input.Barcode == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrBarcodeRequired.Error()})
+			return
+		}
+		item, err := addCopy(c, book.ID, copyInput)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		db.Preload("Authors").Preload("Subjects").First(&book, book.ID)
+		c.JSON(http.StatusOK, gin.H{"data": book, "copy": item})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": book})
+	// Adds the book along with its first copy in a transaction, so that a book
+	// can always be borrowed once added
+	var book models.Book
+	var item models.Copy
+	var copyErr error
+	err = inTransaction(c, func(c *gin.Context) error {
+		var err error
+		if book, err = createBook(c, input, ISBN); err != nil {
+			return err
+		}
+		if copyInput.Barcode == "" {
+			copyInput.Barcode = defaultBarcode(c.MustGet("db").(*gorm.DB), book.ID)
+		}
+		item, copyErr = addCopy(c, book.ID, copyInput)
+		return copyErr
+	})
+	if copyErr != nil {
+		unindexBook(c, book.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": copyErr.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": book, "copy": item})
 }
 
 // UpdateBook updates data of a book
@@ -88,8 +169,38 @@ func UpdateBook(c *gin.Context) {
 		return
 	}
 
+	if input.ISBN != "" {
+		ISBN, err := models.NormalizeISBN(input.ISBN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.ISBN = ISBN
+	}
+	ISBN, edition := book.ISBN, book.Edition
+	if input.ISBN != "" {
+		ISBN = input.ISBN
+	}
+	if input.Edition != "" {
+		edition = input.Edition
+	}
+	if _, ok := findDuplicate(db, ISBN, edition, book.ID); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookExists.Error()})
+		return
+	}
+
 	db.Model(&book).Updates(input)
 
+	authors := input.Authors
+	if authors == nil && input.Author != "" {
+		authors = models.SplitNames(input.Author)
+	}
+	if err := setCatalogData(db, &book, authors, input.Subjects); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	indexBook(c, book)
+
 	logger := c.MustGet("logger").(*zap.SugaredLogger)
 	logger.Infof("Updated book %v", bookID)
 
@@ -108,6 +219,19 @@ func RemoveBook(c *gin.Context) {
 		return
 	}
 
+	// Checks if any copy of the book is on loan or on hold
+	var count uint
+	db.Model(&models.Copy{}).Where("book_id = ? AND status = ?", bookID, models.CopyOnLoan).Count(&count)
+	if count != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnLoan.Error()})
+		return
+	}
+	db.Model(&models.Copy{}).Where("book_id = ? AND status = ?", bookID, models.CopyOnHold).Count(&count)
+	if count != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnHold.Error()})
+		return
+	}
+
 	// Validates input
 	var input RemoveBookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -115,7 +239,15 @@ func RemoveBook(c *gin.Context) {
 		return
 	}
 
+	// Retires all copies of the book and cancels the holds on it as well
+	db.Where("book_id = ?", bookID).Delete(&models.Copy{})
+	db.Model(&models.Hold{}).
+		Where("book_id = ? AND status = ?", bookID, models.HoldWaiting).
+		Update("status", models.HoldCancelled)
+	db.Model(&book).Association("Authors").Clear()
+	db.Model(&book).Association("Subjects").Clear()
 	db.Delete(&book)
+	unindexBook(c, book.ID)
 
 	logger := c.MustGet("logger").(*zap.SugaredLogger)
 	if input.Message == "" {
@@ -127,24 +259,41 @@ func RemoveBook(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
+var bookListQuery = listQuery{
+	sortKeys:     []string{"id", "title", "author", "publisher", "isbn", "year", "language", "series"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
 // ShowBooks shows all books in the library
 // GET /books
 func ShowBooks(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
+	chain, paging, ok := paginate(c, db, &models.Book{}, bookListQuery)
+	if !ok {
+		return
+	}
 	var books []models.Book
-	db.Find(&books)
+	chain.Preload("Authors").Preload("Subjects").Find(&books)
 
-	c.JSON(http.StatusOK, gin.H{"data": books})
+	respondList(c, books, paging)
 }
 
 // ShowBook shows the book of given ID
+// GET /books/search is served here as well, since gin doesn't allow it next
+// to the wildcard, see SearchBooks
 // GET /books/:id
 func ShowBook(c *gin.Context) {
+	if c.Param("id") == "search" {
+		SearchBooks(c)
+		return
+	}
+
 	db := c.MustGet("db").(*gorm.DB)
 
 	var book models.Book
-	if err := db.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
+	if err := db.Preload("Authors").Preload("Subjects").Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotFound.Error()})
 		return
 	}
@@ -153,10 +302,9 @@ func ShowBook(c *gin.Context) {
 }
 
 // FindBooks finds books by title / author / ISBN
+// It's kept for compatibility, and runs a search query with the fields
 // POST /books/find
 func FindBooks(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-
 	// Validates input
 	var input FindBookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -164,20 +312,73 @@ func FindBooks(c *gin.Context) {
 		return
 	}
 
-	var books []models.Book
-	chain := db
-	if ISBN := input.ISBN; ISBN != "" {
-		chain = chain.Where("ISBN = ?", ISBN)
+	query := findBooksQuery(input)
+	if query == "" {
+		ShowBooks(c)
+		return
+	}
+	searchBooks(c, query)
+}
+
+// createBook adds a new book of the normalized ISBN to the library, and to
+// the search index
+func createBook(c *gin.Context, input AddBookInput, ISBN string) (models.Book, error) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	book := models.Book{
+		Title:     input.Title,
+		Publisher: input.Publisher,
+		ISBN:      ISBN,
+		Year:      input.Year,
+		Edition:   input.Edition,
+		Language:  input.Language,
+		Pages:     input.Pages,
+		Series:    input.Series,
+		Volume:    input.Volume,
+	}
+	if err := db.Create(&book).Error; err != nil {
+		return book, err
 	}
-	if author := input.Author; author != "" {
-		query := strings.ReplaceAll(author, " ", "%")
-		chain = chain.Where("author LIKE ?", "%"+query+"%")
+
+	authors := input.Authors
+	if authors == nil {
+		authors = models.SplitNames(input.Author)
 	}
-	if title := input.Title; title != "" {
-		query := strings.ReplaceAll(title, " ", "%")
-		chain = chain.Where("title LIKE ?", "%"+query+"%")
+	if err := setCatalogData(db, &book, authors, input.Subjects); err != nil {
+		return book, err
 	}
-	chain.Find(&books)
+	indexBook(c, book)
 
-	c.JSON(http.StatusOK, gin.H{"data": books})
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Added book %v", book.ID)
+
+	return book, nil
+}
+
+// setCatalogData replaces the authors and subjects of the book if specified,
+// and reloads them
+func setCatalogData(db *gorm.DB, book *models.Book, authors, subjects []string) error {
+	if authors != nil {
+		if err := book.SetAuthors(db, authors); err != nil {
+			return err
+		}
+	}
+	if subjects != nil {
+		if err := book.SetSubjects(db, subjects); err != nil {
+			return err
+		}
+	}
+	return db.Preload("Authors").Preload("Subjects").First(book, book.ID).Error
+}
+
+// findDuplicate finds another book of the same ISBN and edition
+// Books without an ISBN are never duplicates
+func findDuplicate(db *gorm.DB, ISBN, edition string, excludeID uint) (models.Book, bool) {
+	var book models.Book
+	if ISBN == "" {
+		return book, false
+	}
+	err := db.Where("isbn = ? AND edition = ? AND id <> ?", ISBN, edition, excludeID).
+		First(&book).Error
+	return book, err == nil
 }
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/calendar"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrClosedDayExists occurs when the library is already closed on the date
+var ErrClosedDayExists = errors.New("database: closed day already exists")
+
+// ErrClosedDayNotFound occurs when the library is not closed on the date
+var ErrClosedDayNotFound = errors.New("database: closed day not found")
+
+var closedDaySortKeys = []string{"id", "date", "name"}
+
+// SetOpeningHoursInput is a schema that validates input to prevent invalid
+// requests, where the weekday is given in the path
+type SetOpeningHoursInput struct {
+	Opens  string `json:"opens"`
+	Closes string `json:"closes"`
+	Closed bool   `json:"closed"`
+}
+
+// AddClosedDayInput is a schema that validates input to prevent invalid
+// requests
+type AddClosedDayInput struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name"`
+}
+
+// CalendarImportError is an event in the iCalendar file which failed to be
+// imported
+type CalendarImportError struct {
+	Line    int    `json:"line"`
+	Summary string `json:"summary,omitempty"`
+	Error   string `json:"error"`
+}
+
+// CalendarImportReport is the summary of an iCalendar import
+// Added is the number of closed days added, or to be added in a dry run, and
+// Skipped is the number of days already closed
+type CalendarImportReport struct {
+	DryRun  bool                  `json:"dry_run"`
+	Events  uint                  `json:"events"`
+	Added   uint                  `json:"added"`
+	Skipped uint                  `json:"skipped"`
+	Failed  uint                  `json:"failed"`
+	Errors  []CalendarImportError `json:"errors"`
+}
+
+// ShowOpeningHours shows the opening hours of all weekdays, where 0 is Sunday
+// GET /admin/calendar/hours
+func ShowOpeningHours(c *gin.Context) {
+	hours, err := repository.NewGorm(c.MustGet("db").(*gorm.DB)).OpeningHours()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": hours})
+}
+
+// SetOpeningHours sets the opening hours of a weekday, where 0 is Sunday
+// PUT /admin/calendar/hours/:weekday
+func SetOpeningHours(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Validates input
+	weekday, err := strconv.ParseUint(c.Param("weekday"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidWeekday.Error()})
+		return
+	}
+	var input SetOpeningHoursInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hours := models.OpeningHours{
+		Weekday: uint(weekday),
+		Opens:   strings.TrimSpace(input.Opens),
+		Closes:  strings.TrimSpace(input.Closes),
+		Closed:  input.Closed,
+	}
+	if err := hours.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repository.NewGorm(db).SetOpeningHours(&hours); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Set the opening hours of weekday %v", hours.Weekday)
+
+	c.JSON(http.StatusOK, gin.H{"data": hours})
+}
+
+// ShowClosedDays shows the closed days of the library, which can be filtered
+// by a range of dates using the query string, both ends included
+// GET /admin/calendar/closed?from=:from&to=:to
+func ShowClosedDays(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db
+	if from := c.Query("from"); from != "" {
+		if err := (&models.ClosedDay{Date: from}).Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDate.Error()})
+			return
+		}
+		chain = chain.Where("date >= ?", from)
+	}
+	if to := c.Query("to"); to != "" {
+		if err := (&models.ClosedDay{Date: to}).Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDate.Error()})
+			return
+		}
+		chain = chain.Where("date <= ?", to)
+	}
+
+	chain, paging, ok := paginate(c, chain, &models.ClosedDay{}, listQuery{closedDaySortKeys, "date", "asc"})
+	if !ok {
+		return
+	}
+	var days []models.ClosedDay
+	chain.Find(&days)
+
+	respondList(c, days, paging)
+}
+
+// AddClosedDay closes the library on a date
+// POST /admin/calendar/closed
+func AddClosedDay(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Validates input
+	var input AddClosedDayInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	day := models.ClosedDay{
+		Date: strings.TrimSpace(input.Date),
+		Name: strings.TrimSpace(input.Name),
+	}
+	if err := day.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if closedDayExists(db, day.Date) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrClosedDayExists.Error()})
+		return
+	}
+
+	if err := repository.NewGorm(db).CreateClosedDay(&day); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Closed the library on %v", day.Date)
+
+	c.JSON(http.StatusOK, gin.H{"data": day.ID})
+}
+
+// RemoveClosedDay opens the library on a date closed before
+// Loans already due on the date are not changed
+// DELETE /admin/calendar/closed/:date
+func RemoveClosedDay(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	date := c.Param("date")
+	chain := db.Where("date = ?", date).Delete(&models.ClosedDay{})
+	if chain.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": chain.Error.Error()})
+		return
+	}
+	if chain.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrClosedDayNotFound.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Opened the library on %v", date)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
+
+// ImportCalendar closes the library on the days of the events in an
+// iCalendar file, e.g. the public holidays published by the government
+// The file is either uploaded as the request body, or as the file field of a
+// multipart form. Days already closed are skipped, and recurring or malformed
+// events are reported. Nothing is written in a dry run
+// POST /admin/calendar/import?dry_run=:dry_run
+func ImportCalendar(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	libcfg := c.MustGet("libcfg").(config.LibraryConfig)
+
+	// Opens the uploaded file, limiting the whole request body like ImportBooks
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var file multipart.File
+		if file, err = header.Open(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	loc, err := libcfg.Location()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	reader := calendar.NewICalReader(body, loc)
+
+	report := CalendarImportReport{DryRun: c.Query("dry_run") == "true", Errors: []CalendarImportError{}}
+	// Dates in the file so far, to skip the days of overlapping events
+	dates := make(map[string]bool)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		repo := repository.NewGorm(tx)
+		for {
+			event, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			var eventErr *calendar.EventError
+			if errors.As(err, &eventErr) {
+				report.Events++
+				report.Failed++
+				report.Errors = append(report.Errors, CalendarImportError{
+					Line:    eventErr.Line,
+					Summary: eventErr.Summary,
+					Error:   eventErr.Err.Error(),
+				})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			report.Events++
+
+			name := event.Summary
+			if name == "" {
+				name = "Closed"
+			}
+			for _, date := range event.Dates() {
+				if dates[date] || closedDayExists(tx, date) {
+					report.Skipped++
+					continue
+				}
+				dates[date] = true
+				if !report.DryRun {
+					day := models.ClosedDay{Date: date, Name: name, UID: event.UID}
+					if err := repo.CreateClosedDay(&day); err != nil {
+						return err
+					}
+				}
+				report.Added++
+			}
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	if !report.DryRun {
+		logger.Infof("Imported %v closed days from %v events", report.Added, report.Events)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// closedDayExists checks if the library is closed on the date
+func closedDayExists(db *gorm.DB, date string) bool {
+	var count uint
+	db.Model(&models.ClosedDay{}).Where("date = ?", date).Count(&count)
+	return count > 0
+}
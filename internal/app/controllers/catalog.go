@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/catalog"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// maxImportSize is the maximum size of an uploaded catalog file
+const maxImportSize = 32 << 20
+
+// exportBatchSize is the number of books loaded at a time when exporting
+const exportBatchSize = 1000
+
+// ErrTitleRequired occurs when an imported book has no title
+var ErrTitleRequired = errors.New("validate: title required")
+
+// ImportError is a row in the catalog file which failed to be imported
+type ImportError struct {
+	Row   int    `json:"row"`
+	Title string `json:"title,omitempty"`
+	ISBN  string `json:"isbn,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport is the summary of an import
+// Added and Copies are the numbers of books and copies added, or to be added
+// in a dry run
+type ImportReport struct {
+	DryRun bool          `json:"dry_run"`
+	Total  uint          `json:"total"`
+	Added  uint          `json:"added"`
+	Copies uint          `json:"copies"`
+	Failed uint          `json:"failed"`
+	Errors []ImportError `json:"errors"`
+}
+
+// ImportBooks adds books in a catalog file to the library
+// The file is either uploaded as the request body, or as the file field of a
+// multipart form. The format is guessed by the file name if not specified
+// Invalid rows and duplicates are reported and skipped, while the others are
+// imported. Nothing is written in a dry run
+// Served through the wildcard of /admin/books/:id, since gin doesn't allow
+// the path next to it
+// POST /admin/books/import?format=:format&dry_run=:dry_run
+func ImportBooks(c *gin.Context) {
+	if c.Param("id") != "import" {
+		routeNotFound(c)
+		return
+	}
+
+	// Opens the uploaded file, where the limit applies to the whole request
+	// body, so that a multipart form is limited before being parsed as well
+	format := strings.ToLower(c.Query("format"))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var file multipart.File
+		if file, err = header.Open(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format, _ = catalog.FormatOf(header.Filename)
+		}
+	}
+	reader, err := catalog.NewReader(format, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := ImportReport{DryRun: c.Query("dry_run") == "true", Errors: []ImportError{}}
+	// Books and barcodes in the file so far, to detect duplicates in the file
+	books := make(map[string]uint)
+	barcodes := make(map[string]bool)
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *catalog.RowError
+		if errors.As(err, &rowErr) {
+			report.Total++
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Row: row, Error: rowErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+			return
+		}
+		report.Total++
+
+		if err := importRecord(c, record, &report, books, barcodes); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{
+				Row:   row,
+				Title: record.Title,
+				ISBN:  record.ISBN,
+				Error: err.Error(),
+			})
+		}
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	if !report.DryRun {
+		logger.Infof("Imported %v books and %v copies from a %v file", report.Added, report.Copies, format)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// importRecord validates a record, and adds it as a new book or a copy of an
+// existing book if not in a dry run
+func importRecord(
+	c *gin.Context,
+	record catalog.Record,
+	report *ImportReport,
+	books map[string]uint,
+	barcodes map[string]bool,
+) error {
+	db := c.MustGet("db").(*gorm.DB)
+
+	if strings.TrimSpace(record.Title) == "" {
+		return ErrTitleRequired
+	}
+	ISBN, err := models.NormalizeISBN(record.ISBN)
+	if err != nil {
+		return err
+	}
+	barcode := strings.TrimSpace(record.Barcode)
+	if barcode != "" && (barcodes[barcode] || barcodeExists(db, barcode)) {
+		return ErrBarcodeExists
+	}
+
+	// Finds a book of the same ISBN and edition in the database or the file
+	key := ISBN + "\x00" + record.Edition
+	bookID, duplicate := books[key]
+	if !duplicate {
+		if book, ok := findDuplicate(db, ISBN, record.Edition, 0); ok {
+			bookID, duplicate = book.ID, true
+		}
+	}
+	if duplicate {
+		if !record.AdditionalCopy {
+			return ErrBookExists
+		}
+		if barcode == "" {
+			return models.ErrBarcodeRequired
+		}
+	}
+
+	if barcode != "" {
+		barcodes[barcode] = true
+	}
+	if report.DryRun {
+		if !duplicate {
+			report.Added++
+			if ISBN != "" {
+				books[key] = 0
+			}
+		}
+		report.Copies++
+		return nil
+	}
+
+	// Adds the book along with its copy in a transaction, so that a row is
+	// either imported as a whole or not at all. A new book without a barcode is
+	// given a copy labeled by a generated one like AddBook
+	var added uint
+	err = inTransaction(c, func(c *gin.Context) error {
+		if !duplicate {
+			book, err := createBook(c, AddBookInput{
+				Title:     record.Title,
+				Authors:   record.Authors,
+				Publisher: record.Publisher,
+				Year:      record.Year,
+				Edition:   record.Edition,
+				Language:  record.Language,
+				Pages:     record.Pages,
+				Series:    record.Series,
+				Volume:    record.Volume,
+				Subjects:  record.Subjects,
+			}, ISBN)
+			if err != nil {
+				return err
+			}
+			added = book.ID
+			bookID = book.ID
+		}
+		if barcode == "" {
+			barcode = defaultBarcode(c.MustGet("db").(*gorm.DB), bookID)
+		}
+		_, err := addCopy(c, bookID, AddCopyInput{Barcode: barcode, Location: record.Location})
+		return err
+	})
+	if err != nil {
+		if added != 0 {
+			unindexBook(c, added)
+		}
+		return err
+	}
+
+	if !duplicate {
+		report.Added++
+		if ISBN != "" {
+			books[key] = bookID
+		}
+	}
+	report.Copies++
+	return nil
+}
+
+// ExportBooks streams all books in the library as a catalog file
+// Served through the wildcard of /admin/books/:id like ImportBooks
+// GET /admin/books/export?format=:format
+func ExportBooks(c *gin.Context) {
+	if c.Param("id") != "export" {
+		routeNotFound(c)
+		return
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	format := strings.ToLower(c.DefaultQuery("format", catalog.FormatCSV))
+	if err := catalog.ValidateFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", catalog.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="catalog.`+format+`"`)
+	c.Status(http.StatusOK)
+	writer, err := catalog.NewWriter(format, c.Writer)
+	if err != nil {
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	total := 0
+	for offset := 0; ; offset += exportBatchSize {
+		var books []models.Book
+		db.Preload("Authors").Preload("Subjects").Order("id").
+			Limit(exportBatchSize).Offset(offset).Find(&books)
+		for _, book := range books {
+			if err := writer.Write(bookRecord(book)); err != nil {
+				logger.Errorf("Failed to export book %v: %v", book.ID, err)
+				return
+			}
+		}
+		total += len(books)
+		c.Writer.Flush()
+		if len(books) < exportBatchSize {
+			break
+		}
+	}
+	if err := writer.Close(); err != nil {
+		logger.Errorf("Failed to export books: %v", err)
+		return
+	}
+
+	logger.Infof("Exported %v books as %v", total, format)
+}
+
+// bookRecord converts a book to a record in the catalog file, where the
+// authors are sorted as in the byline
+func bookRecord(book models.Book) catalog.Record {
+	authors := make([]string, len(book.Authors))
+	for i, author := range book.Authors {
+		authors[i] = author.Name
+	}
+	sort.SliceStable(authors, func(i, j int) bool {
+		return strings.Index(book.Author, authors[i]) < strings.Index(book.Author, authors[j])
+	})
+	return catalog.Record{
+		Title:     book.Title,
+		Authors:   authors,
+		Publisher: book.Publisher,
+		ISBN:      book.ISBN,
+		Year:      book.Year,
+		Edition:   book.Edition,
+		Language:  book.Language,
+		Pages:     book.Pages,
+		Series:    book.Series,
+		Volume:    book.Volume,
+		Subjects:  book.SubjectNames(),
+	}
+}
+
+// routeNotFound responds as gin does to an unknown route, for the paths served
+// through a wildcard, e.g. /admin/books/:id
+func routeNotFound(c *gin.Context) {
+	c.String(http.StatusNotFound, "404 page not found")
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"github.com/hakula139/REALMS/internal/app/service"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrBookOrBarcodeRequired occurs when neither the book ID nor the barcode is
+// specified
+var ErrBookOrBarcodeRequired = errors.New("validate: book ID or barcode required")
+
+// CirculationInput is a schema that validates input to prevent invalid requests
+// Either BookID or Barcode is required to specify the book, and the barcode
+// takes precedence if both are given
+// UserID is required unless the book is specified by the barcode of a copy
+// on loan
+type CirculationInput struct {
+	UserID  uint   `json:"user_id"`
+	BookID  uint   `json:"book_id"`
+	Barcode string `json:"barcode"`
+}
+
+// CheckOut lends a book to a user at the circulation desk
+// POST /admin/circulation/checkout
+func CheckOut(c *gin.Context) {
+	// Validates input
+	var input CirculationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Barcode = strings.TrimSpace(input.Barcode)
+	if input.BookID == 0 && input.Barcode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookOrBarcodeRequired.Error()})
+		return
+	}
+
+	// Checks if the user exists
+	svc := circulation(c)
+	user, err := svc.Users.FindUser(input.UserID)
+	if err != nil {
+		circulationError(c, notFound(err, ErrUserNotFound))
+		return
+	}
+
+	// Gets book ID from the barcode
+	bookID := input.BookID
+	if input.Barcode != "" {
+		item, err := svc.Books.FindCopyByBarcode(input.Barcode)
+		if err != nil {
+			circulationError(c, notFound(err, ErrCopyNotFound))
+			return
+		}
+		bookID = item.BookID
+	}
+
+	record, err := svc.Lend(user.ID, bookID, input.Barcode, time.Time{}, currentUserID(c))
+	if err != nil {
+		circulationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": record})
+}
+
+// Renew extends the deadline to return a book at the circulation desk
+// POST /admin/circulation/renew
+func Renew(c *gin.Context) {
+	record, ok := findLoan(c)
+	if !ok {
+		return
+	}
+
+	if err := circulation(c).Renew(&record, currentUserID(c)); err != nil {
+		circulationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": record})
+}
+
+// CheckIn returns a book to the library at the circulation desk
+// POST /admin/circulation/checkin
+func CheckIn(c *gin.Context) {
+	record, ok := findLoan(c)
+	if !ok {
+		return
+	}
+
+	fine, err := circulation(c).Return(&record, currentUserID(c))
+	if err != nil {
+		circulationError(c, err)
+		return
+	}
+	if fine != nil {
+		c.JSON(http.StatusOK, gin.H{"data": record, "fine": fine})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": record})
+}
+
+// findLoan finds the active record specified by the request body
+// An error response is sent if not found
+func findLoan(c *gin.Context) (models.Record, bool) {
+	svc := circulation(c)
+	var record models.Record
+
+	// Validates input
+	var input CirculationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return record, false
+	}
+	input.Barcode = strings.TrimSpace(input.Barcode)
+
+	var err error
+	switch {
+	case input.Barcode != "":
+		var item models.Copy
+		if item, err = svc.Books.FindCopyByBarcode(input.Barcode); err != nil {
+			circulationError(c, notFound(err, ErrCopyNotFound))
+			return record, false
+		}
+		record, err = svc.Records.FindLoanByCopy(item.ID)
+	case input.BookID != 0:
+		record, err = svc.Records.FindLoan(input.UserID, input.BookID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookOrBarcodeRequired.Error()})
+		return record, false
+	}
+	if err != nil {
+		circulationError(c, notBorrowed(err))
+		return record, false
+	}
+	return record, true
+}
+
+// circulation returns the circulation service using the database, or the
+// transaction of the request if in one
+func circulation(c *gin.Context) *service.Circulation {
+	db := c.MustGet("db").(*gorm.DB)
+	libcfg := c.MustGet("libcfg").(config.LibraryConfig)
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	repo := repository.NewGorm(db)
+	if c.GetBool("tx") {
+		repo = repository.NewGormTx(db)
+	}
+	return service.NewCirculation(repo, libcfg, logger)
+}
+
+// inTransaction runs fn with a copy of the context, whose database is a new
+// transaction, which is committed if fn returns nil, and rolled back otherwise
+func inTransaction(c *gin.Context, fn func(c *gin.Context) error) error {
+	db := c.MustGet("db").(*gorm.DB)
+	return db.Transaction(func(tx *gorm.DB) error {
+		txc := c.Copy()
+		txc.Set("db", tx)
+		txc.Set("tx", true)
+		return fn(txc)
+	})
+}
+
+// circulationError sends the error from the circulation service, where the
+// user is unauthorized if suspended, the request conflicts with a concurrent
+// one if the data has been changed since read, and the other violations of
+// the library rules are bad requests
+func circulationError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case service.ErrExceedMaxOverdueBooks, service.ErrExceedMaxUnpaidFines,
+		service.ErrAccountInactive:
+		status = http.StatusUnauthorized
+	case repository.ErrConflict:
+		status = http.StatusConflict
+	case ErrUserNotFound, ErrCopyNotFound, service.ErrBookNotFound,
+		service.ErrBookBorrowed, service.ErrBookNotBorrowed,
+		service.ErrNoCopyAvailable, service.ErrExceedMaxExtendTimes,
+		service.ErrNotForLoan, service.ErrExceedCategoryLoans,
+		service.ErrExceedMaxActiveLoans:
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// notFound replaces the error if the entity is not found in the repository
+func notFound(err error, replacement error) error {
+	if err == repository.ErrNotFound {
+		return replacement
+	}
+	return err
+}
+
+// notBorrowed replaces the error if the loan is not found in the repository
+func notBorrowed(err error) error {
+	return notFound(err, service.ErrBookNotBorrowed)
+}
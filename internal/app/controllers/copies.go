@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrCopyNotFound occurs when the queried copy is not found
+var ErrCopyNotFound = errors.New("database: copy not found")
+
+// ErrBarcodeExists occurs when the barcode already exists
+var ErrBarcodeExists = errors.New("database: barcode already exists")
+
+// ErrCopyOnLoan occurs when an admin wants to modify or retire a copy which
+// is currently on loan
+var ErrCopyOnLoan = errors.New("library: copy on loan")
+
+// AddCopyInput is a schema that validates input to prevent invalid requests
+// ID, BookID will be generated automatically
+// Status will be set to available, and Category to regular if left blank
+type AddCopyInput struct {
+	Barcode  string `json:"barcode" binding:"required"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+	Category string `json:"category"`
+}
+
+// UpdateCopyInput is a schema that validates input to prevent invalid requests
+type UpdateCopyInput struct {
+	Barcode  string `json:"barcode"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+	Category string `json:"category"`
+}
+
+var copyListQuery = listQuery{
+	sortKeys:     []string{"id", "barcode", "location", "status", "category"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// AddCopy adds a new copy of a book to the library
+// POST /admin/books/:id/copies
+func AddCopy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var book models.Book
+	if err := db.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotFound.Error()})
+		return
+	}
+
+	// Validates input
+	var input AddCopyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := addCopy(c, book.ID, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// addCopy adds a new copy of the book, and assigns it to the hold queue if
+// available
+func addCopy(c *gin.Context, bookID uint, input AddCopyInput) (models.Copy, error) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	item := models.Copy{
+		BookID:   bookID,
+		Barcode:  input.Barcode,
+		Location: input.Location,
+		Status:   input.Status,
+		Category: input.Category,
+	}
+	if item.Status == "" {
+		item.Status = models.CopyAvailable
+	}
+	if item.Category == "" {
+		item.Category = models.ItemRegular
+	}
+	if err := item.Validate(); err != nil {
+		return item, err
+	}
+	if err := db.Create(&item).Error; err != nil {
+		return item, ErrBarcodeExists
+	}
+	if item.Status == models.CopyAvailable {
+		if err := circulation(c).AssignCopy(item); err != nil {
+			return item, err
+		}
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Added copy %v of book %v", item.ID, bookID)
+
+	return item, nil
+}
+
+// UpdateCopy relabels a copy or changes its status
+// PATCH /admin/books/:id/copies/:copy_id
+func UpdateCopy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var item models.Copy
+	if err := db.Where("id = ? AND book_id = ?", c.Param("copy_id"), c.Param("id")).First(&item).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyNotFound.Error()})
+		return
+	}
+
+	// Validates input
+	var input UpdateCopyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Status != "" {
+		if err := models.ValidateCopyStatus(input.Status); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// The status of a copy in circulation is only changed by borrowing,
+		// returning it or the hold queue
+		if item.Status == models.CopyOnLoan {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnLoan.Error()})
+			return
+		}
+		if item.Status == models.CopyOnHold {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnHold.Error()})
+			return
+		}
+	}
+	if input.Category != "" {
+		if err := models.ValidateItemCategory(input.Category); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Changes the status only if unchanged since read, so that a copy lent by
+	// a concurrent request is never overwritten
+	prevStatus := item.Status
+	err := inTransaction(c, func(c *gin.Context) error {
+		tx := c.MustGet("db").(*gorm.DB)
+		labels := input
+		labels.Status = ""
+		if err := tx.Model(&item).Updates(labels).Error; err != nil {
+			return ErrBarcodeExists
+		}
+		if input.Status != "" {
+			if err := repository.NewGormTx(tx).SetCopyStatus(&item, input.Status); err != nil {
+				return err
+			}
+		}
+		if prevStatus != models.CopyAvailable && item.Status == models.CopyAvailable {
+			return circulation(c).AssignCopy(item)
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+	case ErrBarcodeExists:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case repository.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Updated copy %v of book %v", item.ID, item.BookID)
+
+	c.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// RetireCopy removes a copy from circulation
+// DELETE /admin/books/:id/copies/:copy_id
+func RetireCopy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var item models.Copy
+	if err := db.Where("id = ? AND book_id = ?", c.Param("copy_id"), c.Param("id")).First(&item).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyNotFound.Error()})
+		return
+	}
+	if item.Status == models.CopyOnLoan {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnLoan.Error()})
+		return
+	}
+	if item.Status == models.CopyOnHold {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCopyOnHold.Error()})
+		return
+	}
+
+	// Validates input
+	var input RemoveBookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db.Delete(&item)
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	if input.Message == "" {
+		logger.Infof("Retired copy %v of book %v", item.ID, item.BookID)
+	} else {
+		logger.Infof("Retired copy %v of book %v with explanation: %v", item.ID, item.BookID, input.Message)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
+
+// ShowCopies shows all copies of a book
+// GET /admin/books/:id/copies
+func ShowCopies(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var book models.Book
+	if err := db.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotFound.Error()})
+		return
+	}
+
+	chain := db.Where("book_id = ?", book.ID)
+	chain, paging, ok := paginate(c, chain, &models.Copy{}, copyListQuery)
+	if !ok {
+		return
+	}
+	var copies []models.Copy
+	chain.Find(&copies)
+
+	respondList(c, copies, paging)
+}
+
+// defaultBarcode generates the barcode of the first copy of a book added
+// without one as BOOK-<book ID>-<n>, skipping the barcodes already in use,
+// which is meant to be relabeled by the librarians
+func defaultBarcode(db *gorm.DB, bookID uint) string {
+	for n := 1; ; n++ {
+		barcode := fmt.Sprintf("BOOK-%v-%v", bookID, n)
+		if !barcodeExists(db, barcode) {
+			return barcode
+		}
+	}
+}
+
+// barcodeExists checks if the barcode is taken by any copy, including the
+// retired ones
+func barcodeExists(db *gorm.DB, barcode string) bool {
+	var count uint
+	db.Unscoped().Model(&models.Copy{}).Where("barcode = ?", strings.TrimSpace(barcode)).Count(&count)
+	return count != 0
+}
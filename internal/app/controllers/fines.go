@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrFineNotFound occurs when the queried fine is not found
+var ErrFineNotFound = errors.New("database: fine not found")
+
+// ErrFineResolved occurs when an admin wants to pay or waive a fine which has
+// been paid or waived before
+var ErrFineResolved = errors.New("library: fine already resolved")
+
+// ResolveFineInput is a schema that validates input to prevent invalid requests
+type ResolveFineInput struct {
+	Message string `json:"message"`
+}
+
+var fineListQuery = listQuery{
+	sortKeys:     []string{"id", "user_id", "record_id", "amount", "status", "created_at", "resolved_at"},
+	defaultSort:  "id",
+	defaultOrder: "desc",
+}
+
+// ShowFines shows all fines of the user
+// GET /user/fines
+func ShowFines(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	session := sessions.Default(c)
+	userID := session.Get(UserKey)
+
+	chain := db.Where("user_id = ?", userID)
+	chain, paging, ok := paginate(c, chain, &models.Fine{}, fineListQuery)
+	if !ok {
+		return
+	}
+	var fines []models.Fine
+	chain.Find(&fines)
+
+	respondList(c, fines, paging)
+}
+
+// ShowAllFines shows all fines in the library
+// Fines can be filtered by user ID and status using the query string
+// GET /admin/fines?user_id=:user_id&status=:status
+func ShowAllFines(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db
+	if userID := c.Query("user_id"); userID != "" {
+		chain = chain.Where("user_id = ?", userID)
+	}
+	if status := c.Query("status"); status != "" {
+		chain = chain.Where("status = ?", status)
+	}
+
+	chain, paging, ok := paginate(c, chain, &models.Fine{}, fineListQuery)
+	if !ok {
+		return
+	}
+	var fines []models.Fine
+	chain.Find(&fines)
+
+	respondList(c, fines, paging)
+}
+
+// PayFine marks a fine as paid
+// POST /admin/fines/:id/pay
+func PayFine(c *gin.Context) {
+	resolveFine(c, models.FinePaid)
+}
+
+// WaiveFine marks a fine as waived
+// POST /admin/fines/:id/waive
+func WaiveFine(c *gin.Context) {
+	resolveFine(c, models.FineWaived)
+}
+
+func resolveFine(c *gin.Context, status string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Gets admin ID
+	session := sessions.Default(c)
+	adminID := session.Get(UserKey)
+
+	var fine models.Fine
+	if err := db.Where("id = ?", c.Param("id")).First(&fine).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFineNotFound.Error()})
+		return
+	}
+	if fine.Status != models.FineUnpaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFineResolved.Error()})
+		return
+	}
+
+	// Validates input
+	var input ResolveFineInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminIDUint, _ := adminID.(uint)
+	db.Model(&fine).Updates(map[string]interface{}{
+		"status":      status,
+		"resolved_at": time.Now().Local(),
+		"resolved_by": adminIDUint,
+		"message":     input.Message,
+	})
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Admin %v marked fine %v of user %v as %v", adminID, fine.ID, fine.UserID, status)
+
+	c.JSON(http.StatusOK, gin.H{"data": fine})
+}
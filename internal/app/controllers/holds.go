@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"github.com/hakula139/REALMS/internal/app/service"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrHoldExists occurs when the user wants to place a hold on a book which
+// has been held before
+var ErrHoldExists = errors.New("library: hold already placed")
+
+// ErrHoldNotFound occurs when the user wants to cancel a hold which has not
+// been placed before
+var ErrHoldNotFound = errors.New("library: hold not found")
+
+// ErrCopyAvailable occurs when the user wants to place a hold on a book which
+// can be borrowed right away
+var ErrCopyAvailable = errors.New("library: copy available, borrow it instead")
+
+// ErrNoCopies occurs when the user wants to place a hold on a book which has no
+// copies in the library, and thus can never be borrowed
+var ErrNoCopies = errors.New("library: book has no copies")
+
+// ErrCopyOnHold occurs when an admin wants to modify or retire a copy which
+// is kept for a user
+var ErrCopyOnHold = errors.New("library: copy on hold")
+
+// PlaceHold adds the user to the hold queue of a book
+// POST /user/holds/:book_id
+func PlaceHold(c *gin.Context) {
+	userID := currentUserID(c)
+
+	// Checks and places the hold in a transaction with the user locked, so that
+	// concurrent requests of the same user can't place the hold twice
+	var hold models.Hold
+	err := inTransaction(c, func(c *gin.Context) error {
+		db := c.MustGet("db").(*gorm.DB)
+		if _, err := circulation(c).Users.LockUser(userID); err != nil {
+			return notFound(err, ErrUserNotFound)
+		}
+
+		// Gets book ID and checks if the book exists
+		var book models.Book
+		if err := db.Where("id = ?", c.Param("book_id")).First(&book).Error; err != nil {
+			return ErrBookNotFound
+		}
+
+		// Checks if the book can ever be borrowed
+		var count uint
+		db.Model(&models.Copy{}).Where("book_id = ?", book.ID).Count(&count)
+		if count == 0 {
+			return ErrNoCopies
+		}
+
+		// Checks if the book has been borrowed or held before
+		count = 0
+		db.Model(&models.Record{}).Where("user_id = ? AND book_id = ?", userID, book.ID).Count(&count)
+		if count != 0 {
+			return service.ErrBookBorrowed
+		}
+		count = 0
+		db.Model(&models.Hold{}).Where("user_id = ? AND book_id = ? AND status IN (?)",
+			userID, book.ID, activeHoldStatus).Count(&count)
+		if count != 0 {
+			return ErrHoldExists
+		}
+
+		// Checks if there's no need to wait
+		count = 0
+		db.Model(&models.Copy{}).Where("book_id = ? AND status = ?", book.ID, models.CopyAvailable).Count(&count)
+		if count != 0 {
+			return ErrCopyAvailable
+		}
+
+		hold = models.Hold{
+			UserID: userID,
+			BookID: book.ID,
+			Status: models.HoldWaiting,
+		}
+		if err := db.Create(&hold).Error; err != nil {
+			return err
+		}
+		hold.Position = holdPosition(db, hold)
+		return nil
+	})
+	if err != nil {
+		holdError(c, err)
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v placed a hold on book %v", userID, hold.BookID)
+
+	c.JSON(http.StatusOK, gin.H{"data": hold})
+}
+
+// CancelHold removes the user from the hold queue of a book
+// DELETE /user/holds/:book_id
+func CancelHold(c *gin.Context) {
+	userID := currentUserID(c)
+	bookID := c.Param("book_id")
+
+	// Cancels the hold and passes the kept copy on in a transaction, so that the
+	// copy is never left kept for a cancelled hold
+	err := inTransaction(c, func(c *gin.Context) error {
+		db := c.MustGet("db").(*gorm.DB)
+		svc := circulation(c)
+		if _, err := svc.Users.LockUser(userID); err != nil {
+			return notFound(err, ErrUserNotFound)
+		}
+
+		var hold models.Hold
+		if err := db.Where("user_id = ? AND book_id = ? AND status IN (?)",
+			userID, bookID, activeHoldStatus).First(&hold).Error; err != nil {
+			return ErrHoldNotFound
+		}
+
+		// Fails if a copy has been kept for the hold since read
+		chain := db.Model(&hold).Where("status = ?", hold.Status).
+			Update("status", models.HoldCancelled)
+		if chain.Error != nil {
+			return chain.Error
+		}
+		if chain.RowsAffected == 0 {
+			return repository.ErrConflict
+		}
+
+		// Passes the kept copy on to the next user in line
+		if hold.CopyID == 0 {
+			return nil
+		}
+		item, err := svc.Books.FindCopy(hold.CopyID)
+		if err == repository.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if item.Status != models.CopyOnHold {
+			return nil
+		}
+		return svc.AssignCopy(item)
+	})
+	if err != nil {
+		holdError(c, err)
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v cancelled the hold on book %v", userID, bookID)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
+
+// ShowHolds shows all active holds of the user
+// GET /user/holds
+func ShowHolds(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	if err := circulation(c).ExpireHolds(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := sessions.Default(c)
+	userID := session.Get(UserKey)
+
+	chain := db.Where("user_id = ? AND status IN (?)", userID, activeHoldStatus)
+	chain, paging, ok := paginate(c, chain, &models.Hold{}, holdListQuery)
+	if !ok {
+		return
+	}
+	var holds []models.Hold
+	chain.Find(&holds)
+	for i := range holds {
+		holds[i].Position = holdPosition(db, holds[i])
+	}
+
+	respondList(c, holds, paging)
+}
+
+// ShowHoldQueue shows the hold queue of a book
+// GET /admin/books/:id/holds
+func ShowHoldQueue(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	if err := circulation(c).ExpireHolds(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if err := db.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotFound.Error()})
+		return
+	}
+
+	chain := db.Where("book_id = ? AND status IN (?)", book.ID, activeHoldStatus)
+	chain, paging, ok := paginate(c, chain, &models.Hold{}, holdListQuery)
+	if !ok {
+		return
+	}
+	var holds []models.Hold
+	chain.Find(&holds)
+	for i := range holds {
+		holds[i].Position = holdPosition(db, holds[i])
+	}
+
+	respondList(c, holds, paging)
+}
+
+// holdError sends the error from placing or cancelling a hold, where the
+// request conflicts with a concurrent one if the data has been changed since
+// read, and the other violations of the library rules are bad requests
+func holdError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case repository.ErrConflict:
+		status = http.StatusConflict
+	case ErrUserNotFound, ErrBookNotFound, ErrNoCopies, service.ErrBookBorrowed,
+		ErrHoldExists, ErrHoldNotFound, ErrCopyAvailable:
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+var activeHoldStatus = []string{models.HoldWaiting, models.HoldReady}
+
+var holdListQuery = listQuery{
+	sortKeys:     []string{"id", "user_id", "book_id", "status", "created_at", "pickup_deadline"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// holdPosition returns the position of a waiting hold in the queue
+// A hold ready for pickup is no longer in the queue, thus position 0
+func holdPosition(db *gorm.DB, hold models.Hold) uint {
+	if hold.Status != models.HoldWaiting {
+		return 0
+	}
+	var count uint
+	db.Model(&models.Hold{}).Where("book_id = ? AND status = ? AND id <= ?",
+		hold.BookID, models.HoldWaiting, hold.ID).Count(&count)
+	return count
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrLockoutNotFound occurs when the failed logins are not found
+var ErrLockoutNotFound = errors.New("database: lockout not found")
+
+var lockoutListQuery = listQuery{
+	sortKeys:     []string{"id", "kind", "target", "failures", "locked_until", "last_failed_at"},
+	defaultSort:  "last_failed_at",
+	defaultOrder: "desc",
+}
+
+// ShowLockouts shows the failed logins counted for usernames and IP addresses,
+// the latest first
+// GET /admin/lockouts
+func ShowLockouts(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain, paging, ok := paginate(c, db, &models.LoginFailure{}, lockoutListQuery)
+	if !ok {
+		return
+	}
+	var failures []models.LoginFailure
+	if err := chain.Find(&failures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, failures, paging)
+}
+
+// ClearLockout forgets the failed logins of a username or an IP address, so
+// that it's allowed to log in right away
+// DELETE /admin/lockouts/:id
+func ClearLockout(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var failure models.LoginFailure
+	if err := db.Where("id = ?", c.Param("id")).First(&failure).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrLockoutNotFound.Error()})
+		return
+	}
+	if err := db.Delete(&failure).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v cleared the lockout of %v %q", currentUserID(c), failure.Kind, failure.Target)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
+
+// loginBlocked finds the failed logins of the username or the IP address
+// which refuse logins at the time, if any
+func loginBlocked(db *gorm.DB, username, ip string, now time.Time) (models.LoginFailure, bool) {
+	var failures []models.LoginFailure
+	db.Where("(kind = ? AND target = ?) OR (kind = ? AND target = ?)",
+		models.LoginFailureUsername, username, models.LoginFailureIP, ip,
+	).Find(&failures)
+	for _, failure := range failures {
+		if failure.Blocked(now) {
+			return failure, true
+		}
+	}
+	return models.LoginFailure{}, false
+}
+
+// failureRetention is how long the failures are kept without a lockout, so
+// that the backoff still doubles, while the stale ones are forgotten
+const failureRetention = 24 * time.Hour
+
+// failLogin counts a failed login of the username from the IP address, and
+// forgets the failures that have been stale for the lockout
+// Returns the failures counted which refuse logins from now on, if any
+func failLogin(c *gin.Context, db *gorm.DB, username, ip string, now time.Time) (models.LoginFailure, bool) {
+	authcfg := c.MustGet("authcfg").(config.AuthConfig)
+	cfg := authcfg.Lockout
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+
+	logger.Warnf("Failed login of %q from %v", username, ip)
+	// IP addresses are only locked out without backoff, since they may be
+	// shared by many users
+	targets := []struct {
+		kind    string
+		target  string
+		max     uint
+		backoff time.Duration
+	}{
+		{models.LoginFailureUsername, username, cfg.MaxFailures, cfg.Backoff()},
+		{models.LoginFailureIP, ip, cfg.MaxIPFailures, 0},
+	}
+	var blocking models.LoginFailure
+	blocked := false
+	for _, t := range targets {
+		failure, lockedOut, err := countFailure(db, t.kind, t.target, now, t.max, t.backoff, cfg.Lockout())
+		if err != nil {
+			logger.Errorf("Failed to count the failed login of %v %q: %v", t.kind, t.target, err)
+			continue
+		}
+		if failure.LockedOut && !lockedOut {
+			logger.Warnf("Locked out %v %q after %v failed logins, until %v",
+				t.kind, t.target, failure.Failures, failure.LockedUntil.Format(time.RFC3339))
+		}
+		if failure.Blocked(now) && (!blocked || failure.LockedUntil.After(blocking.LockedUntil)) {
+			blocking, blocked = failure, true
+		}
+	}
+
+	retention := cfg.Lockout()
+	if retention == 0 {
+		retention = failureRetention
+	}
+	db.Where("last_failed_at < ? AND locked_until < ?", now.Add(-retention), now).Delete(models.LoginFailure{})
+	return blocking, blocked
+}
+
+// countFailure counts a failed login of the target in a transaction, where the
+// row is locked, so that none of the concurrent failures is lost
+// Returns the failures counted, and if the target was locked out before
+func countFailure(
+	db *gorm.DB,
+	kind, target string,
+	now time.Time,
+	max uint,
+	backoff, lockout time.Duration,
+) (models.LoginFailure, bool, error) {
+	var failure models.LoginFailure
+	lockedOut, created := false, false
+	count := func(tx *gorm.DB) error {
+		failure = models.LoginFailure{}
+		err := forUpdate(tx).Where("kind = ? AND target = ?", kind, target).First(&failure).Error
+		if gorm.IsRecordNotFoundError(err) {
+			failure = models.LoginFailure{Kind: kind, Target: target}
+			created = true
+		} else if err != nil {
+			return err
+		}
+		lockedOut = failure.LockedOut && failure.Blocked(now)
+		failure.Fail(now, max, backoff, lockout)
+		return tx.Save(&failure).Error
+	}
+
+	// The first failure of the target may be inserted by a concurrent one in
+	// the meantime, which violates the unique index, so that it's counted
+	// again on the row inserted
+	err := db.Transaction(count)
+	if err != nil && created {
+		created = false
+		err = db.Transaction(count)
+	}
+	return failure, lockedOut, err
+}
+
+// forUpdate locks the rows selected until the end of the transaction
+// SQLite has no row locks, where the whole database is locked by a write
+// transaction instead
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialect().GetName() == "sqlite3" {
+		return tx
+	}
+	return tx.Set("gorm:query_option", "FOR UPDATE")
+}
+
+// retryAfter returns the seconds to wait before logging in again, rounded up
+func retryAfter(failure models.LoginFailure, now time.Time) string {
+	wait := failure.LockedUntil.Sub(now)
+	return strconv.Itoa(int((wait + time.Second - 1) / time.Second))
+}
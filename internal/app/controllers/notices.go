@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/service"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// SetNotificationsInput is a schema that validates input to prevent invalid
+// requests
+type SetNotificationsInput struct {
+	Notify string `json:"notify" binding:"required"`
+}
+
+// Notifications is the email address and the notification preference of a
+// user
+type Notifications struct {
+	Email  string `json:"email"`
+	Notify string `json:"notify"`
+}
+
+var noticeListQuery = listQuery{
+	sortKeys:     []string{"id", "user_id", "record_id", "kind", "channel", "sent_at"},
+	defaultSort:  "id",
+	defaultOrder: "desc",
+}
+
+// ShowNotifications shows the email address and the notification preference
+// of the user
+// GET /user/notifications
+func ShowNotifications(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.Where("id = ?", currentUserID(c)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": Notifications{user.Email, user.NotifyPreference()}})
+}
+
+// SetNotifications sets the notices the user wants to receive, namely, all,
+// overdue only or none
+// PUT /user/notifications
+func SetNotifications(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Validates input
+	var input SetNotificationsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	notify := strings.ToLower(strings.TrimSpace(input.Notify))
+	if err := models.ValidateNotifyPreference(notify); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := db.Where("id = ?", currentUserID(c)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+	// Saves the fields changed only
+	if err := db.Model(&user).UpdateColumn("notify", notify).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v set the notification preference to %v", user.ID, notify)
+
+	c.JSON(http.StatusOK, gin.H{"data": Notifications{user.Email, notify}})
+}
+
+// ShowNotices shows all notices sent to the user
+// GET /user/notices
+func ShowNotices(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db.Where("user_id = ?", currentUserID(c))
+	chain, paging, ok := paginate(c, chain, &models.Notice{}, noticeListQuery)
+	if !ok {
+		return
+	}
+	var notices []models.Notice
+	chain.Find(&notices)
+
+	respondList(c, notices, paging)
+}
+
+// ShowAllNotices shows all notices sent in the library
+// Notices can be filtered by user ID and kind using the query string
+// GET /admin/notices?user_id=:user_id&kind=:kind
+func ShowAllNotices(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db
+	if userID := c.Query("user_id"); userID != "" {
+		chain = chain.Where("user_id = ?", userID)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		chain = chain.Where("kind = ?", kind)
+	}
+
+	chain, paging, ok := paginate(c, chain, &models.Notice{}, noticeListQuery)
+	if !ok {
+		return
+	}
+	var notices []models.Notice
+	chain.Find(&notices)
+
+	respondList(c, notices, paging)
+}
+
+// SendNotices sends the notices due now without waiting for the scheduler,
+// and returns the number of notices sent
+// POST /admin/notices/send
+func SendNotices(c *gin.Context) {
+	reminder := c.MustGet("reminder").(*service.Reminder)
+
+	sent, err := reminder.Run()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sent})
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ErrInvalidPaging occurs when limit or offset is not a non-negative integer
+var ErrInvalidPaging = errors.New("validate: invalid limit or offset")
+
+// ErrInvalidSortKey occurs when the list can't be sorted by the given key
+var ErrInvalidSortKey = errors.New("validate: invalid sort key")
+
+// ErrInvalidSortOrder occurs when the sort order is neither asc nor desc
+var ErrInvalidSortOrder = errors.New("validate: invalid sort order, expected asc / desc")
+
+// Paging is the metadata returned alongside a page of a list
+// Total is the number of items in the whole list
+type Paging struct {
+	Limit  uint   `json:"limit"`
+	Offset uint   `json:"offset"`
+	Total  uint   `json:"total"`
+	Sort   string `json:"sort"`
+	Order  string `json:"order"`
+}
+
+// listQuery describes how a list endpoint can be sorted
+// sortKeys whitelists the columns allowed to sort by, and the list is sorted
+// by defaultSort in defaultOrder if not specified. Ties are broken by ID
+type listQuery struct {
+	sortKeys     []string
+	defaultSort  string
+	defaultOrder string
+}
+
+// paginate counts the items in the query chain, and applies the paging and
+// sorting parameters in the query string to it
+// GET ...?limit=:limit&offset=:offset&sort=:sort&order=:order
+// An error response is sent if the parameters are invalid
+func paginate(c *gin.Context, chain *gorm.DB, model interface{}, q listQuery) (*gorm.DB, Paging, bool) {
+	paging, ok := parsePaging(c, q)
+	if !ok {
+		return chain, paging, false
+	}
+
+	chain.Model(model).Count(&paging.Total)
+
+	orderBy := paging.Sort + " " + paging.Order
+	if paging.Sort != "id" {
+		orderBy += ", id " + paging.Order
+	}
+	chain = chain.Order(orderBy).Limit(paging.Limit).Offset(paging.Offset)
+	return chain, paging, true
+}
+
+// parsePaging reads the paging and sorting parameters in the query string
+// An error response is sent if the parameters are invalid
+func parsePaging(c *gin.Context, q listQuery) (Paging, bool) {
+	paging := Paging{
+		Limit: defaultPageLimit,
+		Sort:  q.defaultSort,
+		Order: q.defaultOrder,
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseUint(limit, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPaging.Error()})
+			return paging, false
+		}
+		paging.Limit = uint(n)
+	}
+	if paging.Limit == 0 || paging.Limit > maxPageLimit {
+		paging.Limit = maxPageLimit
+	}
+	if offset := c.Query("offset"); offset != "" {
+		n, err := strconv.ParseUint(offset, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPaging.Error()})
+			return paging, false
+		}
+		paging.Offset = uint(n)
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		if !contains(q.sortKeys, sort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidSortKey.Error()})
+			return paging, false
+		}
+		paging.Sort = sort
+	}
+	if order := strings.ToLower(c.Query("order")); order != "" {
+		if order != "asc" && order != "desc" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidSortOrder.Error()})
+			return paging, false
+		}
+		paging.Order = order
+	}
+	return paging, true
+}
+
+// respondList sends a page of the list along with the paging metadata
+// Only the fields in the query string are kept if specified
+// GET ...?fields=:field1,:field2
+func respondList(c *gin.Context, data interface{}, paging Paging) {
+	fields := c.Query("fields")
+	if fields == "" {
+		c.JSON(http.StatusOK, gin.H{"data": data, "paging": paging})
+		return
+	}
+
+	// Converts the items to maps and filters the keys
+	var items []map[string]interface{}
+	buf, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(buf, &items)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	keys := strings.Split(fields, ",")
+	for i, item := range items {
+		selected := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			key = strings.TrimSpace(key)
+			if value, ok := item[key]; ok {
+				selected[key] = value
+			}
+		}
+		items[i] = selected
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": items, "paging": paging})
+}
+
+func contains(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
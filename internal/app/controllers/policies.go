@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrPolicyNotFound occurs when there's no policy set for the categories
+var ErrPolicyNotFound = errors.New("database: policy not found")
+
+// SetPolicyInput is a schema that validates input to prevent invalid requests
+// The categories are given in the path, and the other fields are replaced
+type SetPolicyInput struct {
+	Circulates  bool `json:"circulates"`
+	LoanDays    uint `json:"loan_days"`
+	RenewDays   uint `json:"renew_days"`
+	MaxRenewals uint `json:"max_renewals"`
+	MaxLoans    uint `json:"max_loans"`
+}
+
+// ShowPolicies shows the loan policies of all item categories for all patron
+// categories, where the ID is 0 if the policy is not set, and the settings in
+// the library config are used
+// GET /admin/policies
+func ShowPolicies(c *gin.Context) {
+	svc := circulation(c)
+
+	var policies []models.Policy
+	for _, patron := range models.PatronCategories {
+		for _, item := range models.ItemCategories {
+			policy, err := svc.Policy(patron, item)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policies})
+}
+
+// SetPolicy sets the loan policy of an item category for a patron category
+// PUT /admin/policies/:patron/:item
+func SetPolicy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Validates input
+	var input SetPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy := models.Policy{
+		PatronCategory: c.Param("patron"),
+		ItemCategory:   c.Param("item"),
+		Circulates:     input.Circulates,
+		LoanDays:       input.LoanDays,
+		RenewDays:      input.RenewDays,
+		MaxRenewals:    input.MaxRenewals,
+		MaxLoans:       input.MaxLoans,
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Replaces the policy if set before
+	var prev models.Policy
+	err := db.Where("patron_category = ? AND item_category = ?",
+		policy.PatronCategory, policy.ItemCategory).First(&prev).Error
+	if err == nil {
+		policy.ID = prev.ID
+		err = db.Model(&policy).Updates(map[string]interface{}{
+			"circulates":   policy.Circulates,
+			"loan_days":    policy.LoanDays,
+			"renew_days":   policy.RenewDays,
+			"max_renewals": policy.MaxRenewals,
+			"max_loans":    policy.MaxLoans,
+		}).Error
+	} else if gorm.IsRecordNotFoundError(err) {
+		err = db.Create(&policy).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Set the loan policy of %v copies for %v users", policy.ItemCategory, policy.PatronCategory)
+
+	c.JSON(http.StatusOK, gin.H{"data": policy})
+}
+
+// ResetPolicy removes the loan policy of an item category for a patron
+// category, so that the settings in the library config are used
+// DELETE /admin/policies/:patron/:item
+func ResetPolicy(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	patron, item := c.Param("patron"), c.Param("item")
+	if err := models.ValidatePatronCategory(patron); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateItemCategory(item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chain := db.Where("patron_category = ? AND item_category = ?", patron, item).Delete(&models.Policy{})
+	if chain.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": chain.Error.Error()})
+		return
+	}
+	if chain.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrPolicyNotFound.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Reset the loan policy of %v copies for %v users", item, patron)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
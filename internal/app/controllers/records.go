@@ -1,80 +1,29 @@
 package controllers
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/hakula139/REALMS/internal/app/config"
 	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/service"
 	"github.com/jinzhu/gorm"
-	"go.uber.org/zap"
 )
 
-const day = time.Hour * 24
-
-// ErrExceedMaxOverdueBooks occurs when the user has too many overdue books,
-// thus being suspended
-var ErrExceedMaxOverdueBooks = errors.New("library: too many overdue books")
-
-// ErrBookBorrowed occurs when the user wants to borrow a book which has been
-// borrowed before
-var ErrBookBorrowed = errors.New("library: book already borrowed")
-
-// ErrBookNotBorrowed occurs when the user wants to return a book which has
-// not been borrowed before
-var ErrBookNotBorrowed = errors.New("library: book not borrowed")
-
-// ErrExceedMaxExtendTimes occurs when the user has extended the deadline too
-// many times
-var ErrExceedMaxExtendTimes = errors.New("library: extended too many times")
-
 // AddRecordInput is a schema that validates input to prevent invalid requests
 // ID, UserID, BookID, ExtendTimes will be generated automatically
 // BorrowDate will be set to current date if left blank
+// Barcode specifies the copy to borrow, any available copy is used if left blank
 type AddRecordInput struct {
 	BorrowDate time.Time `json:"borrow_date"`
+	Barcode    string    `json:"barcode"`
 }
 
 // BorrowBook adds a new record to the database
 // POST /user/books/:id
 func BorrowBook(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-	libcfg := c.MustGet("libcfg").(config.LibraryConfig)
-
-	// Gets user ID
-	session := sessions.Default(c)
-	userID := session.Get(userkey)
-
-	// Checks if the user should be suspended
-	var count uint
-	today := time.Now().Local()
-	db.Model(&models.Record{}).Where("user_id = ? AND return_date < ?", userID, today).Count(&count)
-	if count >= libcfg.MaxOverdueBooks {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrExceedMaxOverdueBooks.Error()})
-		return
-	}
-
-	// Gets book ID and checks if the book exists
-	count = 0
-	bookID := c.Param("id")
-	db.Model(&models.Book{}).Where("id = ?", bookID).Count(&count)
-	if count == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotFound.Error()})
-		return
-	}
-
-	// Checks if the book has been borrowed before
-	count = 0
-	db.Model(&models.Record{}).Where("user_id = ? AND book_id = ?", userID, bookID).Count(&count)
-	if count != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookBorrowed.Error()})
-		return
-	}
-
 	// Validates input
 	var input AddRecordInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -82,29 +31,13 @@ func BorrowBook(c *gin.Context) {
 		return
 	}
 
-	// Calculates return date
-	var returnDate time.Time
-	expire := time.Duration(libcfg.BorrowExpireDays) * day
-	if input.BorrowDate.IsZero() {
-		returnDate = today.Add(expire)
-	} else {
-		returnDate = input.BorrowDate.Add(expire)
-	}
-
-	bookIDInt, _ := strconv.Atoi(bookID)
-	bookIDUint := uint(bookIDInt)
-	userIDUint, _ := userID.(uint)
-
-	record := models.Record{
-		UserID:      userIDUint,
-		BookID:      bookIDUint,
-		ReturnDate:  returnDate,
-		ExtendTimes: 0,
+	bookID, _ := strconv.Atoi(c.Param("id"))
+	svc := circulation(c)
+	record, err := svc.Lend(currentUserID(c), uint(bookID), input.Barcode, input.BorrowDate, 0)
+	if err != nil {
+		circulationError(c, err)
+		return
 	}
-	db.Create(&record)
-
-	logger := c.MustGet("logger").(*zap.SugaredLogger)
-	logger.Infof("User %v borrowed book %v", userID, bookID)
 
 	c.JSON(http.StatusOK, gin.H{"data": record})
 }
@@ -112,72 +45,70 @@ func BorrowBook(c *gin.Context) {
 // ExtendDeadline extends the deadline to return a book
 // PATCH /user/books/:id
 func ExtendDeadline(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-	libcfg := c.MustGet("libcfg").(config.LibraryConfig)
+	svc := circulation(c)
 
-	// Gets user ID
-	session := sessions.Default(c)
-	userID := session.Get(userkey)
-
-	var record models.Record
-	bookID := c.Param("id")
-	if err := db.Where("user_id = ? AND book_id = ?", userID, bookID).First(&record).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotBorrowed.Error()})
+	bookID, _ := strconv.Atoi(c.Param("id"))
+	record, err := svc.Records.FindLoan(currentUserID(c), uint(bookID))
+	if err != nil {
+		circulationError(c, notBorrowed(err))
 		return
 	}
 
-	// Checks if the user has extended the deadline too many times
-	if record.ExtendTimes >= libcfg.MaxExtendTimes {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrExceedMaxExtendTimes.Error()})
+	if err := svc.Renew(&record, 0); err != nil {
+		circulationError(c, err)
 		return
 	}
 
-	extend := time.Duration(libcfg.DdlExtendDays) * day
-	db.Model(&record).Updates(models.Record{
-		ReturnDate:  record.ReturnDate.Add(extend),
-		ExtendTimes: record.ExtendTimes + 1,
-	})
-
 	c.JSON(http.StatusOK, gin.H{"data": record})
 }
 
 // ReturnBook soft deletes the related record from the database
+// A fine is charged if the book is returned past the return date
 // DELETE /user/books/:id
 func ReturnBook(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-
-	// Gets user ID
-	session := sessions.Default(c)
-	userID := session.Get(userkey)
+	svc := circulation(c)
 
 	// Gets book ID and checks if the book has been borrowed before
-	var record models.Record
-	bookID := c.Param("id")
-	if err := db.Where("user_id = ? AND book_id = ?", userID, bookID).First(&record).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotBorrowed.Error()})
+	bookID, _ := strconv.Atoi(c.Param("id"))
+	record, err := svc.Records.FindLoan(currentUserID(c), uint(bookID))
+	if err != nil {
+		circulationError(c, notBorrowed(err))
 		return
 	}
 
-	db.Delete(&record)
-
-	logger := c.MustGet("logger").(*zap.SugaredLogger)
-	logger.Infof("User %v returned book %v", userID, bookID)
-
+	fine, err := svc.Return(&record, 0)
+	if err != nil {
+		circulationError(c, err)
+		return
+	}
+	if fine != nil {
+		c.JSON(http.StatusOK, gin.H{"data": true, "fine": fine})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
+var recordSortKeys = []string{
+	"id", "user_id", "book_id", "copy_id", "borrow_date", "return_date", "extend_times", "deleted_at",
+}
+
 // ShowBookList shows all books that the user has borrowed
 // GET /user/books
 func ShowBookList(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	session := sessions.Default(c)
-	userID := session.Get(userkey)
+	userID := session.Get(UserKey)
 
+	chain := db.Where("user_id = ?", userID)
+	chain, paging, ok := paginate(c, chain, &models.Record{}, listQuery{recordSortKeys, "return_date", "asc"})
+	if !ok {
+		return
+	}
 	var records []models.Record
-	db.Order("return_date").Where("user_id = ?", userID).Find(&records)
+	chain.Find(&records)
 
-	c.JSON(http.StatusOK, gin.H{"data": records})
+	respondList(c, records, paging)
 }
 
 // ShowBorrowed shows a book that the user has borrowed
@@ -188,13 +119,13 @@ func ShowBorrowed(c *gin.Context) {
 
 	// Gets user ID
 	session := sessions.Default(c)
-	userID := session.Get(userkey)
+	userID := session.Get(UserKey)
 
 	// Gets book ID and checks if the book has been borrowed before
 	var record models.Record
 	bookID := c.Param("id")
 	if err := db.Where("user_id = ? AND book_id = ?", userID, bookID).First(&record).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBookNotBorrowed.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrBookNotBorrowed.Error()})
 		return
 	}
 
@@ -207,13 +138,18 @@ func ShowOverdueList(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	session := sessions.Default(c)
-	userID := session.Get(userkey)
+	userID := session.Get(UserKey)
 
-	var records []models.Record
 	today := time.Now().Local()
-	db.Order("return_date").Where("user_id = ? AND return_date < ?", userID, today).Find(&records)
+	chain := db.Where("user_id = ? AND return_date < ?", userID, today)
+	chain, paging, ok := paginate(c, chain, &models.Record{}, listQuery{recordSortKeys, "return_date", "asc"})
+	if !ok {
+		return
+	}
+	var records []models.Record
+	chain.Find(&records)
 
-	c.JSON(http.StatusOK, gin.H{"data": records})
+	respondList(c, records, paging)
 }
 
 // ShowHistory shows all records of the user
@@ -222,10 +158,15 @@ func ShowHistory(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	session := sessions.Default(c)
-	userID := session.Get(userkey)
+	userID := session.Get(UserKey)
 
+	chain := db.Unscoped().Where("user_id = ?", userID)
+	chain, paging, ok := paginate(c, chain, &models.Record{}, listQuery{recordSortKeys, "id", "desc"})
+	if !ok {
+		return
+	}
 	var records []models.Record
-	db.Order("id DESC").Unscoped().Where("user_id = ?", userID).Find(&records)
+	chain.Find(&records)
 
-	c.JSON(http.StatusOK, gin.H{"data": records})
+	respondList(c, records, paging)
 }
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+// ErrInvalidDate occurs when the date is not in the format of yyyy-mm-dd
+var ErrInvalidDate = errors.New("validate: invalid date, expected yyyy-mm-dd")
+
+// ErrInvalidRecordStatus occurs when the record status filter is unknown
+var ErrInvalidRecordStatus = errors.New("validate: invalid record status, expected active / returned / all")
+
+// ShowAllRecords shows records of all users in the library
+// Records can be filtered by user ID, book ID, status (active / returned /
+// all), overdue only and a range of borrowing date using the query string
+// GET /admin/records?user_id=:user_id&book_id=:book_id&status=:status&overdue=:overdue&from=:from&to=:to
+func ShowAllRecords(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db.Unscoped()
+	if userID := c.Query("user_id"); userID != "" {
+		chain = chain.Where("user_id = ?", userID)
+	}
+	if bookID := c.Query("book_id"); bookID != "" {
+		chain = chain.Where("book_id = ?", bookID)
+	}
+
+	switch c.Query("status") {
+	case "active":
+		chain = chain.Where("deleted_at IS NULL")
+	case "returned":
+		chain = chain.Where("deleted_at IS NOT NULL")
+	case "", "all":
+		// Does nothing
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRecordStatus.Error()})
+		return
+	}
+
+	// Filters by borrowing date, both ends included
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation(dateLayout, from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDate.Error()})
+			return
+		}
+		chain = chain.Where("borrow_date >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.ParseInLocation(dateLayout, to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDate.Error()})
+			return
+		}
+		chain = chain.Where("borrow_date < ?", date.AddDate(0, 0, 1))
+	}
+
+	q := listQuery{recordSortKeys, "id", "desc"}
+	if c.Query("overdue") == "true" {
+		today := time.Now().Local()
+		chain = chain.Where("deleted_at IS NULL AND return_date < ?", today)
+		q = listQuery{recordSortKeys, "return_date", "asc"}
+	}
+
+	chain, paging, ok := paginate(c, chain, &models.Record{}, q)
+	if !ok {
+		return
+	}
+	var records []models.Record
+	chain.Find(&records)
+
+	respondList(c, records, paging)
+}
+
+// ShowUserRecords shows all records of the user of given ID
+// GET /admin/users/:id/records
+func ShowUserRecords(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+
+	chain := db.Unscoped().Where("user_id = ?", user.ID)
+	chain, paging, ok := paginate(c, chain, &models.Record{}, listQuery{recordSortKeys, "id", "desc"})
+	if !ok {
+		return
+	}
+	var records []models.Record
+	chain.Find(&records)
+
+	respondList(c, records, paging)
+}
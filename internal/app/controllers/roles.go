@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrRoleNotFound occurs when the role is not found
+var ErrRoleNotFound = errors.New("database: role not found")
+
+// ErrRoleInUse occurs when removing a role granted to users
+var ErrRoleInUse = errors.New("database: role granted to users")
+
+// ErrBuiltinRole occurs when changing or removing a built-in role
+var ErrBuiltinRole = errors.New("database: built-in roles can't be changed")
+
+// ErrGrantDenied occurs when the user grants permissions not held by the user
+var ErrGrantDenied = errors.New("auth: unable to grant permissions you don't hold")
+
+// SetRoleInput is a schema that validates input to prevent invalid requests
+// The name is given in the path, and the permissions are replaced
+type SetRoleInput struct {
+	Permissions []string `json:"permissions"`
+}
+
+var roleListQuery = listQuery{
+	sortKeys:     []string{"id", "name"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// ShowRoles shows all roles along with their permissions
+// GET /admin/roles
+func ShowRoles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain, paging, ok := paginate(c, db, &models.Role{}, roleListQuery)
+	if !ok {
+		return
+	}
+	var roles []models.Role
+	if err := chain.Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, roles, paging)
+}
+
+// SetRole creates a role or replaces its permissions, where the permissions
+// granted before and after should both be held by the current user
+// PUT /admin/roles/:name
+func SetRole(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Validates input
+	var input SetRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	role := models.Role{Name: c.Param("name"), Permissions: input.Permissions}
+	if err := role.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var prev models.Role
+	err := db.Where("name = ?", role.Name).First(&prev).Error
+	if err == nil && prev.IsBuiltin() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBuiltinRole.Error()})
+		return
+	}
+	granter := currentRole(c)
+	if !granter.Covers(role) || !granter.Covers(prev) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrGrantDenied.Error()})
+		return
+	}
+
+	// Replaces the role if created before
+	if err == nil {
+		role.ID = prev.ID
+		err = db.Save(&role).Error
+	} else if gorm.IsRecordNotFoundError(err) {
+		err = db.Create(&role).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Set the permissions of role %v to %v", role.Name, role.Permissions)
+
+	c.JSON(http.StatusOK, gin.H{"data": role})
+}
+
+// RemoveRole removes a role not granted to any user
+// DELETE /admin/roles/:name
+func RemoveRole(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var role models.Role
+	if err := db.Where("name = ?", c.Param("name")).First(&role).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrRoleNotFound.Error()})
+		return
+	}
+	if role.IsBuiltin() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBuiltinRole.Error()})
+		return
+	}
+	if !currentRole(c).Covers(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrGrantDenied.Error()})
+		return
+	}
+	var count uint
+	db.Model(&models.User{}).Where("role = ?", role.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrRoleInUse.Error()})
+		return
+	}
+
+	if err := db.Delete(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Removed role %v", role.Name)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
+
+// findRole finds the role of given name, which grants no permissions if not
+// found, e.g. removed from the database by hand
+func findRole(db *gorm.DB, name string) models.Role {
+	var role models.Role
+	if err := db.Where("name = ?", name).First(&role).Error; err != nil {
+		return models.Role{Name: name}
+	}
+	return role
+}
+
+// currentRole returns the role of the current logged-in user, which is saved
+// by PermissionRequired
+func currentRole(c *gin.Context) models.Role {
+	role, _ := c.Get("role")
+	current, _ := role.(models.Role)
+	return current
+}
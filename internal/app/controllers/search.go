@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/search"
+	"github.com/jinzhu/gorm"
+)
+
+// SearchResult is a book matching the search query, along with its relevance
+// score
+type SearchResult struct {
+	models.Book
+	Score float64 `json:"score"`
+}
+
+// Search results are sorted by relevance only
+var searchListQuery = listQuery{
+	sortKeys:     []string{"relevance"},
+	defaultSort:  "relevance",
+	defaultOrder: "desc",
+}
+
+// SearchBooks finds books by a full-text query over title / author /
+// subjects / series / publisher / language / year / ISBN, ranked by relevance
+// GET /books/search?q=:query
+func SearchBooks(c *gin.Context) {
+	searchBooks(c, c.Query("q"))
+}
+
+// searchBooks runs the query against the search index, and sends a page of
+// the matching books
+func searchBooks(c *gin.Context, query string) {
+	db := c.MustGet("db").(*gorm.DB)
+	idx := c.MustGet("index").(*search.Index)
+
+	paging, ok := parsePaging(c, searchListQuery)
+	if !ok {
+		return
+	}
+	results, err := idx.Search(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if paging.Order == "asc" {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+
+	// Takes the current page of results and loads the books in it
+	paging.Total = uint(len(results))
+	if paging.Offset > paging.Total {
+		paging.Offset = paging.Total
+	}
+	end := paging.Offset + paging.Limit
+	if end > paging.Total {
+		end = paging.Total
+	}
+	results = results[paging.Offset:end]
+
+	ids := make([]uint, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	var books []models.Book
+	db.Preload("Authors").Preload("Subjects").Where("id IN (?)", ids).Find(&books)
+	booksByID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		booksByID[book.ID] = book
+	}
+
+	page := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		if book, ok := booksByID[result.ID]; ok {
+			page = append(page, SearchResult{book, result.Score})
+		}
+	}
+
+	respondList(c, page, paging)
+}
+
+// queryReplacer strips the characters with special meanings in a query
+var queryReplacer = strings.NewReplacer("*", "", ":", "", "(", "", ")", "", "\"", "")
+
+// findBooksQuery converts the fields of FindBooks to a search query, where
+// each word in the text fields is matched as a prefix in its field
+func findBooksQuery(input FindBookInput) string {
+	var terms []string
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"title", input.Title},
+		{"author", input.Author},
+		{"publisher", input.Publisher},
+		{"subjects", input.Subject},
+		{"series", input.Series},
+		{"language", input.Language},
+	}
+	for _, field := range fields {
+		for _, word := range strings.Fields(field.value) {
+			word = strings.TrimLeft(queryReplacer.Replace(strings.ToLower(word)), "-")
+			if word != "" {
+				terms = append(terms, field.name+":"+word+"*")
+			}
+		}
+	}
+	if ISBN := strings.Join(strings.Fields(queryReplacer.Replace(input.ISBN)), ""); ISBN != "" {
+		if normalized, err := models.NormalizeISBN(ISBN); err == nil {
+			ISBN = normalized
+		}
+		terms = append(terms, "isbn:"+ISBN)
+	}
+	if input.Year != 0 {
+		terms = append(terms, "year:"+strconv.Itoa(int(input.Year)))
+	}
+	return strings.Join(terms, " ")
+}
+
+// indexBook adds the book to the search index, or refreshes it
+func indexBook(c *gin.Context, book models.Book) {
+	idx := c.MustGet("index").(*search.Index)
+	idx.Add(book.Document())
+}
+
+// unindexBook removes the book from the search index
+func unindexBook(c *gin.Context, bookID uint) {
+	idx := c.MustGet("index").(*search.Index)
+	idx.Remove(bookID)
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// ErrTokenNotFound occurs when the API token is not found
+var ErrTokenNotFound = errors.New("database: token not found")
+
+// ErrTokenCreateDenied occurs when creating an API token using another one,
+// which would outlive the token used
+var ErrTokenCreateDenied = errors.New("auth: unable to create tokens using a token, please log in")
+
+// CreateTokenInput is a schema that validates input to prevent invalid
+// requests
+// The token never expires if ExpiresInDays is 0
+type CreateTokenInput struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays uint     `json:"expires_in_days"`
+}
+
+var tokenListQuery = listQuery{
+	sortKeys:     []string{"id", "name", "created_at", "expires_at", "last_used_at"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// ShowTokens shows all API tokens of the user, without the tokens themselves
+// GET /user/tokens
+func ShowTokens(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	chain := db.Where("user_id = ?", currentUserID(c))
+	chain, paging, ok := paginate(c, chain, &models.APIToken{}, tokenListQuery)
+	if !ok {
+		return
+	}
+	var tokens []models.APIToken
+	if err := chain.Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, tokens, paging)
+}
+
+// CreateToken creates an API token of the user, whose scopes should be
+// granted by the role of the user
+// The token is returned in the token field, and can't be shown again
+// POST /user/tokens
+func CreateToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	if _, ok := c.Get("token"); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrTokenCreateDenied.Error()})
+		return
+	}
+
+	// Validates input
+	var input CreateTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := currentUserID(c)
+	token := models.APIToken{UserID: userID, Name: input.Name, Scopes: input.Scopes}
+	if err := token.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+	if !findRole(db, user.RoleName()).Covers(models.Role{Permissions: token.Scopes}) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrGrantDenied.Error()})
+		return
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().Local().AddDate(0, 0, int(input.ExpiresInDays))
+		token.ExpiresAt = &expiresAt
+	}
+
+	secret, hash, err := models.NewAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token.SetToken(secret, hash)
+	if err := db.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v created token %v with scopes %v", userID, token.ID, token.Scopes)
+
+	c.JSON(http.StatusOK, gin.H{"data": token, "token": secret})
+}
+
+// RevokeToken removes an API token of the user
+// DELETE /user/tokens/:id
+func RevokeToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	userID := currentUserID(c)
+	var token models.APIToken
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&token).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrTokenNotFound.Error()})
+		return
+	}
+	if err := db.Delete(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v revoked token %v", userID, token.ID)
+
+	c.JSON(http.StatusOK, gin.H{"data": true})
+}
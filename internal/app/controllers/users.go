@@ -3,37 +3,81 @@ package controllers
 import (
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hakula139/REALMS/internal/app/config"
 	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/service"
 	"github.com/jinzhu/gorm"
 	"go.uber.org/zap"
 )
 
 // ErrUserNotFound occurs when the user is not found
-var ErrUserNotFound = errors.New("database: user not found")
+var ErrUserNotFound = service.ErrUserNotFound
 
 // ErrUsernameExists occurs when the username already exists
 var ErrUsernameExists = errors.New("database: username already exists")
 
+// ErrManageDenied occurs when the user manages another user holding
+// permissions not held by the user
+var ErrManageDenied = errors.New("auth: unable to manage users holding permissions you don't hold")
+
+// ErrManageSelf occurs when the user changes the role or state of, or removes
+// oneself
+var ErrManageSelf = errors.New("auth: unable to change your own role or state, or remove yourself")
+
+// ErrLastManager occurs when removing, banning, suspending or changing the
+// role of a user would leave no active user able to manage users
+var ErrLastManager = errors.New("auth: unable to leave no active user holding users.manage")
+
+// ErrUserNotPending occurs when the user to approve is not pending approval
+var ErrUserNotPending = errors.New("database: user not pending approval")
+
+// SetUserStateInput is a schema that validates input to prevent invalid
+// requests
+// Until is required if the user is suspended, and ignored otherwise
+type SetUserStateInput struct {
+	State  string     `json:"state" binding:"required"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
 // AddUserInput is a schema that validates input to prevent invalid requests
 // ID will be generated automatically
+// Role will be set to user if left blank
+// Category will be set to student if left blank
+// MaxLoans overrides the maximum number of books borrowed at a time in the
+// library config if not 0
+// Notify will be set to all if left blank
 type AddUserInput struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Level    uint   `json:"level" binding:"required"`
+	Role     string `json:"role"`
+	Category string `json:"category"`
+	MaxLoans uint   `json:"max_loans"`
+	Email    string `json:"email"`
+	Notify   string `json:"notify"`
 }
 
 // UpdateUserInput is a schema that validates input to prevent invalid requests
+// MaxLoans is set to 0 to use the library config again, and Email is set to
+// blank to remove it, both of which are left as is if not given
 type UpdateUserInput struct {
-	Password string `json:"password"`
-	Level    uint   `json:"level"`
+	Password string  `json:"password"`
+	Role     string  `json:"role"`
+	Category string  `json:"category"`
+	MaxLoans *uint   `json:"max_loans" gorm:"-"`
+	Email    *string `json:"email" gorm:"-"`
+	Notify   string  `json:"notify"`
 }
 
 // AddUser adds a new user to the database
 // POST /admin/users
 func AddUser(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	authcfg := c.MustGet("authcfg").(config.AuthConfig)
 
 	// Validates input
 	var input AddUserInput
@@ -45,12 +89,45 @@ func AddUser(c *gin.Context) {
 	user := models.User{
 		Username: input.Username,
 		Password: input.Password,
-		Level:    input.Level,
+		Role:     input.Role,
+		Category: input.Category,
+		MaxLoans: input.MaxLoans,
+		Email:    strings.TrimSpace(input.Email),
+		Notify:   input.Notify,
+		State:    models.UserActive,
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Category == "" {
+		user.Category = models.PatronStudent
+	}
+	if user.Notify == "" {
+		user.Notify = models.NotifyAll
 	}
 	if err := user.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := models.ValidatePassword(user.Password, user.Username, authcfg.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidatePatronCategory(user.Category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateEmail(user.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateNotifyPreference(user.Notify); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok := checkGrant(c, db, user.Role); !ok {
+		return
+	}
 	if err := db.Create(&user).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUsernameExists.Error()})
 		return
@@ -66,6 +143,7 @@ func AddUser(c *gin.Context) {
 // PATCH /admin/users/:id
 func UpdateUser(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	authcfg := c.MustGet("authcfg").(config.AuthConfig)
 
 	var user models.User
 	if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
@@ -79,12 +157,83 @@ func UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if ok := checkManage(c, db, user, input.Role != ""); !ok {
+		return
+	}
+	if input.Role != "" {
+		if ok := checkGrant(c, db, input.Role); !ok {
+			return
+		}
+	}
+	if input.Category != "" {
+		if err := models.ValidatePatronCategory(input.Category); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.Email != nil {
+		*input.Email = strings.TrimSpace(*input.Email)
+		if err := models.ValidateEmail(*input.Email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.Notify != "" {
+		if err := models.ValidateNotifyPreference(input.Notify); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	if err := models.EncryptPassword(&input.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Encrypts the password only if a new one is given, which is left out of
+	// the update otherwise
+	passwordChanged := input.Password != ""
+	if passwordChanged {
+		if err := models.ValidatePassword(input.Password, user.Username, authcfg.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := models.EncryptPassword(&input.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	roleChanged := input.Role != "" && input.Role != user.RoleName()
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if roleChanged {
+			if err := keepsManager(tx, func(u *models.User) bool {
+				if u.ID == user.ID {
+					u.Role = input.Role
+				}
+				return true
+			}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&user).Updates(input).Error; err != nil {
+			return err
+		}
+		if input.MaxLoans != nil {
+			if err := tx.Model(&user).UpdateColumn("max_loans", *input.MaxLoans).Error; err != nil {
+				return err
+			}
+		}
+		if input.Email != nil {
+			return tx.Model(&user).UpdateColumn("email", *input.Email).Error
+		}
+		return nil
+	}); err != nil {
+		manageError(c, err)
 		return
 	}
-	db.Model(&user).Updates(input)
+
+	// Logs the user out everywhere, so that the new role is granted on login,
+	// and the old password no longer works
+	if roleChanged || passwordChanged {
+		if ok := revokeSessions(c, user.ID); !ok {
+			return
+		}
+	}
 
 	logger := c.MustGet("logger").(*zap.SugaredLogger)
 	logger.Infof("Updated user %v", user.ID)
@@ -102,9 +251,26 @@ func RemoveUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
 		return
 	}
+	if ok := checkManage(c, db, user, true); !ok {
+		return
+	}
 
 	userID := user.ID
-	db.Delete(&user)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := keepsManager(tx, func(u *models.User) bool { return u.ID != userID }); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error
+	}); err != nil {
+		manageError(c, err)
+		return
+	}
+	if ok := revokeSessions(c, userID); !ok {
+		return
+	}
 
 	logger := c.MustGet("logger").(*zap.SugaredLogger)
 	logger.Infof("Removed user %v", userID)
@@ -112,15 +278,31 @@ func RemoveUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
+var userListQuery = listQuery{
+	sortKeys:     []string{"id", "username", "role", "category", "state"},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
 // ShowUsers shows all users in the library
-// GET /admin/users
+// Users can be filtered by state using the query string, e.g. pending
+// GET /admin/users?state=:state
 func ShowUsers(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
+	chain := db
+	if state := c.Query("state"); state != "" {
+		chain = chain.Where("state = ?", state)
+	}
+
+	chain, paging, ok := paginate(c, chain, &models.User{}, userListQuery)
+	if !ok {
+		return
+	}
 	var users []models.User
-	db.Find(&users)
+	chain.Find(&users)
 
-	c.JSON(http.StatusOK, gin.H{"data": users})
+	respondList(c, users, paging)
 }
 
 // ShowUser shows the user of given ID
@@ -136,3 +318,227 @@ func ShowUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": user})
 }
+
+// ApproveUser activates the account of a user pending approval
+// POST /admin/users/:id/approve
+func ApproveUser(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+	if !user.IsPending() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotPending.Error()})
+		return
+	}
+
+	// Saves the fields changed only
+	if err := db.Model(&user).UpdateColumns(map[string]interface{}{
+		"state":        models.UserActive,
+		"state_reason": "",
+		"state_set_by": currentUserID(c),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Approved user %v", user.ID)
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// SetUserState activates, suspends or bans a user, along with the reason
+// A user suspended or banned is logged out everywhere, and unable to log in
+// or use API tokens until active again
+// PUT /admin/users/:id/state
+func SetUserState(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+
+	// Validates input
+	var input SetUserStateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateState(input.State, input.Until, time.Now().Local()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok := checkManage(c, db, user, true); !ok {
+		return
+	}
+	if input.State != models.UserSuspended {
+		input.Until = nil
+	}
+
+	// Saves the fields changed only
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if input.State != models.UserActive {
+			if err := keepsManager(tx, func(u *models.User) bool {
+				if u.ID == user.ID {
+					u.State, u.SuspendedUntil = input.State, input.Until
+				}
+				return true
+			}); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user).UpdateColumns(map[string]interface{}{
+			"state":           input.State,
+			"state_reason":    strings.TrimSpace(input.Reason),
+			"state_set_by":    currentUserID(c),
+			"suspended_until": input.Until,
+		}).Error
+	}); err != nil {
+		manageError(c, err)
+		return
+	}
+	if input.State != models.UserActive {
+		if ok := revokeSessions(c, user.ID); !ok {
+			return
+		}
+	}
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("Set the state of user %v to %v: %v", user.ID, input.State, user.StateReason)
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// ResetPassword sets a random temporary password of a user, which is returned
+// in the password field, and can't be shown again
+// The user is logged out everywhere, and has to set a new password on next
+// login before doing anything else
+// POST /admin/users/:id/reset-password
+func ResetPassword(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUserNotFound.Error()})
+		return
+	}
+	if ok := checkManage(c, db, user, false); !ok {
+		return
+	}
+
+	password, err := models.NewTemporaryPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hash := password
+	if err := models.EncryptPassword(&hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Saves the fields changed only
+	if err := db.Model(&user).UpdateColumns(map[string]interface{}{
+		"password":             hash,
+		"must_change_password": true,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if ok := revokeSessions(c, user.ID); !ok {
+		return
+	}
+	// Lets the user log in with the temporary password right away
+	db.Where("kind = ? AND target = ?", models.LoginFailureUsername, user.Username).Delete(models.LoginFailure{})
+
+	logger := c.MustGet("logger").(*zap.SugaredLogger)
+	logger.Infof("User %v reset the password of user %v", currentUserID(c), user.ID)
+
+	c.JSON(http.StatusOK, gin.H{"data": user, "password": password})
+}
+
+// checkGrant checks if the role exists, and grants only the permissions held
+// by the current user, or responds with the error
+func checkGrant(c *gin.Context, db *gorm.DB, name string) bool {
+	var role models.Role
+	if err := db.Where("name = ?", name).First(&role).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrRoleNotFound.Error()})
+		return false
+	}
+	if !currentRole(c).Covers(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrGrantDenied.Error()})
+		return false
+	}
+	return true
+}
+
+// checkManage checks if the user only holds the permissions held by the
+// current user, and is not the current user if the role is to be changed or
+// the user is to be removed, or responds with the error
+func checkManage(c *gin.Context, db *gorm.DB, user models.User, changesRole bool) bool {
+	if changesRole && user.ID == currentUserID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrManageSelf.Error()})
+		return false
+	}
+	if !currentRole(c).Covers(findRole(db, user.RoleName())) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrManageDenied.Error()})
+		return false
+	}
+	return true
+}
+
+// keepsManager checks if an active user still holds users.manage once the
+// users are changed by change, which returns false if the user is removed
+// The users holding it are locked until the end of the transaction, so that
+// concurrent changes, e.g. two admins banning each other, are checked one
+// after another rather than both allowed
+func keepsManager(tx *gorm.DB, change func(user *models.User) bool) error {
+	var roles []models.Role
+	if err := tx.Find(&roles).Error; err != nil {
+		return err
+	}
+	managing := make(map[string]bool)
+	var names []string
+	for _, role := range roles {
+		if role.Has(models.PermUsersManage) {
+			managing[role.Name] = true
+			names = append(names, role.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var managers []models.User
+	if err := forUpdate(tx).Where("role IN (?)", names).Find(&managers).Error; err != nil {
+		return err
+	}
+	now := time.Now().Local()
+	before, after := false, false
+	for _, manager := range managers {
+		before = before || manager.AccountState(now) == models.UserActive
+		if change(&manager) && managing[manager.RoleName()] && manager.AccountState(now) == models.UserActive {
+			after = true
+		}
+	}
+	// Changes are not to blame if there's no manager already
+	if before && !after {
+		return ErrLastManager
+	}
+	return nil
+}
+
+// manageError sends the error of changing a user, where leaving no manager is
+// forbidden
+func manageError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if err == ErrLastManager {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
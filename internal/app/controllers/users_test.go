@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/migrations"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+)
+
+// newTestDB creates a database on SQLite with all migrations applied, which
+// is removed at the end of the test
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "realms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := models.DbSetup(config.DbConfig{Type: models.DbSQLite, Path: filepath.Join(dir, "realms.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// addTestUser adds a user of the role in the state
+func addTestUser(t *testing.T, db *gorm.DB, username, role, state string) models.User {
+	t.Helper()
+	user := models.User{Username: username, Password: "-", Role: role, State: state}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestKeepsManager(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	remove := func(id uint) func(u *models.User) bool {
+		return func(u *models.User) bool { return u.ID != id }
+	}
+	setRole := func(id uint, role string) func(u *models.User) bool {
+		return func(u *models.User) bool {
+			if u.ID == id {
+				u.Role = role
+			}
+			return true
+		}
+	}
+	setState := func(id uint, state string, until *time.Time) func(u *models.User) bool {
+		return func(u *models.User) bool {
+			if u.ID == id {
+				u.State, u.SuspendedUntil = state, until
+			}
+			return true
+		}
+	}
+
+	tests := []struct {
+		name string
+		// The other users besides the admin to change, by role and state
+		others []models.User
+		change func(admin models.User) func(u *models.User) bool
+		want   error
+	}{
+		{
+			name:   "remove the last admin",
+			change: func(admin models.User) func(u *models.User) bool { return remove(admin.ID) },
+			want:   ErrLastManager,
+		},
+		{
+			name:   "remove an admin of two",
+			others: []models.User{{Role: models.RoleAdmin, State: models.UserActive}},
+			change: func(admin models.User) func(u *models.User) bool { return remove(admin.ID) },
+		},
+		{
+			name:   "demote the last admin",
+			others: []models.User{{Role: "librarian", State: models.UserActive}},
+			change: func(admin models.User) func(u *models.User) bool { return setRole(admin.ID, "librarian") },
+			want:   ErrLastManager,
+		},
+		{
+			name:   "demote an admin along with a custom role managing users",
+			others: []models.User{{Role: "manager", State: models.UserActive}},
+			change: func(admin models.User) func(u *models.User) bool { return setRole(admin.ID, models.RoleUser) },
+		},
+		{
+			name:   "ban the last admin",
+			change: func(admin models.User) func(u *models.User) bool { return setState(admin.ID, models.UserBanned, nil) },
+			want:   ErrLastManager,
+		},
+		{
+			name: "suspend the last admin",
+			change: func(admin models.User) func(u *models.User) bool {
+				return setState(admin.ID, models.UserSuspended, &future)
+			},
+			want: ErrLastManager,
+		},
+		{
+			name:   "ban an admin while the other is banned",
+			others: []models.User{{Role: models.RoleAdmin, State: models.UserBanned}},
+			change: func(admin models.User) func(u *models.User) bool { return setState(admin.ID, models.UserBanned, nil) },
+			want:   ErrLastManager,
+		},
+		{
+			name:   "ban an admin while the other is pending",
+			others: []models.User{{Role: models.RoleAdmin, State: models.UserPending}},
+			change: func(admin models.User) func(u *models.User) bool { return setState(admin.ID, models.UserBanned, nil) },
+			want:   ErrLastManager,
+		},
+		{
+			name:   "ban an admin while the suspension of the other has ended",
+			others: []models.User{{Role: models.RoleAdmin, State: models.UserSuspended, SuspendedUntil: &past}},
+			change: func(admin models.User) func(u *models.User) bool { return setState(admin.ID, models.UserBanned, nil) },
+		},
+		{
+			name:   "change a user not managing users",
+			others: []models.User{{Role: "librarian", State: models.UserActive}},
+			change: func(admin models.User) func(u *models.User) bool { return setRole(admin.ID+1, models.RoleUser) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if err := db.Create(&models.Role{Name: "manager", Permissions: []string{models.PermUsersManage}}).Error; err != nil {
+				t.Fatal(err)
+			}
+			admin := addTestUser(t, db, "admin", models.RoleAdmin, models.UserActive)
+			for i, other := range tt.others {
+				other.Username = "other" + string(rune('a'+i))
+				other.Password = "-"
+				if err := db.Create(&other).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return keepsManager(tx, tt.change(admin))
+			})
+			if err != tt.want {
+				t.Errorf("keepsManager = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepsManagerWithoutManagers(t *testing.T) {
+	// A change isn't refused for leaving no manager if there was none
+	db := newTestDB(t)
+	user := addTestUser(t, db, "admin", models.RoleAdmin, models.UserBanned)
+	if err := keepsManager(db, func(u *models.User) bool { return u.ID != user.ID }); err != nil {
+		t.Errorf("keepsManager = %v, want nil", err)
+	}
+}
+
+func TestKeepsManagerConcurrently(t *testing.T) {
+	// Two admins removing each other at the same time, where only one of them
+	// is allowed
+	db := newTestDB(t)
+	admins := []models.User{
+		addTestUser(t, db, "alice", models.RoleAdmin, models.UserActive),
+		addTestUser(t, db, "bob", models.RoleAdmin, models.UserActive),
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(admins))
+	for i := range admins {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			target := admins[1-i]
+			errs[i] = db.Transaction(func(tx *gorm.DB) error {
+				if err := keepsManager(tx, func(u *models.User) bool { return u.ID != target.ID }); err != nil {
+					return err
+				}
+				// Gives the other a chance to check in the meantime
+				time.Sleep(10 * time.Millisecond)
+				return tx.Delete(&target).Error
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	removed, refused := 0, 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			removed++
+		case ErrLastManager:
+			refused++
+		default:
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	var count uint
+	db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count)
+	if removed != 1 || refused != 1 || count != 1 {
+		t.Errorf("removed %v, refused %v, %v admins left, want 1, 1 and 1", removed, refused, count)
+	}
+}
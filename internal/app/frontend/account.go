@@ -0,0 +1,185 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type registerModel struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+}
+
+type profileModel struct {
+	CurrentPassword string  `json:"current_password"`
+	Password        string  `json:"password,omitempty"`
+	DisplayName     *string `json:"display_name,omitempty"`
+	Email           *string `json:"email,omitempty"`
+	Phone           *string `json:"phone,omitempty"`
+}
+
+// Register creates a library account for a new user
+func Register(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input registerModel
+
+	fmt.Print("Enter Username: ")
+	scanner.Scan()
+	input.Username = strings.TrimSpace(scanner.Text())
+	if input.Username == "" {
+		fmt.Println("Username shouldn't be empty")
+		return ErrInvalidInput
+	}
+
+	password, ok := getNewPassword("Enter Password")
+	if !ok {
+		return ErrInvalidInput
+	}
+	input.Password = password
+
+	fmt.Print("Enter Display Name (optional): ")
+	scanner.Scan()
+	input.DisplayName = strings.TrimSpace(scanner.Text())
+
+	fmt.Print("Enter Email (optional): ")
+	scanner.Scan()
+	input.Email = strings.TrimSpace(scanner.Text())
+
+	fmt.Print("Enter Phone (optional): ")
+	scanner.Scan()
+	input.Phone = strings.TrimSpace(scanner.Text())
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, &input, URL+"/register")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		user, _ := dataBody.(map[string]interface{})
+		if user["state"] == "pending" {
+			fmt.Printf("Successfully registered as %v, please wait for an admin to approve your account\n", input.Username)
+		} else {
+			fmt.Printf("Successfully registered as %v, you may log in now\n", input.Username)
+		}
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ChangePassword changes the password of the current logged-in user
+func ChangePassword(jar *cookiejar.Jar) error {
+	return setNewPassword(jar, getPassword("Enter Current Password"))
+}
+
+// setNewPassword asks for a new password, and changes the password of the
+// current logged-in user confirmed by the current one
+func setNewPassword(jar *cookiejar.Jar, current string) error {
+	var input profileModel
+
+	input.CurrentPassword = current
+	password, ok := getNewPassword("Enter New Password")
+	if !ok {
+		return ErrInvalidInput
+	}
+	input.Password = password
+
+	return sendProfile(jar, &input, "Successfully changed your password")
+}
+
+// UpdateProfile changes the display name and contact details of the current
+// logged-in user
+func UpdateProfile(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input profileModel
+
+	fmt.Println("(leave blank to keep as is, or enter - to remove)")
+	fields := []struct {
+		prompt string
+		value  **string
+	}{
+		{"Display Name", &input.DisplayName},
+		{"Email", &input.Email},
+		{"Phone", &input.Phone},
+	}
+	for _, field := range fields {
+		fmt.Printf("Enter %v: ", field.prompt)
+		scanner.Scan()
+		switch text := strings.TrimSpace(scanner.Text()); text {
+		case "":
+		case "-":
+			blank := ""
+			*field.value = &blank
+		default:
+			*field.value = &text
+		}
+	}
+
+	input.CurrentPassword = getPassword("Enter Current Password")
+
+	return sendProfile(jar, &input, "Successfully updated your profile")
+}
+
+// sendProfile sends the changes of the profile, and prints the message if
+// succeeded
+func sendProfile(jar *cookiejar.Jar, input *profileModel, message string) error {
+	// Sends a PATCH request
+	res, err := sendRequest("PATCH", jar, input, URL+"/user/me")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Println(message)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// getPassword reads a password from the terminal without echoing it
+func getPassword(prompt string) string {
+	fmt.Print(prompt + ": ")
+	bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	return string(bytePassword)
+}
+
+// getNewPassword reads a new password twice, and reports if it's not empty
+// and both match
+func getNewPassword(prompt string) (string, bool) {
+	password := getPassword(prompt)
+	if password == "" {
+		fmt.Println("Password shouldn't be empty")
+		return "", false
+	}
+	if password != getPassword(prompt+" again") {
+		fmt.Println("Password doesn't match")
+		return "", false
+	}
+	return password, true
+}
@@ -3,7 +3,6 @@ package frontend
 import (
 	"bufio"
 	"fmt"
-	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"os"
@@ -16,9 +15,7 @@ import (
 // Login helps the user log in to his/her library account
 func Login(jar *cookiejar.Jar) error {
 	// Uses cookiejar to manage the cookies
-	client := &http.Client{
-		Jar: jar,
-	}
+	client := newClient(jar)
 
 	// Sends a POST request
 	username, password := getCredentials()
@@ -40,6 +37,10 @@ func Login(jar *cookiejar.Jar) error {
 	}
 	if _, ok := data["data"]; ok {
 		fmt.Printf("Welcome %v!\n", username)
+		if reset, _ := data["must_change_password"].(bool); reset {
+			fmt.Println("Your password has been reset by an admin, please set a new one.")
+			return setNewPassword(jar, password)
+		}
 	} else if errBody, ok := data["error"]; ok {
 		fmt.Println(errBody)
 	}
@@ -49,9 +50,7 @@ func Login(jar *cookiejar.Jar) error {
 // Logout helps the user log out of his/her library account
 func Logout(jar *cookiejar.Jar) error {
 	// Uses cookiejar to manage the cookies
-	client := &http.Client{
-		Jar: jar,
-	}
+	client := newClient(jar)
 
 	// Sends a GET request
 	logoutURL := URL + "/logout"
@@ -78,9 +77,7 @@ func Logout(jar *cookiejar.Jar) error {
 // Me shows the currently logged-in user
 func Me(jar *cookiejar.Jar) error {
 	// Uses cookiejar to manage the cookies
-	client := &http.Client{
-		Jar: jar,
-	}
+	client := newClient(jar)
 
 	// Sends a GET request
 	meURL := URL + "/user/me"
@@ -98,6 +95,20 @@ func Me(jar *cookiejar.Jar) error {
 	}
 	if dataBody, ok := data["data"]; ok {
 		fmt.Printf("Current user ID: %v\n", dataBody)
+		if role, ok := data["role"].(map[string]interface{}); ok {
+			fmt.Printf("Role: %v\n", role["name"])
+			if permissions, _ := role["permissions"].([]interface{}); len(permissions) > 0 {
+				fmt.Printf("Permissions: %v\n", joinList(permissions))
+			}
+		}
+		if quota, ok := data["quota"].(map[string]interface{}); ok {
+			if quota["remaining"] == nil {
+				fmt.Printf("Books borrowed: %v (no limit)\n", quota["loans"])
+			} else {
+				fmt.Printf("Books borrowed: %v/%v, %v more allowed\n",
+					quota["loans"], quota["limit"], quota["remaining"])
+			}
+		}
 	} else if errBody, ok := data["error"]; ok {
 		fmt.Println(errBody)
 	}
@@ -107,9 +118,7 @@ func Me(jar *cookiejar.Jar) error {
 // Status shows current login status
 func Status(jar *cookiejar.Jar) error {
 	// Uses cookiejar to manage the cookies
-	client := &http.Client{
-		Jar: jar,
-	}
+	client := newClient(jar)
 
 	// Sends a GET request
 	statURL := URL + "/status"
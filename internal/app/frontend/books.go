@@ -4,16 +4,29 @@ import (
 	"bufio"
 	"fmt"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 )
 
 type bookModel struct {
-	ID        uint   `json:"id,omitempty"`
-	Title     string `json:"title,omitempty"`
-	Author    string `json:"author,omitempty"`
-	Publisher string `json:"publisher,omitempty"`
-	ISBN      string `json:"isbn,omitempty"`
+	ID        uint     `json:"id,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Authors   []string `json:"authors,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+	ISBN      string   `json:"isbn,omitempty"`
+	Year      uint     `json:"year,omitempty"`
+	Edition   string   `json:"edition,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Pages     uint     `json:"pages,omitempty"`
+	Series    string   `json:"series,omitempty"`
+	Volume    uint     `json:"volume,omitempty"`
+	Subjects  []string `json:"subjects,omitempty"`
+
+	AdditionalCopy bool   `json:"additional_copy,omitempty"`
+	Barcode        string `json:"barcode,omitempty"`
+	Location       string `json:"location,omitempty"`
 }
 
 type messageInput struct {
@@ -27,30 +40,65 @@ func AddBook(jar *cookiejar.Jar) error {
 		return err
 	}
 
-	// Sends a POST request
-	res, err := sendBookRequest("POST", jar, &input, 0, addMode)
-	if err != nil {
-		fmt.Println(ErrRequestFailed.Error())
-		return err
-	}
-	defer res.Body.Close()
+	for {
+		// Sends a POST request
+		res, err := sendBookRequest("POST", jar, &input, 0, addMode)
+		if err != nil {
+			fmt.Println(ErrRequestFailed.Error())
+			return err
+		}
+		data, err := readResponse(res)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
 
-	// Outputs the response
-	data, err := readResponse(res)
-	if err != nil {
-		return err
-	}
-	if dataBody, ok := data["data"]; ok {
-		book, ok := dataBody.(map[string]interface{})
+		// Outputs the response
+		if dataBody, ok := data["data"]; ok {
+			book, ok := dataBody.(map[string]interface{})
+			if !ok {
+				fmt.Println(ErrInvalidResponse.Error())
+				return nil
+			}
+			item, hasCopy := data["copy"].(map[string]interface{})
+			switch {
+			case input.AdditionalCopy:
+				fmt.Printf("Successfully added copy %v of book %v\n", item["id"], book["id"])
+			case hasCopy:
+				fmt.Printf("Successfully added book %v with copy %v\n", book["id"], item["id"])
+			default:
+				fmt.Printf("Successfully added book %v\n", book["id"])
+			}
+			return nil
+		}
+		errBody, ok := data["error"]
 		if !ok {
-			fmt.Println(ErrInvalidResponse.Error())
 			return nil
 		}
-		fmt.Printf("Successfully added book %v\n", book["id"])
-	} else if errBody, ok := data["error"]; ok {
 		fmt.Println(errBody)
+
+		// Offers to add a copy to the existing book of the same ISBN and edition
+		bookID, ok := data["book_id"]
+		if !ok || input.AdditionalCopy {
+			return nil
+		}
+		scanner := bufio.NewScanner(os.Stdin)
+		fmt.Printf("Add a copy of book %v instead? (y/n): ", bookID)
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			return nil
+		}
+		input.AdditionalCopy = true
+		if input.Barcode == "" {
+			fmt.Print("Barcode (required): ")
+			scanner.Scan()
+			input.Barcode = strings.TrimSpace(scanner.Text())
+			if input.Barcode == "" {
+				fmt.Println("The copy must have a barcode!")
+				return ErrInvalidInput
+			}
+		}
 	}
-	return nil
 }
 
 // UpdateBook updates data of a book
@@ -113,24 +161,8 @@ func RemoveBook(jar *cookiejar.Jar) error {
 
 // ShowBooks shows all books in the library
 func ShowBooks() error {
-	// Sends a GET request
-	res, err := sendBookRequest("GET", nil, nil, 0, showMode)
-	if err != nil {
-		fmt.Println(ErrRequestFailed.Error())
-		return err
-	}
-	defer res.Body.Close()
-
-	// Outputs the response
-	data, err := readResponse(res)
-	if err != nil {
-		return err
-	}
-	if dataBody, ok := data["data"]; ok {
-		books := dataBody.([]interface{})
-		printBooks(books)
-	}
-	return nil
+	// Sends GET requests page by page
+	return showPages("GET", nil, nil, URL+"/books", printBooks)
 }
 
 // ShowBook shows the book of given ID
@@ -163,33 +195,21 @@ func ShowBook() error {
 	return nil
 }
 
-// FindBooks finds books by title / author / ISBN
+// FindBooks finds books by a full-text query, ranked by relevance
 func FindBooks(jar *cookiejar.Jar) error {
-	var input bookModel
-	if err := getBookInput(&input, findMode); err != nil {
-		return err
-	}
-
-	// Sends a POST request
-	res, err := sendBookRequest("POST", jar, &input, 0, findMode)
-	if err != nil {
-		fmt.Println(ErrRequestFailed.Error())
-		return err
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println(`(e.g. go programming, "the go language", prog*, go OR golang, go -python, author:kernighan)`)
+	fmt.Print("Query: ")
+	scanner.Scan()
+	query := strings.TrimSpace(scanner.Text())
+	if query == "" {
+		fmt.Println("The query must not be empty!")
+		return ErrInvalidInput
 	}
-	defer res.Body.Close()
 
-	// Outputs the response
-	data, err := readResponse(res)
-	if err != nil {
-		return err
-	}
-	if dataBody, ok := data["data"]; ok {
-		books := dataBody.([]interface{})
-		printBooks(books)
-	} else if errBody, ok := data["error"]; ok {
-		fmt.Println(errBody)
-	}
-	return nil
+	// Sends GET requests page by page
+	searchURL := URL + "/books/search?q=" + url.QueryEscape(query)
+	return showPages("GET", jar, nil, searchURL, printBooks)
 }
 
 func getBookInput(input *bookModel, mode int) error {
@@ -208,23 +228,87 @@ func getBookInput(input *bookModel, mode int) error {
 	}
 	input.Title = title
 
-	fmt.Print("Author (optional): ")
+	fmt.Print("Authors (optional, separated by ';'): ")
 	scanner.Scan()
-	input.Author = scanner.Text()
+	input.Authors = splitList(scanner.Text())
 
-	if mode != findMode {
-		fmt.Print("Publisher (optional): ")
-		scanner.Scan()
-		input.Publisher = scanner.Text()
-	}
+	fmt.Print("Publisher (optional): ")
+	scanner.Scan()
+	input.Publisher = scanner.Text()
 
 	fmt.Print("ISBN (optional): ")
 	scanner.Scan()
 	input.ISBN = scanner.Text()
 
+	var err error
+	if input.Year, err = getNumberInput(scanner, "Year (optional): "); err != nil {
+		return err
+	}
+
+	fmt.Print("Edition (optional): ")
+	scanner.Scan()
+	input.Edition = scanner.Text()
+
+	fmt.Print("Language (optional): ")
+	scanner.Scan()
+	input.Language = scanner.Text()
+
+	if input.Pages, err = getNumberInput(scanner, "Pages (optional): "); err != nil {
+		return err
+	}
+
+	fmt.Print("Series (optional): ")
+	scanner.Scan()
+	input.Series = scanner.Text()
+
+	if input.Volume, err = getNumberInput(scanner, "Volume (optional): "); err != nil {
+		return err
+	}
+
+	fmt.Print("Subjects (optional, separated by ';'): ")
+	scanner.Scan()
+	input.Subjects = splitList(scanner.Text())
+
+	if mode == addMode {
+		fmt.Print("Barcode of the first copy (optional): ")
+		scanner.Scan()
+		input.Barcode = strings.TrimSpace(scanner.Text())
+		fmt.Print("Location (optional): ")
+		scanner.Scan()
+		input.Location = scanner.Text()
+	}
+
 	return nil
 }
 
+// getNumberInput reads a non-negative integer, which is 0 if left blank
+func getNumberInput(scanner *bufio.Scanner, prompt string) (uint, error) {
+	fmt.Print(prompt)
+	scanner.Scan()
+	text := strings.TrimSpace(scanner.Text())
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(text, 10, 32)
+	if err != nil {
+		fmt.Println("Please enter a number!")
+		return 0, ErrInvalidInput
+	}
+	return uint(n), nil
+}
+
+// splitList splits a list of items separated by ";", which is nil if left
+// blank
+func splitList(text string) []string {
+	var items []string
+	for _, item := range strings.Split(text, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getMessageInput(input *messageInput) error {
 	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Print("Explanation (optional): ")
@@ -267,7 +351,37 @@ func printBooks(books []interface{}) {
 func printBook(book map[string]interface{}) {
 	fmt.Printf("Book %v\n", book["id"])
 	fmt.Printf("   Title:     %v\n", book["title"])
-	fmt.Printf("   Author:    %v\n", book["author"])
+	fmt.Printf("   Authors:   %v\n", book["author"])
 	fmt.Printf("   Publisher: %v\n", book["publisher"])
 	fmt.Printf("   ISBN:      %v\n", book["isbn"])
+	fmt.Printf("   Year:      %v\n", formatNumber(book["year"]))
+	fmt.Printf("   Edition:   %v\n", book["edition"])
+	fmt.Printf("   Language:  %v\n", book["language"])
+	fmt.Printf("   Pages:     %v\n", formatNumber(book["pages"]))
+	if volume := formatNumber(book["volume"]); volume != "" {
+		fmt.Printf("   Series:    %v, Vol. %v\n", book["series"], volume)
+	} else {
+		fmt.Printf("   Series:    %v\n", book["series"])
+	}
+	fmt.Printf("   Subjects:  %v\n", joinNames(book["subjects"]))
+}
+
+// joinNames joins the names of a list of authors or subjects
+func joinNames(list interface{}) string {
+	items, _ := list.([]interface{})
+	names := make([]string, 0, len(items))
+	for _, elem := range items {
+		if item, ok := elem.(map[string]interface{}); ok {
+			names = append(names, fmt.Sprint(item["name"]))
+		}
+	}
+	return strings.Join(names, "; ")
+}
+
+// formatNumber formats a number in the response, which is blank if 0
+func formatNumber(n interface{}) string {
+	if f, ok := n.(float64); ok && f != 0 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return ""
 }
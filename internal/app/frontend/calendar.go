@@ -0,0 +1,278 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type openingHoursModel struct {
+	Opens  string `json:"opens"`
+	Closes string `json:"closes"`
+	Closed bool   `json:"closed"`
+}
+
+type closedDayModel struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// ShowOpeningHours shows the opening hours of all weekdays
+func ShowOpeningHours(jar *cookiejar.Jar) error {
+	// Sends a GET request
+	res, err := sendRequest("GET", jar, nil, URL+"/admin/calendar/hours")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		hours, ok := dataBody.([]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		printOpeningHours(hours)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// SetOpeningHours sets the opening hours of a weekday
+func SetOpeningHours(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("(0 for Sunday, 1 for Monday, ..., 6 for Saturday)")
+	fmt.Print("Weekday: ")
+	scanner.Scan()
+	weekday := strings.TrimSpace(scanner.Text())
+
+	var input openingHoursModel
+	fmt.Print("Closed all day (y/n): ")
+	scanner.Scan()
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "y", "yes":
+		input.Closed = true
+	case "n", "no":
+		fmt.Print("Opens (hh:mm): ")
+		scanner.Scan()
+		input.Opens = strings.TrimSpace(scanner.Text())
+		fmt.Print("Closes (hh:mm): ")
+		scanner.Scan()
+		input.Closes = strings.TrimSpace(scanner.Text())
+	default:
+		fmt.Println("Please answer y or n")
+		return ErrInvalidInput
+	}
+
+	// Sends a PUT request
+	res, err := sendRequest("PUT", jar, &input, URL+"/admin/calendar/hours/"+url.PathEscape(weekday))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Println("Successfully set the opening hours")
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ShowClosedDays shows the closed days of the library in a range of dates
+func ShowClosedDays(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	query := url.Values{}
+
+	fmt.Print("From (yyyy-mm-dd, optional): ")
+	scanner.Scan()
+	if from := strings.TrimSpace(scanner.Text()); from != "" {
+		query.Set("from", from)
+	}
+	fmt.Print("To (yyyy-mm-dd, optional): ")
+	scanner.Scan()
+	if to := strings.TrimSpace(scanner.Text()); to != "" {
+		query.Set("to", to)
+	}
+
+	return showPages("GET", jar, nil, URL+"/admin/calendar/closed?"+query.Encode(), printClosedDays)
+}
+
+// AddClosedDay closes the library on a date
+func AddClosedDay(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input closedDayModel
+
+	fmt.Print("Date (yyyy-mm-dd): ")
+	scanner.Scan()
+	input.Date = strings.TrimSpace(scanner.Text())
+	fmt.Print("Name (optional): ")
+	scanner.Scan()
+	input.Name = strings.TrimSpace(scanner.Text())
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, &input, URL+"/admin/calendar/closed")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully closed the library on %v\n", input.Date)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// RemoveClosedDay opens the library on a date closed before
+func RemoveClosedDay(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Date (yyyy-mm-dd): ")
+	scanner.Scan()
+	date := strings.TrimSpace(scanner.Text())
+
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/admin/calendar/closed/"+url.PathEscape(date))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully opened the library on %v\n", date)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ImportCalendar closes the library on the days of the events in an
+// iCalendar file (.ics)
+func ImportCalendar(jar *cookiejar.Jar, filename string) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	if filename == "" {
+		fmt.Print("File (.ics): ")
+		scanner.Scan()
+		filename = strings.TrimSpace(scanner.Text())
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil
+	}
+	defer file.Close()
+
+	fmt.Print("Dry run? (y/n): ")
+	scanner.Scan()
+	dryRun := strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+
+	// Sends a POST request with the file
+	req, err := http.NewRequest("POST", URL+"/admin/calendar/import?dry_run="+fmt.Sprint(dryRun), file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar")
+	client := newClient(jar)
+	res, err := client.Do(req)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		report, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		printCalendarImportReport(report)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printOpeningHours(hours []interface{}) {
+	width := 12
+	fmt.Printf("%-*s%s\n", width, "Weekday", "Hours")
+	fmt.Println(strings.Repeat("-", width+12))
+	for _, elem := range hours {
+		h := elem.(map[string]interface{})
+		weekday, _ := h["weekday"].(float64)
+		fmt.Printf("%-*s", width, time.Weekday(weekday))
+		if h["closed"] == true {
+			fmt.Println("Closed")
+		} else {
+			fmt.Printf("%v - %v\n", h["opens"], h["closes"])
+		}
+	}
+}
+
+func printClosedDays(days []interface{}) {
+	width := 14
+	fmt.Printf("%-*s%s\n", width, "Date", "Name")
+	fmt.Println(strings.Repeat("-", width+20))
+	for _, elem := range days {
+		day := elem.(map[string]interface{})
+		fmt.Printf("%-*v%v\n", width, day["date"], day["name"])
+	}
+}
+
+func printCalendarImportReport(report map[string]interface{}) {
+	if report["dry_run"] == true {
+		fmt.Println("Dry run, nothing has been imported")
+		fmt.Printf("%v events in total, %v closed days to be added, %v already closed, %v failed\n",
+			report["events"], report["added"], report["skipped"], report["failed"])
+	} else {
+		fmt.Printf("%v events in total, %v closed days added, %v already closed, %v failed\n",
+			report["events"], report["added"], report["skipped"], report["failed"])
+	}
+	errs, _ := report["errors"].([]interface{})
+	for _, elem := range errs {
+		item := elem.(map[string]interface{})
+		fmt.Printf("   Line %v", item["line"])
+		if summary, ok := item["summary"]; ok {
+			fmt.Printf(" (%v)", summary)
+		}
+		fmt.Printf(": %v\n", item["error"])
+	}
+}
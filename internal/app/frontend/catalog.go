@@ -0,0 +1,146 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hakula139/REALMS/internal/app/catalog"
+)
+
+// ImportBooks adds books in a catalog file to the library
+// The format is guessed by the file extension, i.e. .csv, .jsonl, .mrc or .xml
+func ImportBooks(jar *cookiejar.Jar, filename string) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	filename = getFilename(scanner, filename)
+	format, err := catalog.FormatOf(filename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil
+	}
+	defer file.Close()
+
+	fmt.Print("Dry run? (y/n): ")
+	scanner.Scan()
+	dryRun := strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+
+	// Sends a POST request with the file
+	query := url.Values{}
+	query.Set("format", format)
+	query.Set("dry_run", fmt.Sprint(dryRun))
+	req, err := http.NewRequest("POST", URL+"/admin/books/import?"+query.Encode(), file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", catalog.ContentType(format))
+	client := newClient(jar)
+	res, err := client.Do(req)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		report, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		printImportReport(report)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ExportBooks saves all books in the library to a catalog file
+// The format is guessed by the file extension, i.e. .csv, .jsonl, .mrc or .xml
+func ExportBooks(jar *cookiejar.Jar, filename string) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	filename = getFilename(scanner, filename)
+	format, err := catalog.FormatOf(filename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil
+	}
+
+	// Sends a GET request
+	client := newClient(jar)
+	res, err := client.Get(URL + "/admin/books/export?format=" + format)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the error if any
+	if res.StatusCode != http.StatusOK {
+		data, err := readResponse(res)
+		if err != nil {
+			return err
+		}
+		if errBody, ok := data["error"]; ok {
+			fmt.Println(errBody)
+		}
+		return nil
+	}
+
+	// Writes the catalog to the file
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, res.Body); err != nil {
+		fmt.Println(ErrReadResponseFailed.Error())
+		return err
+	}
+	fmt.Printf("Successfully exported the catalog to %v\n", filename)
+	return nil
+}
+
+func getFilename(scanner *bufio.Scanner, filename string) string {
+	if filename != "" {
+		return filename
+	}
+	fmt.Print("File (.csv / .jsonl / .mrc / .xml): ")
+	scanner.Scan()
+	return strings.TrimSpace(scanner.Text())
+}
+
+func printImportReport(report map[string]interface{}) {
+	if report["dry_run"] == true {
+		fmt.Println("Dry run, nothing has been imported")
+		fmt.Printf("%v rows in total, %v books and %v copies to be added, %v failed\n",
+			report["total"], report["added"], report["copies"], report["failed"])
+	} else {
+		fmt.Printf("%v rows in total, %v books and %v copies added, %v failed\n",
+			report["total"], report["added"], report["copies"], report["failed"])
+	}
+	errs, _ := report["errors"].([]interface{})
+	for _, elem := range errs {
+		item := elem.(map[string]interface{})
+		fmt.Printf("   Row %v", item["row"])
+		if title, ok := item["title"]; ok {
+			fmt.Printf(" (%v)", title)
+		}
+		fmt.Printf(": %v\n", item["error"])
+	}
+}
@@ -0,0 +1,173 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+)
+
+type circulationInput struct {
+	UserID  uint   `json:"user_id,omitempty"`
+	BookID  uint   `json:"book_id,omitempty"`
+	Barcode string `json:"barcode,omitempty"`
+}
+
+// CheckOut lends a book to a user at the circulation desk
+func CheckOut(jar *cookiejar.Jar) error {
+	userID := getUserID()
+	var input circulationInput
+	if err := getCirculationInput(&input); err != nil {
+		return err
+	}
+	input.UserID = uint(userID)
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, &input, URL+"/admin/circulation/checkout")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		record, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully lent copy %v of book %v to user %v\n",
+			record["copy_id"], record["book_id"], record["user_id"])
+		fmt.Printf("The return date is: %v\n", record["return_date"])
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// Renew extends the deadline to return a book at the circulation desk
+func Renew(jar *cookiejar.Jar) error {
+	var input circulationInput
+	if err := getCirculationInput(&input); err != nil {
+		return err
+	}
+	if input.Barcode == "" {
+		input.UserID = uint(getUserID())
+	}
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, &input, URL+"/admin/circulation/renew")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		record, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully renewed copy %v of book %v for user %v\n",
+			record["copy_id"], record["book_id"], record["user_id"])
+		fmt.Printf("The return date is: %v\n", record["return_date"])
+		fmt.Printf("Extended %v/%v times\n", record["extend_times"], maxExtendTimes)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// CheckIn returns a book to the library at the circulation desk
+func CheckIn(jar *cookiejar.Jar) error {
+	var input circulationInput
+	if err := getCirculationInput(&input); err != nil {
+		return err
+	}
+	if input.Barcode == "" {
+		input.UserID = uint(getUserID())
+	}
+	return checkIn(jar, &input)
+}
+
+// Desk checks in books by barcode consecutively, until an empty line is read
+func Desk(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println("Scan or enter barcodes to check in, press Enter on an empty line to quit")
+	for {
+		fmt.Print("Barcode: ")
+		if !scanner.Scan() {
+			return nil
+		}
+		barcode := strings.TrimSpace(scanner.Text())
+		if barcode == "" {
+			return nil
+		}
+		if err := checkIn(jar, &circulationInput{Barcode: barcode}); err != nil {
+			return err
+		}
+	}
+}
+
+func checkIn(jar *cookiejar.Jar, input *circulationInput) error {
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, input, URL+"/admin/circulation/checkin")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		record, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully checked in copy %v of book %v from user %v\n",
+			record["copy_id"], record["book_id"], record["user_id"])
+		if fine, ok := data["fine"].(map[string]interface{}); ok {
+			fmt.Printf("The book is %v days overdue, the user has been fined %.2f\n",
+				fine["overdue_days"], fine["amount"])
+		}
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func getCirculationInput(input *circulationInput) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Barcode (optional if Book ID given): ")
+	scanner.Scan()
+	input.Barcode = strings.TrimSpace(scanner.Text())
+	if input.Barcode != "" {
+		return nil
+	}
+
+	bookID := getBookID()
+	if bookID <= 0 {
+		fmt.Println("Either a barcode or a book ID is required")
+		return ErrInvalidInput
+	}
+	input.BookID = uint(bookID)
+	return nil
+}
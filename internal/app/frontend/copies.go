@@ -0,0 +1,185 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type copyModel struct {
+	ID       uint   `json:"id,omitempty"`
+	Barcode  string `json:"barcode,omitempty"`
+	Location string `json:"location,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// AddCopy adds a new copy of a book to the library
+func AddCopy(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+	var input copyModel
+	if err := getCopyInput(&input, addMode); err != nil {
+		return err
+	}
+
+	// Sends a POST request
+	res, err := sendCopyRequest("POST", jar, &input, bookID, 0, addMode)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		item, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully added copy %v of book %v\n", item["id"], bookID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// UpdateCopy relabels a copy or changes its status
+func UpdateCopy(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+	copyID := getCopyID()
+	var input copyModel
+	if err := getCopyInput(&input, updateMode); err != nil {
+		return err
+	}
+
+	// Sends a PATCH request
+	res, err := sendCopyRequest("PATCH", jar, &input, bookID, copyID, updateMode)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully updated copy %v of book %v\n", copyID, bookID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// RetireCopy removes a copy from circulation
+func RetireCopy(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+	copyID := getCopyID()
+	var input messageInput
+	if err := getMessageInput(&input); err != nil {
+		return err
+	}
+
+	// Sends a DELETE request
+	res, err := sendCopyRequest("DELETE", jar, &input, bookID, copyID, removeMode)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully retired copy %v of book %v\n", copyID, bookID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ShowCopies shows all copies of a book
+func ShowCopies(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+
+	// Sends GET requests page by page
+	copiesURL := URL + "/admin/books/" + strconv.Itoa(bookID) + "/copies"
+	return showPages("GET", jar, nil, copiesURL, printCopies)
+}
+
+func getCopyInput(input *copyModel, mode int) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	if mode == addMode {
+		fmt.Print("Barcode (required): ")
+	} else {
+		fmt.Print("Barcode (optional): ")
+	}
+	scanner.Scan()
+	barcode := strings.TrimSpace(scanner.Text())
+	if mode == addMode && barcode == "" {
+		fmt.Println("The copy must have a barcode!")
+		return ErrInvalidInput
+	}
+	input.Barcode = barcode
+
+	fmt.Print("Location (optional): ")
+	scanner.Scan()
+	input.Location = scanner.Text()
+
+	fmt.Println("(available / lost / damaged / in_repair)")
+	fmt.Print("Status (optional): ")
+	scanner.Scan()
+	input.Status = strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(regular / reserve / reference)")
+	fmt.Print("Category (optional): ")
+	scanner.Scan()
+	input.Category = strings.TrimSpace(scanner.Text())
+
+	return nil
+}
+
+func getCopyID() int {
+	var copyID int
+	fmt.Print("Copy ID: ")
+	fmt.Scanln(&copyID)
+	return copyID
+}
+
+func printCopies(copies []interface{}) {
+	if len(copies) == 0 {
+		fmt.Println("No copies found")
+		return
+	}
+	width := 25
+	fmt.Printf("%s\t%-*s%-*s%-*s%-s\n",
+		"ID",
+		width, "Barcode",
+		width, "Location",
+		12, "Status",
+		"Category",
+	)
+	fmt.Println(strings.Repeat("-", 30+width*2))
+	for _, elem := range copies {
+		item := elem.(map[string]interface{})
+		fmt.Printf("%v\t", item["id"])
+		fmt.Printf("%-*s", width, slice(item["barcode"].(string), width-2))
+		fmt.Printf("%-*s", width, slice(item["location"].(string), width-2))
+		fmt.Printf("%-*v", 12, item["status"])
+		fmt.Printf("%v\n", item["category"])
+	}
+}
@@ -0,0 +1,124 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ShowFines shows all fines of the user
+func ShowFines(jar *cookiejar.Jar) error {
+	return showFines(jar, URL+"/user/fines")
+}
+
+// ShowAllFines shows all fines in the library, filtered by user ID and status
+func ShowAllFines(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	query := url.Values{}
+
+	fmt.Print("User ID (optional): ")
+	scanner.Scan()
+	if userID := strings.TrimSpace(scanner.Text()); userID != "" {
+		query.Set("user_id", userID)
+	}
+
+	fmt.Println("(unpaid / paid / waived)")
+	fmt.Print("Status (optional): ")
+	scanner.Scan()
+	if status := strings.TrimSpace(scanner.Text()); status != "" {
+		query.Set("status", status)
+	}
+
+	return showFines(jar, URL+"/admin/fines?"+query.Encode())
+}
+
+// PayFine marks a fine as paid
+func PayFine(jar *cookiejar.Jar) error {
+	return resolveFine(jar, "pay")
+}
+
+// WaiveFine marks a fine as waived
+func WaiveFine(jar *cookiejar.Jar) error {
+	return resolveFine(jar, "waive")
+}
+
+func resolveFine(jar *cookiejar.Jar, action string) error {
+	fineID := getFineID()
+	var input messageInput
+	if err := getMessageInput(&input); err != nil {
+		return err
+	}
+
+	// Sends a POST request
+	fineURL := URL + "/admin/fines/" + strconv.Itoa(fineID) + "/" + action
+	res, err := sendRequest("POST", jar, &input, fineURL)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		fine, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully marked fine %v as %v\n", fine["id"], fine["status"])
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func showFines(jar *cookiejar.Jar, finesURL string) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, finesURL, printFines)
+}
+
+func getFineID() int {
+	var fineID int
+	fmt.Print("Fine ID: ")
+	fmt.Scanln(&fineID)
+	return fineID
+}
+
+func printFines(fines []interface{}) {
+	if len(fines) == 0 {
+		fmt.Println("No fines found")
+		return
+	}
+	fmt.Printf("%s\t%s\t  %s\t  %s\t  %s\t  %s\n",
+		"ID",
+		"User ID",
+		"Record ID",
+		"Overdue",
+		"Amount",
+		"Status",
+	)
+	fmt.Println(strings.Repeat("-", 70))
+	unpaid := 0.0
+	for _, elem := range fines {
+		fine := elem.(map[string]interface{})
+		amount, _ := fine["amount"].(float64)
+		fmt.Printf("%v\t", fine["id"])
+		fmt.Printf("%v\t  ", fine["user_id"])
+		fmt.Printf("%v\t  ", fine["record_id"])
+		fmt.Printf("%v days\t  ", fine["overdue_days"])
+		fmt.Printf("%.2f\t  ", amount)
+		fmt.Printf("%v\n", fine["status"])
+		if fine["status"] == "unpaid" {
+			unpaid += amount
+		}
+	}
+	fmt.Printf("Unpaid on this page: %.2f\n", unpaid)
+}
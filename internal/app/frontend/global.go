@@ -10,12 +10,15 @@ const (
 	debugMode = true
 )
 
+// Token is the API token sent in each request instead of the session cookie,
+// which is set by realms --token
+var Token string
+
 const (
 	addMode    = iota
 	updateMode = iota
 	removeMode = iota
 	showMode   = iota
-	findMode   = iota
 
 	showOverdueMode = iota
 	showHistoryMode = iota
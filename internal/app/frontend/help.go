@@ -13,29 +13,88 @@ func ShowHelp() error {
 	printCommand("help", "Shows a list of commands")
 	printCommand("exit", "Quit")
 	fmt.Println()
+	printCommand("register", "Creates a library account")
 	printCommand("login", "Log in to your library account")
 	printCommand("logout", "Log out of your library account")
 	printCommand("status", "Shows the current login status")
 	fmt.Println()
 	printCommand("show books", "Shows all books in the library")
 	printCommand("show book", "Shows the book of given ID")
-	printCommand("find books", "Finds books by title / author / ISBN")
+	printCommand("find books", "Finds books by a full-text query")
 	fmt.Println()
 
-	printRequiredPrivilege("admin")
+	printRequiredPrivilege("catalog.write")
 	printCommand("add book", "Adds a new book to the library")
 	printCommand("update book", "Updates data of a book")
 	printCommand("remove book", "Removes a book from the library")
+	printCommand("import books <file>", "Adds books in a catalog file to the library")
+	printCommand("export books <file>", "Saves all books to a catalog file")
 	fmt.Println()
+	printCommand("add copy", "Adds a new copy of a book to the library")
+	printCommand("update copy", "Relabels a copy or changes its status")
+	printCommand("retire copy", "Removes a copy from circulation")
+	printCommand("show copies", "Shows all copies of a book")
+	fmt.Println()
+
+	printRequiredPrivilege("users.manage")
 	printCommand("add user", "Adds a new user to the database")
 	printCommand("update user", "Updates data of a user")
 	printCommand("remove user", "Removes a user from the database")
 	printCommand("show users", "Shows all users in the library")
 	printCommand("show user", "Shows the user of given ID")
+	printCommand("show pending users", "Shows all users waiting for approval")
+	printCommand("approve user", "Activates the account of a user registered")
+	printCommand("set user state", "Activates, suspends or bans a user")
+	printCommand("reset password", "Sets a temporary password of a user")
+	printCommand("show lockouts", "Shows failed logins of usernames and IPs")
+	printCommand("clear lockout", "Lets a username or IP log in again")
+	fmt.Println()
+	printCommand("show roles", "Shows all roles and their permissions")
+	printCommand("set role", "Creates a role or changes its permissions")
+	printCommand("remove role", "Removes a role granted to no users")
+	fmt.Println()
+
+	printRequiredPrivilege("circulation.desk")
+	printCommand("check out", "Lends a book to a user at the circulation desk")
+	printCommand("renew", "Extends the deadline to return a book at the desk")
+	printCommand("check in", "Returns a book to the library at the desk")
+	printCommand("desk", "Checks in books by barcode consecutively")
+	printCommand("show queue", "Shows the hold queue of a book")
+	fmt.Println()
+	printCommand("show all fines", "Shows all fines in the library")
+	printCommand("pay fine", "Marks a fine as paid")
+	printCommand("waive fine", "Marks a fine as waived")
+	fmt.Println()
+	printCommand("show policies", "Shows the loan policies of all categories")
+	printCommand("set policy", "Sets the loan policy of a pair of categories")
+	printCommand("reset policy", "Reverts a loan policy to the library defaults")
+	fmt.Println()
+	printCommand("show hours", "Shows the opening hours of each weekday")
+	printCommand("set hours", "Sets the opening hours of a weekday")
+	printCommand("show closed days", "Shows the days when the library is closed")
+	printCommand("add closed day", "Closes the library on a date")
+	printCommand("remove closed day", "Opens the library on a closed date")
+	printCommand("import ical <file>", "Closes the library on the events in an .ics file")
+	fmt.Println()
+	printCommand("send notices", "Sends the notices due now without waiting")
+	fmt.Println()
+
+	printRequiredPrivilege("reports.read")
+	printCommand("show loans", "Shows all books on loan in the library")
+	printCommand("show all overdue", "Shows all overdue books in the library")
+	printCommand("show user history", "Shows all records of the user of given ID")
+	printCommand("show all notices", "Shows all due-date reminders and overdue notices sent")
 	fmt.Println()
 
 	printRequiredPrivilege("user")
 	printCommand("me", "Shows the current logged-in user")
+	printCommand("passwd", "Changes your password")
+	printCommand("profile", "Changes your display name and contact details")
+	printCommand("show sessions", "Shows where you've logged in")
+	printCommand("logout all", "Log out everywhere, including here")
+	printCommand("show tokens", "Shows your API tokens")
+	printCommand("create token", "Creates an API token for scripts, see realms --help")
+	printCommand("revoke token", "Revokes an API token")
 	fmt.Println()
 	printCommand("borrow book", "Borrows a book from the library")
 	printCommand("return book", "Returns a book to the library")
@@ -44,6 +103,15 @@ func ShowHelp() error {
 	printCommand("show list", "Shows all books that you've borrowed")
 	printCommand("show overdue", "Shows all overdue books that you've borrowed")
 	printCommand("show history", "Shows all your records")
+	fmt.Println()
+	printCommand("place hold", "Places a hold on a book with no available copies")
+	printCommand("cancel hold", "Cancels the hold on a book")
+	printCommand("show holds", "Shows all your holds")
+	printCommand("show fines", "Shows all your fines")
+	fmt.Println()
+	printCommand("show notifications", "Shows your email and the notices you receive")
+	printCommand("set notifications", "Chooses which notices you receive")
+	printCommand("show notices", "Shows all notices sent to you")
 
 	return nil
 }
@@ -52,19 +120,17 @@ func printRequiredPrivilege(level string) {
 	indent := 3
 	fmt.Print(strings.Repeat(" ", indent))
 	switch level {
+	case "public":
+		fmt.Println("Public:")
 	case "user":
 		fmt.Println("User privilege required:")
-	case "admin":
-		fmt.Println("Admin privilege required:")
-	case "root":
-		fmt.Println("Super Admin privilege required:")
 	default:
-		fmt.Println("Public:")
+		fmt.Printf("Permission %v required:\n", level)
 	}
 }
 
 func printCommand(cmd, usage string) {
 	indent := 6
 	fmt.Print(strings.Repeat(" ", indent))
-	fmt.Printf("%-15s%s\n", cmd, usage)
+	fmt.Printf("%-20s%s\n", cmd, usage)
 }
@@ -0,0 +1,105 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+)
+
+// PlaceHold places a hold on a book with no available copies
+func PlaceHold(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+
+	// Sends a POST request
+	res, err := sendHoldRequest("POST", jar, nil, bookID, addMode)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		hold, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		fmt.Printf("Successfully placed a hold on book %v\n", hold["book_id"])
+		fmt.Printf("Your position in the queue is: %v\n", hold["position"])
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// CancelHold cancels the hold on a book
+func CancelHold(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+
+	// Sends a DELETE request
+	res, err := sendHoldRequest("DELETE", jar, nil, bookID, removeMode)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully cancelled the hold on book %v\n", bookID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ShowHolds shows all active holds of the user
+func ShowHolds(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/user/holds", printHolds)
+}
+
+// ShowHoldQueue shows the hold queue of a book
+func ShowHoldQueue(jar *cookiejar.Jar) error {
+	bookID := getBookID()
+
+	// Sends GET requests page by page
+	queueURL := URL + "/admin/books/" + strconv.Itoa(bookID) + "/holds"
+	return showPages("GET", jar, nil, queueURL, printHolds)
+}
+
+func printHolds(holds []interface{}) {
+	if len(holds) == 0 {
+		fmt.Println("No holds found")
+		return
+	}
+	fmt.Printf("%s\t%s\t  %s\t  %s\t  %s\n",
+		"ID",
+		"User ID",
+		"Book ID",
+		"Position",
+		"Pickup Deadline",
+	)
+	fmt.Println(strings.Repeat("-", 70))
+	for _, elem := range holds {
+		hold := elem.(map[string]interface{})
+		fmt.Printf("%v\t", hold["id"])
+		fmt.Printf("%v\t  ", hold["user_id"])
+		fmt.Printf("%v\t  ", hold["book_id"])
+		if deadline := hold["pickup_deadline"]; hold["status"] == "ready" && deadline != nil {
+			fmt.Printf("%v\t  %v\n", "Ready", deadline)
+		} else {
+			fmt.Printf("%v\t  %v\n", hold["position"], "N/A")
+		}
+	}
+}
@@ -31,12 +31,30 @@ func sendBookRequest(
 			booksMgrURL += "/" + strconv.Itoa(bookID)
 		}
 		return http.Get(booksMgrURL)
-	case findMode:
-		booksMgrURL += "/books/find"
 	}
 	return sendRequest(method, jar, input, booksMgrURL)
 }
 
+func sendCopyRequest(
+	method string,
+	jar *cookiejar.Jar,
+	input interface{},
+	bookID int,
+	copyID int,
+	mode int,
+) (res *http.Response, err error) {
+	copiesMgrURL := URL + "/admin/books/" + strconv.Itoa(bookID) + "/copies"
+	switch mode {
+	case addMode:
+		// Does nothing
+	case updateMode:
+		fallthrough
+	case removeMode:
+		copiesMgrURL += "/" + strconv.Itoa(copyID)
+	}
+	return sendRequest(method, jar, input, copiesMgrURL)
+}
+
 func sendUserRequest(
 	method string,
 	jar *cookiejar.Jar,
@@ -80,23 +98,34 @@ func sendRecordRequest(
 		if bookID != 0 {
 			recordsMgrURL += "/" + strconv.Itoa(bookID)
 		}
-	case showOverdueMode:
-		recordsMgrURL += "/overdue"
-	case showHistoryMode:
-		recordsMgrURL += "/history"
 	}
 	return sendRequest(method, jar, input, recordsMgrURL)
 }
 
+func sendHoldRequest(
+	method string,
+	jar *cookiejar.Jar,
+	input interface{},
+	bookID int,
+	mode int,
+) (res *http.Response, err error) {
+	holdsMgrURL := URL + "/user/holds"
+	switch mode {
+	case addMode:
+		fallthrough
+	case removeMode:
+		holdsMgrURL += "/" + strconv.Itoa(bookID)
+	}
+	return sendRequest(method, jar, input, holdsMgrURL)
+}
+
 func sendRequest(
 	method string,
 	jar *cookiejar.Jar,
 	input interface{},
 	url string,
 ) (res *http.Response, err error) {
-	client := &http.Client{
-		Jar: jar,
-	}
+	client := newClient(jar)
 	jsonStr, err := json.Marshal(input)
 	if err != nil {
 		return nil, err
@@ -109,6 +138,28 @@ func sendRequest(
 	return client.Do(req)
 }
 
+// newClient creates an http client using the cookiejar, which sends the API
+// token in each request if set
+func newClient(jar *cookiejar.Jar) *http.Client {
+	client := &http.Client{Jar: jar}
+	if Token != "" {
+		client.Transport = tokenTransport{token: Token}
+	}
+	return client
+}
+
+// tokenTransport adds the API token to the header of requests
+type tokenTransport struct {
+	token string
+}
+
+// RoundTrip sends the request with the header Authorization: Bearer <token>
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
 func readResponse(res *http.Response) (data map[string]interface{}, err error) {
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
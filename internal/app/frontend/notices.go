@@ -0,0 +1,160 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type notificationsModel struct {
+	Notify string `json:"notify"`
+}
+
+// ShowNotifications shows the email address and the notification preference
+// of the user
+func ShowNotifications(jar *cookiejar.Jar) error {
+	// Sends a GET request
+	res, err := sendRequest("GET", jar, nil, URL+"/user/notifications")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		notifications, ok := dataBody.(map[string]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		printNotifications(notifications)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// SetNotifications sets the notices the user wants to receive
+func SetNotifications(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input notificationsModel
+
+	fmt.Println("(all / overdue / none)")
+	fmt.Print("Notices to receive: ")
+	scanner.Scan()
+	input.Notify = strings.TrimSpace(scanner.Text())
+
+	// Sends a PUT request
+	res, err := sendRequest("PUT", jar, &input, URL+"/user/notifications")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Println("Successfully set your notification preference")
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ShowNotices shows all notices sent to the user
+func ShowNotices(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/user/notices", printNotices)
+}
+
+// ShowAllNotices shows all notices sent in the library, filtered by user ID
+// and kind
+func ShowAllNotices(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	query := url.Values{}
+
+	fmt.Print("User ID (optional): ")
+	scanner.Scan()
+	if userID := strings.TrimSpace(scanner.Text()); userID != "" {
+		query.Set("user_id", userID)
+	}
+
+	fmt.Println("(due_soon / overdue)")
+	fmt.Print("Kind (optional): ")
+	scanner.Scan()
+	if kind := strings.TrimSpace(scanner.Text()); kind != "" {
+		query.Set("kind", kind)
+	}
+
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/admin/notices?"+query.Encode(), printNotices)
+}
+
+// SendNotices sends the notices due now without waiting for the scheduler
+func SendNotices(jar *cookiejar.Jar) error {
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, nil, URL+"/admin/notices/send")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		fmt.Printf("Successfully sent %v notices\n", dataBody)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printNotifications(notifications map[string]interface{}) {
+	email := notifications["email"]
+	if email == "" {
+		email = "not set, ask an admin to set it"
+	}
+	fmt.Printf("Email:   %v\n", email)
+	fmt.Printf("Notices: %v\n", notifications["notify"])
+}
+
+func printNotices(notices []interface{}) {
+	if len(notices) == 0 {
+		fmt.Println("No notices found")
+		return
+	}
+	fmt.Printf("%-8s%-10s%-12s%-10s%-10s%s\n",
+		"ID",
+		"User ID",
+		"Record ID",
+		"Kind",
+		"Channel",
+		"Sent At",
+	)
+	fmt.Println(strings.Repeat("-", 80))
+	for _, elem := range notices {
+		notice := elem.(map[string]interface{})
+		fmt.Printf("%-8v", notice["id"])
+		fmt.Printf("%-10v", notice["user_id"])
+		fmt.Printf("%-12v", notice["record_id"])
+		fmt.Printf("%-10v", notice["kind"])
+		fmt.Printf("%-10v", notice["channel"])
+		fmt.Printf("%v\n", notice["sent_at"])
+	}
+}
@@ -0,0 +1,92 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pageSize is the number of items shown on each page
+const pageSize = 20
+
+// showPages fetches a list page by page, and lets the user flip through the
+// pages interactively using "next" and "prev"
+func showPages(
+	method string,
+	jar *cookiejar.Jar,
+	input interface{},
+	listURL string,
+	print func([]interface{}),
+) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	offset := 0
+	for {
+		// Sends a request for the current page
+		res, err := sendRequest(method, jar, input, pageURL(listURL, offset))
+		if err != nil {
+			fmt.Println(ErrRequestFailed.Error())
+			return err
+		}
+		data, err := readResponse(res)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		// Outputs the response
+		dataBody, ok := data["data"]
+		if !ok {
+			if errBody, ok := data["error"]; ok {
+				fmt.Println(errBody)
+			}
+			return nil
+		}
+		items, _ := dataBody.([]interface{})
+		print(items)
+
+		paging, _ := data["paging"].(map[string]interface{})
+		total, _ := paging["total"].(float64)
+		if int(total) <= pageSize {
+			return nil
+		}
+		pages := (int(total) + pageSize - 1) / pageSize
+		fmt.Printf("Page %d/%d, %d in total\n", offset/pageSize+1, pages, int(total))
+
+		// Reads the next operation
+		fmt.Print("(next / prev / quit): ")
+		if !scanner.Scan() {
+			return nil
+		}
+		switch strings.TrimSpace(scanner.Text()) {
+		case "next", "n":
+			if offset+pageSize < int(total) {
+				offset += pageSize
+			} else {
+				fmt.Println("Already on the last page")
+			}
+		case "prev", "p":
+			if offset >= pageSize {
+				offset -= pageSize
+			} else {
+				fmt.Println("Already on the first page")
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// pageURL adds the paging parameters to the query string of the URL
+func pageURL(listURL string, offset int) string {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(pageSize))
+	query.Set("offset", strconv.Itoa(offset))
+	if strings.Contains(listURL, "?") {
+		return listURL + "&" + query.Encode()
+	}
+	return listURL + "?" + query.Encode()
+}
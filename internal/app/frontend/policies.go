@@ -0,0 +1,204 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type policyModel struct {
+	Circulates  bool `json:"circulates"`
+	LoanDays    uint `json:"loan_days"`
+	RenewDays   uint `json:"renew_days"`
+	MaxRenewals uint `json:"max_renewals"`
+	MaxLoans    uint `json:"max_loans"`
+}
+
+// ShowPolicies shows the loan policies of all categories
+func ShowPolicies(jar *cookiejar.Jar) error {
+	// Sends a GET request
+	res, err := sendRequest("GET", jar, nil, URL+"/admin/policies")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		policies, ok := dataBody.([]interface{})
+		if !ok {
+			fmt.Println(ErrInvalidResponse.Error())
+			return nil
+		}
+		printPolicies(policies)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// SetPolicy sets the loan policy of an item category for a patron category
+func SetPolicy(jar *cookiejar.Jar) error {
+	patron, item, err := getPolicyCategories()
+	if err != nil {
+		return err
+	}
+	var input policyModel
+	if err := getPolicyInput(&input); err != nil {
+		return err
+	}
+
+	// Sends a PUT request
+	res, err := sendRequest("PUT", jar, &input, URL+"/admin/policies/"+patron+"/"+item)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully set the loan policy of %v copies for %v users\n", item, patron)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ResetPolicy removes the loan policy of an item category for a patron
+// category, so that the library defaults are used
+func ResetPolicy(jar *cookiejar.Jar) error {
+	patron, item, err := getPolicyCategories()
+	if err != nil {
+		return err
+	}
+
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/admin/policies/"+patron+"/"+item)
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully reset the loan policy of %v copies for %v users\n", item, patron)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func getPolicyCategories() (string, string, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("(student / faculty / staff / guest)")
+	fmt.Print("Patron Category: ")
+	scanner.Scan()
+	patron := strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(regular / reserve / reference)")
+	fmt.Print("Item Category: ")
+	scanner.Scan()
+	item := strings.TrimSpace(scanner.Text())
+
+	if patron == "" || item == "" {
+		fmt.Println("Both categories are required!")
+		return "", "", ErrInvalidInput
+	}
+	return patron, item, nil
+}
+
+func getPolicyInput(input *policyModel) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Circulates (y/n): ")
+	scanner.Scan()
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "y", "yes":
+		input.Circulates = true
+	case "n", "no":
+		input.Circulates = false
+	default:
+		fmt.Println("Please answer y or n")
+		return ErrInvalidInput
+	}
+	if !input.Circulates {
+		return nil
+	}
+
+	fields := []struct {
+		prompt string
+		value  *uint
+	}{
+		{"Loan Days: ", &input.LoanDays},
+		{"Renew Days: ", &input.RenewDays},
+		{"Max Renewals: ", &input.MaxRenewals},
+		{"Max Loans (0 for no limit): ", &input.MaxLoans},
+	}
+	for _, field := range fields {
+		fmt.Print(field.prompt)
+		scanner.Scan()
+		value, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 32)
+		if err != nil {
+			fmt.Println("Please enter a non-negative integer")
+			return ErrInvalidInput
+		}
+		*field.value = uint(value)
+	}
+	return nil
+}
+
+func printPolicies(policies []interface{}) {
+	width := 12
+	fmt.Printf("%-*s%-*s%-*s%-*s%-*s%-*s%s\n",
+		width, "Patron",
+		width, "Item",
+		width, "Circulates",
+		width, "Loan Days",
+		width, "Renew Days",
+		width, "Renewals",
+		"Max Loans",
+	)
+	fmt.Println(strings.Repeat("-", 10+width*6))
+	for _, elem := range policies {
+		policy := elem.(map[string]interface{})
+		patron := fmt.Sprint(policy["patron_category"])
+		if policy["id"] == 0.0 {
+			patron += "*"
+		}
+		fmt.Printf("%-*s", width, patron)
+		fmt.Printf("%-*v", width, policy["item_category"])
+		if policy["circulates"] != true {
+			fmt.Printf("%-*s\n", width, "no")
+			continue
+		}
+		fmt.Printf("%-*s", width, "yes")
+		fmt.Printf("%-*v", width, policy["loan_days"])
+		fmt.Printf("%-*v", width, policy["renew_days"])
+		fmt.Printf("%-*v", width, policy["max_renewals"])
+		if policy["max_loans"] == 0.0 {
+			fmt.Println("-")
+		} else {
+			fmt.Printf("%v\n", policy["max_loans"])
+		}
+	}
+	fmt.Println("* Not set, where the library defaults are used")
+}
@@ -11,15 +11,17 @@ import (
 
 const maxExtendTimes = 3
 
-// recordInput should only be used in debug mode
+// recordInput.BorrowDate should only be used in debug mode
 type recordInput struct {
 	BorrowDate time.Time `json:"borrow_date,omitempty"`
+	Barcode    string    `json:"barcode,omitempty"`
 }
 
 // BorrowBook borrows a book from the library
 func BorrowBook(jar *cookiejar.Jar) error {
 	bookID := getBookID()
 	var input recordInput
+	input.Barcode = getBarcode()
 	if debugMode {
 		if err := getRecordInput(&input); err != nil {
 			return err
@@ -45,7 +47,7 @@ func BorrowBook(jar *cookiejar.Jar) error {
 			fmt.Println(ErrInvalidResponse.Error())
 			return nil
 		}
-		fmt.Printf("Successfully borrowed book %v\n", record["book_id"])
+		fmt.Printf("Successfully borrowed copy %v of book %v\n", record["copy_id"], record["book_id"])
 		fmt.Printf("Your return date is: %v\n", record["return_date"])
 	} else if errBody, ok := data["error"]; ok {
 		fmt.Println(errBody)
@@ -104,6 +106,10 @@ func ReturnBook(jar *cookiejar.Jar) error {
 	}
 	if _, ok := data["data"]; ok {
 		fmt.Printf("Successfully returned book %v\n", bookID)
+		if fine, ok := data["fine"].(map[string]interface{}); ok {
+			fmt.Printf("The book is %v days overdue, you've been fined %.2f\n",
+				fine["overdue_days"], fine["amount"])
+		}
 	} else if errBody, ok := data["error"]; ok {
 		fmt.Println(errBody)
 	}
@@ -156,26 +162,20 @@ func ShowBorrowed(jar *cookiejar.Jar) error {
 }
 
 func showBookList(jar *cookiejar.Jar, mode int) error {
-	// Sends a GET request
-	res, err := sendRecordRequest("GET", jar, nil, 0, mode)
-	if err != nil {
-		fmt.Println(ErrRequestFailed.Error())
-		return err
-	}
-	defer res.Body.Close()
-
-	// Outputs the response
-	data, err := readResponse(res)
-	if err != nil {
-		return err
-	}
-	if dataBody, ok := data["data"]; ok {
-		records := dataBody.([]interface{})
+	recordsURL := URL + "/user"
+	switch mode {
+	case showMode:
+		recordsURL += "/books"
+	case showOverdueMode:
+		recordsURL += "/overdue"
+	case showHistoryMode:
+		recordsURL += "/history"
+	}
+
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, recordsURL, func(records []interface{}) {
 		printRecords(records, mode)
-	} else if errBody, ok := data["error"]; ok {
-		fmt.Println(errBody)
-	}
-	return nil
+	})
 }
 
 func getRecordInput(input *recordInput) error {
@@ -201,6 +201,13 @@ func getRecordInput(input *recordInput) error {
 	return nil
 }
 
+func getBarcode() string {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Barcode (optional): ")
+	scanner.Scan()
+	return strings.TrimSpace(scanner.Text())
+}
+
 func printRecords(records []interface{}, mode int) {
 	if len(records) == 0 {
 		fmt.Println("No records found")
@@ -242,6 +249,7 @@ func printRecords(records []interface{}, mode int) {
 func printRecord(record map[string]interface{}) {
 	fmt.Printf("Record %v\n", record["id"])
 	fmt.Printf("   Book ID:     %v\n", record["book_id"])
+	fmt.Printf("   Copy ID:     %v\n", record["copy_id"])
 	fmt.Printf("   Return Date: %v\n", record["return_date"])
 	fmt.Printf("   Extended:    %v/%v\n", record["extend_times"], maxExtendTimes)
 }
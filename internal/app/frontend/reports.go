@@ -0,0 +1,90 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ShowLoans shows all books on loan in the library, filtered by user ID and
+// book ID
+func ShowLoans(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	query := url.Values{"status": {"active"}}
+
+	fmt.Print("User ID (optional): ")
+	scanner.Scan()
+	if userID := strings.TrimSpace(scanner.Text()); userID != "" {
+		query.Set("user_id", userID)
+	}
+
+	fmt.Print("Book ID (optional): ")
+	scanner.Scan()
+	if bookID := strings.TrimSpace(scanner.Text()); bookID != "" {
+		query.Set("book_id", bookID)
+	}
+
+	return showLoans(jar, URL+"/admin/records?"+query.Encode())
+}
+
+// ShowAllOverdue shows all overdue books in the library
+func ShowAllOverdue(jar *cookiejar.Jar) error {
+	query := url.Values{"overdue": {"true"}}
+	return showLoans(jar, URL+"/admin/records?"+query.Encode())
+}
+
+// ShowUserHistory shows all records of the user of given ID
+func ShowUserHistory(jar *cookiejar.Jar) error {
+	userID := getUserID()
+	return showLoans(jar, URL+"/admin/users/"+strconv.Itoa(userID)+"/records")
+}
+
+func showLoans(jar *cookiejar.Jar, recordsURL string) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, recordsURL, printLoans)
+}
+
+func printLoans(records []interface{}) {
+	if len(records) == 0 {
+		fmt.Println("No records found")
+		return
+	}
+	width := 12
+	fmt.Printf("%s\t%s\t  %s\t  %s\t  %-*s%-*s%s\n",
+		"ID",
+		"User ID",
+		"Book ID",
+		"Copy ID",
+		width, "Borrowed",
+		width, "Due",
+		"Returned",
+	)
+	fmt.Println(strings.Repeat("-", 80))
+	for _, elem := range records {
+		record := elem.(map[string]interface{})
+		fmt.Printf("%v\t", record["id"])
+		fmt.Printf("%v\t  ", record["user_id"])
+		fmt.Printf("%v\t  ", record["book_id"])
+		fmt.Printf("%v\t  ", record["copy_id"])
+		fmt.Printf("%-*s", width, formatDate(record["borrow_date"]))
+		fmt.Printf("%-*s", width, formatDate(record["return_date"]))
+		if returnedDate := record["real_return_date"]; returnedDate != nil {
+			fmt.Println(formatDate(returnedDate))
+		} else {
+			fmt.Println("N/A")
+		}
+	}
+}
+
+// formatDate keeps the date part of a timestamp in the response
+func formatDate(v interface{}) string {
+	s, _ := v.(string)
+	if len(s) < 10 || strings.HasPrefix(s, "0001-01-01") {
+		return "N/A"
+	}
+	return s[:10]
+}
@@ -0,0 +1,115 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type roleModel struct {
+	Permissions []string `json:"permissions"`
+}
+
+// ShowRoles shows all roles along with their permissions
+func ShowRoles(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/admin/roles", printRoles)
+}
+
+// SetRole creates a role or replaces its permissions
+func SetRole(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input roleModel
+
+	fmt.Print("Role: ")
+	scanner.Scan()
+	name := strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(catalog.write / users.manage / circulation.desk / reports.read)")
+	fmt.Print("Permissions (comma-separated, optional): ")
+	scanner.Scan()
+	input.Permissions = splitPermissions(scanner.Text())
+
+	// Sends a PUT request
+	res, err := sendRequest("PUT", jar, &input, URL+"/admin/roles/"+url.PathEscape(name))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully set role %v\n", name)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// RemoveRole removes a role not granted to any user
+func RemoveRole(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Role: ")
+	scanner.Scan()
+	name := strings.TrimSpace(scanner.Text())
+
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/admin/roles/"+url.PathEscape(name))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully removed role %v\n", name)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printRoles(roles []interface{}) {
+	width := 16
+	fmt.Printf("%-*s%s\n", width, "Role", "Permissions")
+	fmt.Println(strings.Repeat("-", width+40))
+	for _, elem := range roles {
+		role := elem.(map[string]interface{})
+		permissions, _ := role["permissions"].([]interface{})
+		fmt.Printf("%-*v%v\n", width, role["name"], joinList(permissions))
+	}
+}
+
+// splitPermissions splits a comma-separated list of permissions
+func splitPermissions(text string) []string {
+	permissions := []string{}
+	for _, permission := range strings.Split(text, ",") {
+		if permission = strings.TrimSpace(permission); permission != "" {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// joinList joins a list of strings in the response
+func joinList(list []interface{}) string {
+	items := make([]string, 0, len(list))
+	for _, elem := range list {
+		items = append(items, fmt.Sprint(elem))
+	}
+	return strings.Join(items, ", ")
+}
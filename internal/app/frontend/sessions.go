@@ -0,0 +1,69 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"strings"
+)
+
+// ShowSessions shows all sessions of the user, the latest first
+func ShowSessions(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/user/sessions", printSessions)
+}
+
+// LogoutAll logs the user out everywhere, including here
+func LogoutAll(jar *cookiejar.Jar) error {
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/user/sessions")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if dataBody, ok := data["data"]; ok {
+		fmt.Printf("Successfully logged out of %v sessions!\n", dataBody)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printSessions(sessions []interface{}) {
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return
+	}
+	fmt.Printf("%-8s%-18s%-18s%-18s%s\n",
+		"ID",
+		"Logged In",
+		"Last Seen",
+		"IP",
+		"User Agent",
+	)
+	fmt.Println(strings.Repeat("-", 80))
+	for _, elem := range sessions {
+		session := elem.(map[string]interface{})
+		fmt.Printf("%-8v", session["id"])
+		fmt.Printf("%-18v", formatDateTime(session["created_at"]))
+		fmt.Printf("%-18v", formatDateTime(session["last_seen_at"]))
+		fmt.Printf("%-18v", session["ip"])
+		fmt.Printf("%v\n", session["user_agent"])
+	}
+}
+
+// formatDateTime keeps the date and the minutes of a timestamp in the
+// response
+func formatDateTime(v interface{}) string {
+	s, _ := v.(string)
+	if len(s) < 16 {
+		return formatDate(v)
+	}
+	return s[:10] + " " + s[11:16]
+}
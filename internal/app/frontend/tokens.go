@@ -0,0 +1,126 @@
+package frontend
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type tokenModel struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays uint     `json:"expires_in_days"`
+}
+
+// ShowTokens shows all API tokens of the user
+func ShowTokens(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/user/tokens", printTokens)
+}
+
+// CreateToken creates an API token, which is shown only once
+func CreateToken(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input tokenModel
+
+	fmt.Print("Token Name: ")
+	scanner.Scan()
+	input.Name = strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(catalog.write / users.manage / circulation.desk / reports.read)")
+	fmt.Print("Scopes (comma-separated, optional): ")
+	scanner.Scan()
+	input.Scopes = splitPermissions(scanner.Text())
+
+	var err error
+	if input.ExpiresInDays, err = getNumberInput(scanner, "Expires in Days (optional, 0 for never): "); err != nil {
+		return nil
+	}
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, &input, URL+"/user/tokens")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if token, ok := data["token"]; ok {
+		fmt.Println("Successfully created the token below, which won't be shown again:")
+		fmt.Println(token)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// RevokeToken removes an API token of the user
+func RevokeToken(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Token ID: ")
+	scanner.Scan()
+	tokenID, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		fmt.Println("Please enter a number!")
+		return nil
+	}
+
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/user/tokens/"+strconv.Itoa(tokenID))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully revoked token %v\n", tokenID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printTokens(tokens []interface{}) {
+	if len(tokens) == 0 {
+		fmt.Println("No tokens found")
+		return
+	}
+	fmt.Printf("%-6s%-20s%-18s%-18s%-18s%s\n",
+		"ID",
+		"Name",
+		"Token",
+		"Expires At",
+		"Last Used",
+		"Scopes",
+	)
+	fmt.Println(strings.Repeat("-", 100))
+	for _, elem := range tokens {
+		token := elem.(map[string]interface{})
+		scopes, _ := token["scopes"].([]interface{})
+		fmt.Printf("%-6v", token["id"])
+		fmt.Printf("%-20v", slice(fmt.Sprint(token["name"]), 19))
+		fmt.Printf("%-18v", fmt.Sprint(token["prefix"], "..."))
+		if token["expires_at"] == nil {
+			fmt.Printf("%-18v", "Never")
+		} else {
+			fmt.Printf("%-18v", formatDateTime(token["expires_at"]))
+		}
+		fmt.Printf("%-18v", formatDateTime(token["last_used_at"]))
+		fmt.Printf("%v\n", joinList(scopes))
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http/cookiejar"
 	"os"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -15,7 +17,17 @@ type userModel struct {
 	ID       uint   `json:"id,omitempty"`
 	Username string `json:"username,omitempty"`
 	Password string `json:"password,omitempty"`
-	Level    uint   `json:"level,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Category string `json:"category,omitempty"`
+	MaxLoans *uint  `json:"max_loans,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Notify   string `json:"notify,omitempty"`
+}
+
+type userStateModel struct {
+	State  string     `json:"state"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
 }
 
 // AddUser adds a new user to the database
@@ -107,26 +119,8 @@ func RemoveUser(jar *cookiejar.Jar) error {
 
 // ShowUsers shows all users in the library
 func ShowUsers(jar *cookiejar.Jar) error {
-	// Sends a GET request
-	res, err := sendUserRequest("GET", jar, nil, 0, showMode)
-	if err != nil {
-		fmt.Println(ErrRequestFailed.Error())
-		return err
-	}
-	defer res.Body.Close()
-
-	// Outputs the response
-	data, err := readResponse(res)
-	if err != nil {
-		return err
-	}
-	if dataBody, ok := data["data"]; ok {
-		users := dataBody.([]interface{})
-		printUsers(users)
-	} else if errBody, ok := data["error"]; ok {
-		fmt.Println(errBody)
-	}
-	return nil
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/admin/users", printUsers)
 }
 
 // ShowUser shows the user of given ID
@@ -173,36 +167,64 @@ func getUserInput(input *userModel, mode int) error {
 		}
 	}
 
-	fmt.Print("Enter Password: ")
+	// Keeps the password as is if left blank when updating
+	if mode == addMode {
+		fmt.Print("Enter Password: ")
+	} else {
+		fmt.Print("Enter Password (optional): ")
+	}
 	bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
 	password := string(bytePassword)
 	fmt.Println()
-	if password == "" {
+	if password == "" && mode == addMode {
 		fmt.Println("Password shouldn't be empty")
 		return ErrInvalidInput
 	}
 
-	fmt.Print("Enter Password again: ")
-	bytePasswordConfirm, _ := terminal.ReadPassword(int(syscall.Stdin))
-	passwordConfirm := string(bytePasswordConfirm)
-	fmt.Println()
-	if password != passwordConfirm {
-		fmt.Println("Password doesn't match")
-		return ErrInvalidInput
+	if password != "" {
+		fmt.Print("Enter Password again: ")
+		bytePasswordConfirm, _ := terminal.ReadPassword(int(syscall.Stdin))
+		passwordConfirm := string(bytePasswordConfirm)
+		fmt.Println()
+		if password != passwordConfirm {
+			fmt.Println("Password doesn't match")
+			return ErrInvalidInput
+		}
 	}
 
-	var level uint
-	fmt.Println("(1: User, 2: Admin, 3: Super Admin)")
-	fmt.Print("Enter Privilege Level: ")
-	fmt.Scanln(&level)
-	if level < 1 || level > 3 {
-		fmt.Println("Level should be an integer between 1 and 3")
-		return ErrInvalidInput
+	fmt.Println("(user / librarian / cataloger / admin, see show roles)")
+	fmt.Print("Enter Role (optional): ")
+	scanner.Scan()
+	input.Role = strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(student / faculty / staff / guest)")
+	fmt.Print("Enter Patron Category (optional): ")
+	scanner.Scan()
+	input.Category = strings.TrimSpace(scanner.Text())
+
+	fmt.Print("Enter Max Loans (optional, 0 for the library default): ")
+	scanner.Scan()
+	if maxLoans := strings.TrimSpace(scanner.Text()); maxLoans != "" {
+		value, err := strconv.ParseUint(maxLoans, 10, 32)
+		if err != nil {
+			fmt.Println("Max loans should be a non-negative integer")
+			return ErrInvalidInput
+		}
+		limit := uint(value)
+		input.MaxLoans = &limit
 	}
 
+	fmt.Print("Enter Email (optional): ")
+	scanner.Scan()
+	input.Email = strings.TrimSpace(scanner.Text())
+
+	fmt.Println("(all / overdue / none)")
+	fmt.Print("Enter Notices to Receive (optional): ")
+	scanner.Scan()
+	input.Notify = strings.TrimSpace(scanner.Text())
+
 	input.Username = strings.TrimSpace(username)
 	input.Password = password
-	input.Level = level
 	return nil
 }
 
@@ -219,22 +241,223 @@ func printUsers(users []interface{}) {
 		return
 	}
 	width := 25
-	fmt.Printf("%s\t%-*s%-s\n",
+	fmt.Printf("%s\t%-*s%-*s%-*s%-s\n",
 		"ID",
 		width, "Username",
-		"Level",
+		12, "Role",
+		12, "Category",
+		"State",
 	)
-	fmt.Println(strings.Repeat("-", 13+width))
+	fmt.Println(strings.Repeat("-", 42+width))
 	for _, elem := range users {
 		user := elem.(map[string]interface{})
 		fmt.Printf("%v\t", user["id"])
 		fmt.Printf("%-*s", width, slice(user["username"].(string), width-2))
-		fmt.Printf("%v\n", user["level"])
+		fmt.Printf("%-*v", 12, user["role"])
+		fmt.Printf("%-*v", 12, user["category"])
+		fmt.Printf("%v\n", user["state"])
 	}
 }
 
 func printUser(user map[string]interface{}) {
 	fmt.Printf("User %v\n", user["id"])
-	fmt.Printf("   Username: %v\n", user["username"])
-	fmt.Printf("   Level:    %v\n", user["level"])
+	fmt.Printf("   Username:  %v\n", user["username"])
+	fmt.Printf("   Role:      %v\n", user["role"])
+	fmt.Printf("   Category:  %v\n", user["category"])
+	if user["max_loans"] == 0.0 {
+		fmt.Println("   Max Loans: library default")
+	} else {
+		fmt.Printf("   Max Loans: %v\n", user["max_loans"])
+	}
+	fmt.Printf("   Email:     %v\n", user["email"])
+	fmt.Printf("   Notices:   %v\n", user["notify"])
+	fmt.Printf("   Name:      %v\n", user["display_name"])
+	fmt.Printf("   Phone:     %v\n", user["phone"])
+	if user["state"] == "suspended" {
+		fmt.Printf("   State:     suspended until %v\n", formatDateTime(user["suspended_until"]))
+	} else {
+		fmt.Printf("   State:     %v\n", user["state"])
+	}
+	if reason, _ := user["state_reason"].(string); reason != "" {
+		fmt.Printf("   Reason:    %v\n", reason)
+	}
+	if user["state_set_by"] != nil && user["state_set_by"] != 0.0 {
+		fmt.Printf("   Set By:    user %v\n", user["state_set_by"])
+	}
+	if user["must_change_password"] == true {
+		fmt.Println("   Password:  reset, to be changed on next login")
+	}
+}
+
+// ShowPendingUsers shows all users registered and waiting for approval
+func ShowPendingUsers(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/admin/users?state=pending", printUsers)
+}
+
+// ApproveUser activates the account of a user pending approval
+func ApproveUser(jar *cookiejar.Jar) error {
+	userID := getUserID()
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, nil, URL+"/admin/users/"+strconv.Itoa(userID)+"/approve")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully approved user %v\n", userID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// SetUserState activates, suspends or bans a user
+func SetUserState(jar *cookiejar.Jar) error {
+	userID := getUserID()
+	scanner := bufio.NewScanner(os.Stdin)
+	var input userStateModel
+
+	fmt.Println("(active / suspended / banned)")
+	fmt.Print("State: ")
+	scanner.Scan()
+	input.State = strings.TrimSpace(scanner.Text())
+
+	if input.State == "suspended" {
+		fmt.Print("Suspended Until (YYYY-MM-DD): ")
+		scanner.Scan()
+		until, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(scanner.Text()), time.Local)
+		if err != nil {
+			fmt.Println("Please enter a date like 2020-06-01!")
+			return nil
+		}
+		input.Until = &until
+	}
+
+	fmt.Print("Reason (optional): ")
+	scanner.Scan()
+	input.Reason = strings.TrimSpace(scanner.Text())
+
+	// Sends a PUT request
+	res, err := sendRequest("PUT", jar, &input, URL+"/admin/users/"+strconv.Itoa(userID)+"/state")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully set user %v %v\n", userID, input.State)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+// ShowLockouts shows the failed logins counted for usernames and IP addresses
+func ShowLockouts(jar *cookiejar.Jar) error {
+	// Sends GET requests page by page
+	return showPages("GET", jar, nil, URL+"/admin/lockouts", printLockouts)
+}
+
+// ClearLockout forgets the failed logins of a username or an IP address
+func ClearLockout(jar *cookiejar.Jar) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("Lockout ID: ")
+	scanner.Scan()
+	lockoutID, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		fmt.Println("Please enter a number!")
+		return nil
+	}
+
+	// Sends a DELETE request
+	res, err := sendRequest("DELETE", jar, nil, URL+"/admin/lockouts/"+strconv.Itoa(lockoutID))
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if _, ok := data["data"]; ok {
+		fmt.Printf("Successfully cleared lockout %v\n", lockoutID)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
+}
+
+func printLockouts(failures []interface{}) {
+	if len(failures) == 0 {
+		fmt.Println("No failed logins found")
+		return
+	}
+	fmt.Printf("%-6s%-10s%-26s%-10s%-18s%s\n",
+		"ID",
+		"Kind",
+		"Target",
+		"Failures",
+		"Last Failed",
+		"Locked Until",
+	)
+	fmt.Println(strings.Repeat("-", 90))
+	for _, elem := range failures {
+		failure := elem.(map[string]interface{})
+		fmt.Printf("%-6v", failure["id"])
+		fmt.Printf("%-10v", failure["kind"])
+		fmt.Printf("%-26v", slice(fmt.Sprint(failure["target"]), 25))
+		fmt.Printf("%-10v", failure["failures"])
+		fmt.Printf("%-18v", formatDateTime(failure["last_failed_at"]))
+		if failure["locked_out"] == true {
+			fmt.Printf("%v\n", formatDateTime(failure["locked_until"]))
+		} else {
+			fmt.Println("-")
+		}
+	}
+}
+
+// ResetPassword sets a temporary password of a user, which has to be changed
+// on next login
+func ResetPassword(jar *cookiejar.Jar) error {
+	userID := getUserID()
+
+	// Sends a POST request
+	res, err := sendRequest("POST", jar, nil, URL+"/admin/users/"+strconv.Itoa(userID)+"/reset-password")
+	if err != nil {
+		fmt.Println(ErrRequestFailed.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	// Outputs the response
+	data, err := readResponse(res)
+	if err != nil {
+		return err
+	}
+	if password, ok := data["password"]; ok {
+		fmt.Printf("Successfully reset the password of user %v to the one below, which won't be shown again:\n", userID)
+		fmt.Println(password)
+	} else if errBody, ok := data["error"]; ok {
+		fmt.Println(errBody)
+	}
+	return nil
 }
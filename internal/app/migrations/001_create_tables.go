@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// createTables creates the tables of books, copies, users, records, holds and
+// fines, along with the authors and subjects of books
+// The models are copied as they were, so that later changes to them are made
+// by later migrations. Databases set up by AutoMigrate before migrations were
+// introduced are brought up to date as well, since only missing tables and
+// columns are created, and the books added before copies were introduced are
+// given copies by seedCopies
+var createTables = Migration{
+	Version: 1,
+	Name:    "create_tables",
+	Up: func(tx *gorm.DB) error {
+		type Author struct {
+			ID   uint
+			Name string `gorm:"NOT NULL; UNIQUE"`
+		}
+		type Subject struct {
+			ID   uint
+			Name string `gorm:"NOT NULL; UNIQUE"`
+		}
+		type Book struct {
+			ID        uint
+			Title     string `gorm:"NOT NULL"`
+			Author    string
+			Authors   []Author `gorm:"many2many:book_authors"`
+			Publisher string
+			ISBN      string `gorm:"INDEX"`
+			Year      uint
+			Edition   string
+			Language  string
+			Pages     uint
+			Series    string
+			Volume    uint
+			Subjects  []Subject `gorm:"many2many:book_subjects"`
+		}
+		type Copy struct {
+			ID        uint
+			BookID    uint   `gorm:"NOT NULL; INDEX"`
+			Barcode   string `gorm:"NOT NULL; UNIQUE"`
+			Location  string
+			Status    string `gorm:"NOT NULL"`
+			DeletedAt *time.Time
+		}
+		type User struct {
+			ID       uint
+			Username string `gorm:"NOT NULL; UNIQUE"`
+			Password string `gorm:"NOT NULL"`
+			Level    uint   `gorm:"NOT NULL"`
+		}
+		type Record struct {
+			ID          uint
+			UserID      uint `gorm:"NOT NULL"`
+			BookID      uint `gorm:"NOT NULL"`
+			CopyID      uint `gorm:"NOT NULL"`
+			BorrowDate  time.Time
+			ReturnDate  time.Time `gorm:"NOT NULL"`
+			ExtendTimes uint      `gorm:"NOT NULL"`
+			IssuedBy    uint
+			RenewedBy   uint
+			ReceivedBy  uint
+			DeletedAt   *time.Time
+		}
+		type Hold struct {
+			ID             uint
+			UserID         uint `gorm:"NOT NULL; INDEX"`
+			BookID         uint `gorm:"NOT NULL; INDEX"`
+			CopyID         uint
+			Status         string `gorm:"NOT NULL"`
+			CreatedAt      time.Time
+			PickupDeadline *time.Time
+		}
+		type Fine struct {
+			ID          uint
+			UserID      uint    `gorm:"NOT NULL; INDEX"`
+			RecordID    uint    `gorm:"NOT NULL"`
+			OverdueDays uint    `gorm:"NOT NULL"`
+			Amount      float64 `gorm:"NOT NULL"`
+			Status      string  `gorm:"NOT NULL"`
+			CreatedAt   time.Time
+			ResolvedAt  *time.Time
+			ResolvedBy  uint
+			Message     string
+		}
+		seed := !tx.HasTable(&Copy{})
+		err := tx.AutoMigrate(&Book{}, &Author{}, &Subject{}, &Copy{}, &User{}, &Record{}, &Hold{}, &Fine{}).Error
+		if err != nil || !seed {
+			return err
+		}
+		return seedCopies(tx)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(
+			"book_authors", "book_subjects", "authors", "subjects",
+			"fines", "holds", "records", "users", "copies", "books",
+		).Error
+	},
+}
+
+// seedCopies adds copies to the books added before copies were introduced,
+// which could be borrowed by any number of users at a time
+// Each book gets a copy on loan for every record still open, which is linked
+// to the record, and an available copy. The barcodes are generated as
+// LEGACY-<book ID>-<n>, and are meant to be relabeled by the librarians
+func seedCopies(tx *gorm.DB) error {
+	type Copy struct {
+		ID      uint
+		BookID  uint
+		Barcode string
+		Status  string
+	}
+	var bookIDs []uint
+	if err := tx.Table("books").Order("id").Pluck("id", &bookIDs).Error; err != nil {
+		return err
+	}
+	for _, bookID := range bookIDs {
+		var recordIDs []uint
+		err := tx.Table("records").
+			Where("book_id = ? AND copy_id = 0 AND deleted_at IS NULL", bookID).
+			Order("id").Pluck("id", &recordIDs).Error
+		if err != nil {
+			return err
+		}
+		for i, recordID := range recordIDs {
+			item := Copy{
+				BookID:  bookID,
+				Barcode: fmt.Sprintf("LEGACY-%v-%v", bookID, i+1),
+				Status:  "on_loan",
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			err := tx.Table("records").Where("id = ?", recordID).
+				UpdateColumn("copy_id", item.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		item := Copy{
+			BookID:  bookID,
+			Barcode: fmt.Sprintf("LEGACY-%v-%v", bookID, len(recordIDs)+1),
+			Status:  "available",
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
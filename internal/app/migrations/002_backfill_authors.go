@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+)
+
+// backfillAuthors links the books added before authors were introduced to
+// their authors, by splitting the bylines
+var backfillAuthors = Migration{
+	Version: 2,
+	Name:    "backfill_authors",
+	Up: func(tx *gorm.DB) error {
+		var books []models.Book
+		err := tx.Where("author <> '' AND id NOT IN (?)",
+			tx.Table("book_authors").Select("book_id").QueryExpr()).
+			Find(&books).Error
+		if err != nil {
+			return err
+		}
+		for _, book := range books {
+			if err := book.SetAuthors(tx, models.SplitNames(book.Author)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
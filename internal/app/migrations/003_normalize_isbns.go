@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+)
+
+// normalizeISBNs converts the valid ISBNs of the books added before ISBNs were
+// normalized to canonical ISBN-13, and leaves the invalid ones as is
+var normalizeISBNs = Migration{
+	Version: 3,
+	Name:    "normalize_isbns",
+	Up: func(tx *gorm.DB) error {
+		var books []models.Book
+		if err := tx.Select("id, isbn").Where("isbn <> ''").Find(&books).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			isbn, err := models.NormalizeISBN(book.ISBN)
+			if err != nil || isbn == book.ISBN {
+				continue
+			}
+			err = tx.Model(&models.Book{}).Where("id = ?", book.ID).Update("isbn", isbn).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+// loanPolicies adds the patron categories of users and the item categories of
+// copies, along with the policy matrix of them
+// Existing users are students, and existing copies are regular ones. Reference
+// copies are kept in the library for all patron categories by default
+// The category columns are kept when reverted on SQLite, which is unable to
+// drop columns, and ignored by the older releases
+var loanPolicies = Migration{
+	Version: 4,
+	Name:    "loan_policies",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			Category string `gorm:"NOT NULL; DEFAULT:'student'"`
+		}
+		type Copy struct {
+			Category string `gorm:"NOT NULL; DEFAULT:'regular'"`
+		}
+		type Policy struct {
+			ID             uint
+			PatronCategory string `gorm:"NOT NULL; UNIQUE_INDEX:idx_policies_categories"`
+			ItemCategory   string `gorm:"NOT NULL; UNIQUE_INDEX:idx_policies_categories"`
+			Circulates     bool   `gorm:"NOT NULL"`
+			LoanDays       uint   `gorm:"NOT NULL"`
+			RenewDays      uint   `gorm:"NOT NULL"`
+			MaxRenewals    uint   `gorm:"NOT NULL"`
+			MaxLoans       uint   `gorm:"NOT NULL"`
+		}
+		if err := tx.AutoMigrate(&User{}, &Copy{}, &Policy{}).Error; err != nil {
+			return err
+		}
+		for _, patron := range []string{"student", "faculty", "staff", "guest"} {
+			policy := Policy{PatronCategory: patron, ItemCategory: "reference"}
+			if err := tx.Create(&policy).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.DropTableIfExists("policies").Error; err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		if err := tx.Table("copies").DropColumn("category").Error; err != nil {
+			return err
+		}
+		return tx.Table("users").DropColumn("category").Error
+	},
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+// maxLoans adds the maximum number of books borrowed at a time of users, where
+// the library default is used for existing users
+var maxLoans = Migration{
+	Version: 5,
+	Name:    "max_loans",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			MaxLoans uint `gorm:"NOT NULL; DEFAULT:0"`
+		}
+		return tx.AutoMigrate(&User{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		// SQLite is unable to drop columns, where the column is ignored by the
+		// older releases
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		return tx.Table("users").DropColumn("max_loans").Error
+	},
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+// libraryCalendar adds the opening hours of each weekday and the closed days
+// of the library, where the library opens from 08:00 to 22:00 except on
+// Sundays by default
+var libraryCalendar = Migration{
+	Version: 6,
+	Name:    "library_calendar",
+	Up: func(tx *gorm.DB) error {
+		type OpeningHours struct {
+			ID      uint
+			Weekday uint   `gorm:"NOT NULL; UNIQUE_INDEX"`
+			Opens   string `gorm:"NOT NULL"`
+			Closes  string `gorm:"NOT NULL"`
+			Closed  bool   `gorm:"NOT NULL"`
+		}
+		type ClosedDay struct {
+			ID   uint
+			Date string `gorm:"NOT NULL; UNIQUE_INDEX"`
+			Name string
+			UID  string
+		}
+		if err := tx.AutoMigrate(&OpeningHours{}, &ClosedDay{}).Error; err != nil {
+			return err
+		}
+		for weekday := uint(0); weekday < 7; weekday++ {
+			hours := OpeningHours{Weekday: weekday, Opens: "08:00", Closes: "22:00", Closed: weekday == 0}
+			if err := tx.Create(&hours).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists("closed_days", "opening_hours").Error
+	},
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// notices adds the email addresses and notification preferences of users,
+// along with the notices sent to them
+// Existing users receive all notices once they have an email address. The
+// columns of users are kept when reverted on SQLite, which is unable to drop
+// columns, and ignored by the older releases
+var notices = Migration{
+	Version: 7,
+	Name:    "notices",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			Email  string
+			Notify string `gorm:"NOT NULL; DEFAULT:'all'"`
+		}
+		type Notice struct {
+			ID          uint
+			UserID      uint      `gorm:"NOT NULL; INDEX"`
+			RecordID    uint      `gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+			Kind        string    `gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+			Channel     string    `gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+			ExtendTimes uint      `gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+			ReturnDate  time.Time `gorm:"NOT NULL"`
+			SentAt      time.Time `gorm:"NOT NULL"`
+		}
+		return tx.AutoMigrate(&User{}, &Notice{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.DropTableIfExists("notices").Error; err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		if err := tx.Table("users").DropColumn("notify").Error; err != nil {
+			return err
+		}
+		return tx.Table("users").DropColumn("email").Error
+	},
+}
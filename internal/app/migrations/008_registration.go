@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// registration adds the display names, phone numbers and states of users, so
+// that users can register and manage their profiles
+// Existing users are active. The columns are kept when reverted on SQLite,
+// which is unable to drop columns, and ignored by the older releases
+var registration = Migration{
+	Version: 8,
+	Name:    "registration",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			DisplayName string
+			Phone       string
+			State       string `gorm:"NOT NULL; DEFAULT:'active'"`
+		}
+		return tx.AutoMigrate(&User{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		for _, column := range []string{"state", "phone", "display_name"} {
+			if err := tx.Table("users").DropColumn(column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// roles replaces the privilege levels of users with named roles, each of
+// which grants a set of permissions
+// Users of level 2 (Admin) and 3 (Super Admin) become admins, and the others
+// become users, where those of level 0 (Banned) are banned. Column level is
+// dropped, where the table of users is rebuilt on SQLite, which is unable to
+// drop columns. When reverted, the levels are restored from the roles, where
+// users of roles granting users.manage become admins, and banned users become
+// level 0 again
+var roles = Migration{
+	Version: 9,
+	Name:    "roles",
+	Up: func(tx *gorm.DB) error {
+		type Role struct {
+			ID     uint
+			Name   string `gorm:"NOT NULL; UNIQUE"`
+			Grants string `gorm:"NOT NULL"`
+		}
+		type User struct {
+			Role string `gorm:"NOT NULL; DEFAULT:'user'"`
+		}
+		if err := tx.AutoMigrate(&Role{}, &User{}).Error; err != nil {
+			return err
+		}
+		seeds := []Role{
+			{Name: "user", Grants: ""},
+			{Name: "librarian", Grants: "circulation.desk,reports.read"},
+			{Name: "cataloger", Grants: "catalog.write"},
+			{Name: "admin", Grants: "catalog.write,circulation.desk,reports.read,users.manage"},
+		}
+		for i := range seeds {
+			if err := tx.Create(&seeds[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Table("users").Where("level >= ?", 2).UpdateColumn("role", "admin").Error; err != nil {
+			return err
+		}
+		if err := tx.Table("users").Where("level = ?", 0).UpdateColumn("state", "banned").Error; err != nil {
+			return err
+		}
+
+		if tx.Dialect().GetName() != "sqlite3" {
+			return tx.Table("users").DropColumn("level").Error
+		}
+		type UserWithoutLevel struct {
+			ID          uint
+			Username    string `gorm:"NOT NULL; UNIQUE"`
+			Password    string `gorm:"NOT NULL"`
+			Category    string `gorm:"NOT NULL; DEFAULT:'student'"`
+			MaxLoans    uint   `gorm:"NOT NULL; DEFAULT:0"`
+			Email       string
+			Notify      string `gorm:"NOT NULL; DEFAULT:'all'"`
+			DisplayName string
+			Phone       string
+			State       string `gorm:"NOT NULL; DEFAULT:'active'"`
+			Role        string `gorm:"NOT NULL; DEFAULT:'user'"`
+		}
+		columns := "id, username, password, category, max_loans, email, notify, display_name, phone, state, role"
+		steps := []func() error{
+			func() error { return tx.Table("users_rebuilt").CreateTable(&UserWithoutLevel{}).Error },
+			func() error {
+				return tx.Exec("INSERT INTO users_rebuilt (" + columns + ") SELECT " + columns + " FROM users").Error
+			},
+			func() error { return tx.DropTable("users").Error },
+			func() error { return tx.Exec("ALTER TABLE users_rebuilt RENAME TO users").Error },
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		type User struct {
+			Level uint `gorm:"NOT NULL; DEFAULT:1"`
+		}
+		if err := tx.AutoMigrate(&User{}).Error; err != nil {
+			return err
+		}
+		managers := tx.Table("roles").Select("name").Where("grants LIKE ?", "%users.manage%").QueryExpr()
+		if err := tx.Table("users").Where("role IN (?)", managers).UpdateColumn("level", 2).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("users").Where("state = ?", "banned").UpdateColumn("level", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.DropTableIfExists("roles").Error; err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		return tx.Table("users").DropColumn("role").Error
+	},
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// sessions adds the login sessions kept on the server, which used to be kept
+// in the cookies of the clients
+// The sessions in the old cookies are no longer accepted, so users have to
+// log in again
+var sessions = Migration{
+	Version: 10,
+	Name:    "sessions",
+	Up: func(tx *gorm.DB) error {
+		type Session struct {
+			ID         uint
+			TokenHash  string `gorm:"NOT NULL; UNIQUE"`
+			UserID     uint   `gorm:"NOT NULL; INDEX"`
+			IP         string
+			UserAgent  string
+			CreatedAt  time.Time `gorm:"NOT NULL"`
+			LastSeenAt time.Time `gorm:"NOT NULL"`
+		}
+		return tx.AutoMigrate(&Session{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists("sessions").Error
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// apiTokens adds the personal access tokens of users
+var apiTokens = Migration{
+	Version: 11,
+	Name:    "api_tokens",
+	Up: func(tx *gorm.DB) error {
+		type APIToken struct {
+			ID         uint
+			UserID     uint   `gorm:"NOT NULL; INDEX"`
+			Name       string `gorm:"NOT NULL"`
+			Prefix     string `gorm:"NOT NULL"`
+			TokenHash  string `gorm:"NOT NULL; UNIQUE"`
+			Grants     string `gorm:"NOT NULL"`
+			ExpiresAt  *time.Time
+			LastUsedAt *time.Time
+			CreatedAt  time.Time
+		}
+		return tx.AutoMigrate(&APIToken{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists("api_tokens").Error
+	},
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// accountStates adds why, by whom and until when the state of a user was set,
+// where users may be suspended or banned as well
+// When reverted, the columns are kept on SQLite, which is unable to drop
+// columns. Suspended and banned users are able to log in to the older
+// releases, so reactivate or remove them before rolling back
+var accountStates = Migration{
+	Version: 12,
+	Name:    "account_states",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			StateReason    string
+			StateSetBy     uint `gorm:"NOT NULL; DEFAULT:0"`
+			SuspendedUntil *time.Time
+		}
+		return tx.AutoMigrate(&User{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		for _, column := range []string{"suspended_until", "state_set_by", "state_reason"} {
+			if err := tx.Table("users").DropColumn(column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// loginFailures adds the failed logins counted for each username and IP
+// address, which throttle logins and lock them out
+var loginFailures = Migration{
+	Version: 13,
+	Name:    "login_failures",
+	Up: func(tx *gorm.DB) error {
+		type LoginFailure struct {
+			ID           uint
+			Kind         string    `gorm:"NOT NULL; UNIQUE_INDEX:idx_login_failures_target"`
+			Target       string    `gorm:"NOT NULL; UNIQUE_INDEX:idx_login_failures_target"`
+			Failures     uint      `gorm:"NOT NULL"`
+			LockedOut    bool      `gorm:"NOT NULL"`
+			LockedUntil  time.Time `gorm:"NOT NULL"`
+			LastFailedAt time.Time `gorm:"NOT NULL"`
+		}
+		return tx.AutoMigrate(&LoginFailure{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists("login_failures").Error
+	},
+}
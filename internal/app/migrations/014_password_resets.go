@@ -0,0 +1,23 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+// passwordResets adds whether users have to change their passwords, which is
+// set when an admin resets the password
+// When reverted, the column is kept on SQLite, which is unable to drop columns
+var passwordResets = Migration{
+	Version: 14,
+	Name:    "password_resets",
+	Up: func(tx *gorm.DB) error {
+		type User struct {
+			MustChangePassword bool `gorm:"NOT NULL; DEFAULT:false"`
+		}
+		return tx.AutoMigrate(&User{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Dialect().GetName() == "sqlite3" {
+			return nil
+		}
+		return tx.Table("users").DropColumn("must_change_password").Error
+	},
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+// activeHolds makes sure a user has at most one active hold on a book, by a
+// unique index on the active holds
+// MySQL has no partial indexes, so the index is on a generated column which is
+// the book ID of the active holds, and NULL otherwise. Duplicate active holds
+// placed before are cancelled first, keeping the one ready for pickup or the
+// earliest one, and passing the copies kept for the others back into
+// circulation
+var activeHolds = Migration{
+	Version: 15,
+	Name:    "active_holds",
+	Up: func(tx *gorm.DB) error {
+		type Hold struct {
+			ID     uint
+			UserID uint
+			BookID uint
+			CopyID uint
+			Status string
+		}
+		var holds []Hold
+		err := tx.Table("holds").Select("id, user_id, book_id, copy_id, status").
+			Where("status IN (?)", []string{"waiting", "ready"}).
+			Order("CASE WHEN status = 'ready' THEN 0 ELSE 1 END, id").
+			Scan(&holds).Error
+		if err != nil {
+			return err
+		}
+		kept := make(map[[2]uint]bool)
+		for _, hold := range holds {
+			key := [2]uint{hold.UserID, hold.BookID}
+			if !kept[key] {
+				kept[key] = true
+				continue
+			}
+			err := tx.Table("holds").Where("id = ?", hold.ID).
+				UpdateColumn("status", "cancelled").Error
+			if err != nil {
+				return err
+			}
+			if hold.Status != "ready" {
+				continue
+			}
+			err = tx.Table("copies").Where("id = ? AND status = ?", hold.CopyID, "on_hold").
+				UpdateColumn("status", "available").Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if tx.Dialect().GetName() != "mysql" {
+			return tx.Exec("CREATE UNIQUE INDEX idx_holds_active ON holds (user_id, book_id) " +
+				"WHERE status IN ('waiting', 'ready')").Error
+		}
+		err = tx.Exec("ALTER TABLE holds ADD COLUMN active_book_id INT UNSIGNED " +
+			"AS (IF(status IN ('waiting', 'ready'), book_id, NULL)) STORED").Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX idx_holds_active ON holds (user_id, active_book_id)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Table("holds").RemoveIndex("idx_holds_active").Error; err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() != "mysql" {
+			return nil
+		}
+		return tx.Table("holds").DropColumn("active_book_id").Error
+	},
+}
@@ -0,0 +1,231 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidVersion occurs when the target version is not a known migration
+var ErrInvalidVersion = errors.New("migrate: invalid version")
+
+// ErrSchemaTooNew occurs when the database has migrations applied which are
+// unknown to this release, e.g. after rolling back to an older release
+var ErrSchemaTooNew = errors.New("migrate: database schema is newer than this release, migrate down using the newer release first")
+
+// Migration is a numbered change to the database schema
+// Up and Down run in a transaction, where Down may be nil if there's nothing
+// to revert, e.g. a data migration
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// String returns the version and name of the migration, e.g. 001_create_tables
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+// SchemaVersion is a migration applied to the database
+type SchemaVersion struct {
+	Version   uint      `json:"version" gorm:"primary_key; AUTO_INCREMENT:false"`
+	Name      string    `json:"name" gorm:"NOT NULL"`
+	AppliedAt time.Time `json:"applied_at" gorm:"NOT NULL"`
+}
+
+// Status is a known migration, along with when it was applied if so
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// all is the list of migrations, sorted by version
+// New migrations are appended to the list, and applied migrations should
+// never be modified
+var all = []Migration{
+	createTables,
+	backfillAuthors,
+	normalizeISBNs,
+	loanPolicies,
+	maxLoans,
+	libraryCalendar,
+	notices,
+	registration,
+	roles,
+	sessions,
+	apiTokens,
+	accountStates,
+	loginFailures,
+	passwordResets,
+	activeHolds,
+}
+
+// Latest returns the version of the last known migration
+func Latest() uint {
+	return all[len(all)-1].Version
+}
+
+// Current returns the version of the last migration applied to the database,
+// or 0 if none
+func Current(db *gorm.DB) (uint, error) {
+	applied, err := appliedVersions(db)
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// List shows all known migrations and whether they're applied
+func List(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[uint]time.Time, len(applied))
+	for _, v := range applied {
+		appliedAt[v.Version] = v.AppliedAt
+	}
+	list := make([]Status, len(all))
+	for i, m := range all {
+		list[i].Migration = m
+		if t, ok := appliedAt[m.Version]; ok {
+			list[i].AppliedAt = &t
+		}
+	}
+	return list, nil
+}
+
+// Up applies the pending migrations up to the target version in order, or
+// all of them if the target is 0
+// The migrations applied are returned even if a later one fails
+func Up(db *gorm.DB, to uint) ([]Migration, error) {
+	if to == 0 {
+		to = Latest()
+	}
+	if !known(to) {
+		return nil, ErrInvalidVersion
+	}
+	applied, err := appliedSet(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range all {
+		if m.Version > to || applied[m.Version] {
+			continue
+		}
+		if err := run(db, m, true); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down reverts the applied migrations after the target version in reverse
+// order, where a target of 0 reverts all of them
+// The migrations reverted are returned even if a later one fails
+func Down(db *gorm.DB, to uint) ([]Migration, error) {
+	if to != 0 && !known(to) {
+		return nil, ErrInvalidVersion
+	}
+	applied, err := appliedSet(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= to || !applied[m.Version] {
+			continue
+		}
+		if err := run(db, m, false); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Previous returns the version before reverting the given number of applied
+// migrations, which is the target of Down
+func Previous(db *gorm.DB, steps uint) (uint, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	if steps >= uint(len(applied)) {
+		return 0, nil
+	}
+	return applied[uint(len(applied))-steps-1].Version, nil
+}
+
+// run applies or reverts a migration in a transaction, and records it in the
+// schema versions
+func run(db *gorm.DB, m Migration, up bool) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	var err error
+	if up {
+		if err = m.Up(tx); err == nil {
+			err = tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}
+	} else {
+		if m.Down != nil {
+			err = m.Down(tx)
+		}
+		if err == nil {
+			err = tx.Where("version = ?", m.Version).Delete(&SchemaVersion{}).Error
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: %v failed: %w", m, err)
+	}
+	return tx.Commit().Error
+}
+
+// appliedVersions returns the migrations applied to the database sorted by
+// version, and creates the schema versions table if not exists
+// It fails if any of them is unknown to this release
+func appliedVersions(db *gorm.DB) ([]SchemaVersion, error) {
+	if err := db.AutoMigrate(&SchemaVersion{}).Error; err != nil {
+		return nil, err
+	}
+	var applied []SchemaVersion
+	if err := db.Order("version").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range applied {
+		if !known(v.Version) {
+			return nil, ErrSchemaTooNew
+		}
+	}
+	return applied, nil
+}
+
+func appliedSet(db *gorm.DB) (map[uint]bool, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[uint]bool, len(applied))
+	for _, v := range applied {
+		set[v.Version] = true
+	}
+	return set, nil
+}
+
+func known(version uint) bool {
+	i := sort.Search(len(all), func(i int) bool { return all[i].Version >= version })
+	return i < len(all) && all[i].Version == version
+}
@@ -1,10 +1,101 @@
 package models
 
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
 // Book is the basic object stored in the database
+// Author is the byline of the book, which is kept in sync with Authors by
+// SetAuthors. Volume is the number of the book in its series
 type Book struct {
-	ID        uint   `json:"id"`
-	Title     string `json:"title" gorm:"NOT NULL"`
-	Author    string `json:"author"`
-	Publisher string `json:"publisher"`
-	ISBN      string `json:"isbn"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title" gorm:"NOT NULL"`
+	Author    string    `json:"author"`
+	Authors   []Author  `json:"authors" gorm:"many2many:book_authors"`
+	Publisher string    `json:"publisher"`
+	ISBN      string    `json:"isbn" gorm:"INDEX"`
+	Year      uint      `json:"year"`
+	Edition   string    `json:"edition"`
+	Language  string    `json:"language"`
+	Pages     uint      `json:"pages"`
+	Series    string    `json:"series"`
+	Volume    uint      `json:"volume"`
+	Subjects  []Subject `json:"subjects" gorm:"many2many:book_subjects"`
+}
+
+// Author is a person who wrote one or more books
+type Author struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name" gorm:"NOT NULL; UNIQUE"`
+}
+
+// Subject is a tag used to categorize books
+type Subject struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name" gorm:"NOT NULL; UNIQUE"`
+}
+
+// SplitNames splits a list of names separated by ";" or ",", and removes the
+// blank and duplicate ones
+func SplitNames(s string) []string {
+	return uniqueNames(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ','
+	}))
+}
+
+func uniqueNames(names []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+// SetAuthors replaces the authors of the book with the ones of given names,
+// adding new authors to the database if necessary
+// The byline is updated to the names in order
+func (b *Book) SetAuthors(db *gorm.DB, names []string) error {
+	names = uniqueNames(names)
+	authors := make([]Author, len(names))
+	for i, name := range names {
+		if err := db.Where(Author{Name: name}).FirstOrCreate(&authors[i]).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Model(b).Association("Authors").Replace(authors).Error; err != nil {
+		return err
+	}
+	b.Author = strings.Join(names, ", ")
+	return db.Model(&Book{}).Where("id = ?", b.ID).Update("author", b.Author).Error
+}
+
+// SetSubjects replaces the subjects of the book with the ones of given names,
+// adding new subjects to the database if necessary
+func (b *Book) SetSubjects(db *gorm.DB, names []string) error {
+	names = uniqueNames(names)
+	subjects := make([]Subject, len(names))
+	for i, name := range names {
+		if err := db.Where(Subject{Name: name}).FirstOrCreate(&subjects[i]).Error; err != nil {
+			return err
+		}
+	}
+	return db.Model(b).Association("Subjects").Replace(subjects).Error
+}
+
+// SubjectNames returns the names of the subjects of the book
+func (b *Book) SubjectNames() []string {
+	names := make([]string, len(b.Subjects))
+	for i, subject := range b.Subjects {
+		names[i] = subject.Name
+	}
+	return names
 }
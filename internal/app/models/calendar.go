@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/calendar"
+)
+
+// ErrInvalidWeekday occurs when the weekday is out of range
+var ErrInvalidWeekday = errors.New("validate: invalid weekday, expected 0 (Sunday) to 6 (Saturday)")
+
+// ErrInvalidHours occurs when the opening hours are malformed, or the library
+// closes before it opens
+var ErrInvalidHours = errors.New("validate: invalid opening hours, expected hh:mm where opens is before closes")
+
+// ErrInvalidClosedDate occurs when the date of a closed day is malformed
+var ErrInvalidClosedDate = errors.New("validate: invalid date, expected yyyy-mm-dd")
+
+// OpeningHours is the opening hours of the library on a weekday, where
+// Weekday is 0 for Sunday, and Opens and Closes are in the format hh:mm
+// Books fall due at the closing time, and never on a day marked as Closed
+type OpeningHours struct {
+	ID      uint   `json:"-"`
+	Weekday uint   `json:"weekday" gorm:"NOT NULL; UNIQUE_INDEX"`
+	Opens   string `json:"opens" gorm:"NOT NULL"`
+	Closes  string `json:"closes" gorm:"NOT NULL"`
+	Closed  bool   `json:"closed" gorm:"NOT NULL"`
+}
+
+// ClosedDay is a date when the library is closed, e.g. a public holiday, in
+// the format yyyy-mm-dd
+// UID is the unique identifier of the event if imported from an iCalendar file
+type ClosedDay struct {
+	ID   uint   `json:"id"`
+	Date string `json:"date" gorm:"NOT NULL; UNIQUE_INDEX"`
+	Name string `json:"name"`
+	UID  string `json:"uid,omitempty"`
+}
+
+// Validate checks if the weekday is in range, and the library opens before it
+// closes unless closed all day
+func (h *OpeningHours) Validate() error {
+	if h.Weekday > uint(time.Saturday) {
+		return ErrInvalidWeekday
+	}
+	if h.Closed {
+		return nil
+	}
+	opens, err := time.Parse(calendar.ClockLayout, h.Opens)
+	if err != nil {
+		return ErrInvalidHours
+	}
+	closes, err := time.Parse(calendar.ClockLayout, h.Closes)
+	if err != nil || !opens.Before(closes) {
+		return ErrInvalidHours
+	}
+	return nil
+}
+
+// Validate checks if the date is well formed
+func (d *ClosedDay) Validate() error {
+	if _, err := time.Parse(calendar.DateLayout, d.Date); err != nil {
+		return ErrInvalidClosedDate
+	}
+	return nil
+}
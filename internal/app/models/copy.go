@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Status of a physical copy
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on_loan"
+	CopyOnHold    = "on_hold"
+	CopyLost      = "lost"
+	CopyDamaged   = "damaged"
+	CopyInRepair  = "in_repair"
+)
+
+// ErrBarcodeRequired occurs when the barcode field is left blank
+var ErrBarcodeRequired = errors.New("validate: barcode required")
+
+// ErrInvalidCopyStatus occurs when the status of a copy is unknown
+var ErrInvalidCopyStatus = errors.New("validate: invalid copy status")
+
+// Copy is a physical item of a book in the library
+// A book may have multiple copies, each of which can be borrowed by one user
+// at a time, and is soft deleted when retired
+// Category is the item category, which decides the loan policies applied
+type Copy struct {
+	ID        uint       `json:"id"`
+	BookID    uint       `json:"book_id" gorm:"NOT NULL; INDEX"`
+	Barcode   string     `json:"barcode" gorm:"NOT NULL; UNIQUE"`
+	Location  string     `json:"location"`
+	Status    string     `json:"status" gorm:"NOT NULL"`
+	Category  string     `json:"category" gorm:"NOT NULL; DEFAULT:'regular'"`
+	DeletedAt *time.Time `json:"retired_at"`
+}
+
+// ValidateCopyStatus checks if the status is one that can be set manually
+// A copy can only be put on loan by borrowing it, and on hold by the hold queue
+func ValidateCopyStatus(status string) error {
+	switch status {
+	case CopyAvailable, CopyLost, CopyDamaged, CopyInRepair:
+		return nil
+	}
+	return ErrInvalidCopyStatus
+}
+
+// ItemCategory returns the item category of the copy, which is regular if left
+// blank
+func (c *Copy) ItemCategory() string {
+	if c.Category == "" {
+		return ItemCategories[0]
+	}
+	return c.Category
+}
+
+// BeforeSave trims the barcode before saving copy data
+func (c *Copy) BeforeSave() error {
+	c.Barcode = strings.TrimSpace(c.Barcode)
+	return nil
+}
+
+// Validate checks if the copy has the required fields
+func (c *Copy) Validate() error {
+	if strings.TrimSpace(c.Barcode) == "" {
+		return ErrBarcodeRequired
+	}
+	if err := ValidateItemCategory(c.ItemCategory()); err != nil {
+		return err
+	}
+	return ValidateCopyStatus(c.Status)
+}
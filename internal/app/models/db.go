@@ -1,24 +1,117 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hakula139/REALMS/internal/app/config"
 	"github.com/jinzhu/gorm"
+
+	// Database drivers
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// Supported database types
+const (
+	DbMySQL    = "mysql"
+	DbPostgres = "postgres"
+	DbSQLite   = "sqlite3"
 )
 
-// DbSetup opens a database connection and initializes
+// sqliteMemory is the path of an in-memory SQLite database
+const sqliteMemory = ":memory:"
+
+// ErrUnsupportedDb occurs when the database type is unknown
+var ErrUnsupportedDb = errors.New("database: unsupported type, expected mysql / postgres / sqlite3")
+
+// DbSetup opens a database connection of the type in the config, and creates
+// the database if not exists
+// The schema is set up by migrations afterwards
 func DbSetup(cfg config.DbConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@%s(%s:%d)/?parseTime=true",
-		cfg.Username, cfg.Password,
-		cfg.Protocol, cfg.Host, cfg.Port)
-	db, err := gorm.Open(cfg.Type, dsn)
+	var db *gorm.DB
+	var err error
+	switch cfg.Type {
+	case DbMySQL:
+		db, err = mysqlOpen(cfg)
+	case DbPostgres:
+		db, err = postgresOpen(cfg)
+	case DbSQLite, "sqlite":
+		db, err = sqliteOpen(cfg)
+	default:
+		err = ErrUnsupportedDb
+	}
 	if err != nil {
 		fmt.Println("[error] DbSetup: connection failed: " + err.Error())
 		return nil, err
 	}
-	db.Exec("CREATE DATABASE IF NOT EXISTS " + cfg.Database)
-	db.Exec("USE " + cfg.Database)
-	db.AutoMigrate(&Book{}, &User{}, &Record{})
 	return db, nil
 }
+
+func mysqlOpen(cfg config.DbConfig) (*gorm.DB, error) {
+	dsn := func(database string) string {
+		return fmt.Sprintf("%s:%s@%s(%s:%d)/%s?charset=utf8mb4&parseTime=true",
+			cfg.Username, cfg.Password,
+			cfg.Protocol, cfg.Host, cfg.Port, database)
+	}
+
+	// Creates the database using a connection without a default database
+	db, err := gorm.Open(DbMySQL, dsn(""))
+	if err != nil {
+		return nil, err
+	}
+	err = db.Exec("CREATE DATABASE IF NOT EXISTS `" + cfg.Database + "`").Error
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(DbMySQL, dsn(cfg.Database))
+}
+
+func postgresOpen(cfg config.DbConfig) (*gorm.DB, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := func(database string) string {
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.Username, cfg.Password, database, sslMode)
+	}
+
+	// Creates the database using the maintenance database, since PostgreSQL
+	// has no CREATE DATABASE IF NOT EXISTS
+	db, err := gorm.Open(DbPostgres, dsn("postgres"))
+	if err != nil {
+		return nil, err
+	}
+	var count uint
+	err = db.Table("pg_database").Where("datname = ?", cfg.Database).Count(&count).Error
+	if err == nil && count == 0 {
+		err = db.Exec(`CREATE DATABASE "` + cfg.Database + `"`).Error
+	}
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(DbPostgres, dsn(cfg.Database))
+}
+
+func sqliteOpen(cfg config.DbConfig) (*gorm.DB, error) {
+	path := cfg.Path
+	if path == "" {
+		path = cfg.Database + ".db"
+	}
+	if path == sqliteMemory {
+		db, err := gorm.Open(DbSQLite, path)
+		if err != nil {
+			return nil, err
+		}
+		// Each connection to an in-memory database opens a new one
+		db.DB().SetMaxOpenConns(1)
+		return db, nil
+	}
+	// Takes the write lock when a transaction begins, and waits for the lock
+	// instead of failing when written concurrently
+	return gorm.Open(DbSQLite, "file:"+path+"?_busy_timeout=5000&_foreign_keys=1&_txlock=immediate")
+}
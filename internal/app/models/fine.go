@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Status of a fine
+const (
+	FineUnpaid = "unpaid"
+	FinePaid   = "paid"
+	FineWaived = "waived"
+)
+
+// Fine is charged when a user returns a book past the return date
+// ResolvedBy is the ID of the admin who marked the fine as paid or waived
+type Fine struct {
+	ID          uint       `json:"id"`
+	UserID      uint       `json:"user_id" gorm:"NOT NULL; INDEX"`
+	RecordID    uint       `json:"record_id" gorm:"NOT NULL"`
+	OverdueDays uint       `json:"overdue_days" gorm:"NOT NULL"`
+	Amount      float64    `json:"amount" gorm:"NOT NULL"`
+	Status      string     `json:"status" gorm:"NOT NULL"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	ResolvedBy  uint       `json:"resolved_by"`
+	Message     string     `json:"message"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Status of a hold
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+	HoldExpired   = "expired"
+)
+
+// Hold is placed when a user wants to borrow a book with no available copies
+// Holds on the same book are served first in, first out. When a copy is
+// returned, it's kept for the first user in line until the pickup deadline
+type Hold struct {
+	ID             uint       `json:"id"`
+	UserID         uint       `json:"user_id" gorm:"NOT NULL; INDEX"`
+	BookID         uint       `json:"book_id" gorm:"NOT NULL; INDEX"`
+	CopyID         uint       `json:"copy_id"`
+	Status         string     `json:"status" gorm:"NOT NULL"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PickupDeadline *time.Time `json:"pickup_deadline"`
+	Position       uint       `json:"position,omitempty" gorm:"-"`
+}
+
+// IsActive checks if the hold is still waiting in line or ready for pickup
+func (h *Hold) IsActive() bool {
+	return h.Status == HoldWaiting || h.Status == HoldReady
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidISBN occurs when the ISBN is neither a valid ISBN-10 nor ISBN-13
+var ErrInvalidISBN = errors.New("validate: invalid ISBN")
+
+// NormalizeISBN validates the checksum of an ISBN-10 or ISBN-13, and returns
+// it as a canonical ISBN-13 of digits only
+// Hyphens and spaces are ignored, and a blank ISBN is kept blank
+func NormalizeISBN(isbn string) (string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+	switch len(isbn) {
+	case 0:
+		return "", nil
+	case 10:
+		if !validISBN10(isbn) {
+			return "", ErrInvalidISBN
+		}
+		isbn = "978" + isbn[:9]
+		return isbn + string(isbn13CheckDigit(isbn)), nil
+	case 13:
+		if !validISBN13(isbn) {
+			return "", ErrInvalidISBN
+		}
+		return isbn, nil
+	}
+	return "", ErrInvalidISBN
+}
+
+// ISBN10 converts a canonical ISBN-13 to ISBN-10, which is blank if the
+// ISBN-13 has no ISBN-10 equivalent, i.e. not prefixed with 978
+func ISBN10(isbn string) string {
+	if len(isbn) != 13 || !strings.HasPrefix(isbn, "978") {
+		return ""
+	}
+	sum := 0
+	for i, r := range isbn[3:12] {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return isbn[3:12] + "X"
+	}
+	return isbn[3:12] + string(rune('0'+check))
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		switch {
+		case r >= '0' && r <= '9':
+			sum += (10 - i) * int(r-'0')
+		case r == 'X' && i == 9:
+			sum += 10
+		default:
+			return false
+		}
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(isbn string) bool {
+	if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+		return false
+	}
+	for _, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(isbn) == rune(isbn[12])
+}
+
+// isbn13CheckDigit computes the check digit from the first 12 digits
+func isbn13CheckDigit(isbn string) rune {
+	sum := 0
+	for i, r := range isbn[:12] {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	return rune('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,90 @@
+package models
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name string
+		isbn string
+		want string
+	}{
+		{"blank", "", ""},
+		{"spaces only", "  ", ""},
+		{"ISBN-10", "0306406152", "9780306406157"},
+		{"ISBN-10 with hyphens", "0-306-40615-2", "9780306406157"},
+		{"ISBN-10 with spaces", "0 306 40615 2", "9780306406157"},
+		{"ISBN-10 with check digit X", "0-8044-2957-X", "9780804429573"},
+		{"ISBN-10 with lowercase x", "080442957x", "9780804429573"},
+		{"ISBN-10 of another check digit in ISBN-13", "0-13-409266-X", "9780134092669"},
+		{"ISBN-13", "9780306406157", "9780306406157"},
+		{"ISBN-13 with hyphens", "978-0-306-40615-7", "9780306406157"},
+		{"ISBN-13 with spaces and hyphens", "978 1-985086-59 3", "9781985086593"},
+		{"ISBN-13 prefixed with 979", "979-10-90636-07-1", "9791090636071"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.isbn)
+			if err != nil {
+				t.Fatalf("NormalizeISBN(%q) = %v", tt.isbn, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeISBN(%q) = %q, want %q", tt.isbn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeISBNInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		isbn string
+	}{
+		{"ISBN-10 with wrong check digit", "0-306-40615-3"},
+		{"ISBN-10 with X not last", "0-306-4X615-2"},
+		{"ISBN-10 with X as wrong check digit", "0-306-40615-X"},
+		{"ISBN-10 with letters", "0-306-4O615-2"},
+		{"ISBN-13 with wrong check digit", "978-0-306-40615-8"},
+		{"ISBN-13 with X", "978-0-306-40615-X"},
+		{"ISBN-13 with unknown prefix", "977-0-306-40615-7"},
+		{"too short", "030640615"},
+		{"too long", "97803064061571"},
+		{"length 11", "03064061521"},
+		{"other separators", "0.306.40615.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := NormalizeISBN(tt.isbn); err != ErrInvalidISBN {
+				t.Errorf("NormalizeISBN(%q) = %q, %v, want %v", tt.isbn, got, err, ErrInvalidISBN)
+			}
+		})
+	}
+}
+
+func TestISBN10(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want string
+	}{
+		{"9780306406157", "0306406152"},
+		{"9780804429573", "080442957X"},
+		{"9780134092669", "013409266X"},
+		{"9791090636071", ""},
+		{"", ""},
+		{"0306406152", ""},
+	}
+	for _, tt := range tests {
+		if got := ISBN10(tt.isbn); got != tt.want {
+			t.Errorf("ISBN10(%q) = %q, want %q", tt.isbn, got, tt.want)
+		}
+	}
+
+	// Converting back and forth keeps the ISBN
+	for _, tt := range tests {
+		if tt.want == "" {
+			continue
+		}
+		if got, _ := NormalizeISBN(tt.want); got != tt.isbn {
+			t.Errorf("NormalizeISBN(ISBN10(%q)) = %q", tt.isbn, got)
+		}
+	}
+}
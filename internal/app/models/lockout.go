@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Kinds of what the failed logins are counted for
+const (
+	LoginFailureUsername = "username"
+	LoginFailureIP       = "ip"
+)
+
+// maxBackoffShift limits how many times the backoff is doubled
+const maxBackoffShift = 16
+
+// LoginFailure counts the failed logins in a row of a username or an IP
+// address, given by Kind and Target
+// No login is allowed before LockedUntil, which is a short backoff after each
+// failure, or the whole lockout if LockedOut is set. Usernames are counted
+// whether the user exists or not, so that a lockout tells nothing about the
+// users
+type LoginFailure struct {
+	ID           uint      `json:"id"`
+	Kind         string    `json:"kind" gorm:"NOT NULL; UNIQUE_INDEX:idx_login_failures_target"`
+	Target       string    `json:"target" gorm:"NOT NULL; UNIQUE_INDEX:idx_login_failures_target"`
+	Failures     uint      `json:"failures" gorm:"NOT NULL"`
+	LockedOut    bool      `json:"locked_out" gorm:"NOT NULL"`
+	LockedUntil  time.Time `json:"locked_until" gorm:"NOT NULL"`
+	LastFailedAt time.Time `json:"last_failed_at" gorm:"NOT NULL"`
+}
+
+// Blocked checks if logins are refused at the time
+func (f LoginFailure) Blocked(now time.Time) bool {
+	return now.Before(f.LockedUntil)
+}
+
+// Fail counts a failed login at the time, after which the next login is
+// allowed when the backoff, doubled on each failure, has passed, or the
+// lockout when max failures are reached, where a zero max never locks out
+// The failures before are forgotten if none occurs for the lockout
+func (f *LoginFailure) Fail(now time.Time, max uint, backoff, lockout time.Duration) {
+	if lockout > 0 && now.Sub(f.LastFailedAt) > lockout {
+		f.Failures = 0
+		f.LockedOut = false
+	}
+	f.Failures++
+	f.LastFailedAt = now
+
+	if max > 0 && f.Failures >= max {
+		f.LockedOut = true
+		f.LockedUntil = now.Add(lockout)
+		return
+	}
+	shift := f.Failures - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	wait := backoff << shift
+	if lockout > 0 && wait > lockout {
+		wait = lockout
+	}
+	f.LockedUntil = now.Add(wait)
+}
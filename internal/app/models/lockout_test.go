@@ -0,0 +1,145 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginFailureFail(t *testing.T) {
+	start := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	type attempt struct {
+		after time.Duration // since start
+		// The expected state after the failure
+		failures  uint
+		lockedOut bool
+		wait      time.Duration // until LockedUntil
+	}
+	tests := []struct {
+		name     string
+		max      uint
+		backoff  time.Duration
+		lockout  time.Duration
+		attempts []attempt
+	}{
+		{
+			name:    "backoff doubles until locked out",
+			max:     5,
+			backoff: time.Second,
+			lockout: 15 * time.Minute,
+			attempts: []attempt{
+				{0, 1, false, time.Second},
+				{time.Second, 2, false, 2 * time.Second},
+				{3 * time.Second, 3, false, 4 * time.Second},
+				{7 * time.Second, 4, false, 8 * time.Second},
+				{15 * time.Second, 5, true, 15 * time.Minute},
+			},
+		},
+		{
+			name:    "backoff capped by lockout",
+			max:     0,
+			backoff: time.Minute,
+			lockout: 5 * time.Minute,
+			attempts: []attempt{
+				{0, 1, false, time.Minute},
+				{time.Minute, 2, false, 2 * time.Minute},
+				{3 * time.Minute, 3, false, 4 * time.Minute},
+				{7 * time.Minute, 4, false, 5 * time.Minute},
+				{12 * time.Minute, 5, false, 5 * time.Minute},
+			},
+		},
+		{
+			name:    "forgotten after lockout",
+			max:     3,
+			backoff: time.Second,
+			lockout: 15 * time.Minute,
+			attempts: []attempt{
+				{0, 1, false, time.Second},
+				{time.Second, 2, false, 2 * time.Second},
+				{20 * time.Minute, 1, false, time.Second},
+			},
+		},
+		{
+			name:    "locked out again after lockout",
+			max:     2,
+			backoff: time.Second,
+			lockout: time.Minute,
+			attempts: []attempt{
+				{0, 1, false, time.Second},
+				{time.Second, 2, true, time.Minute},
+				// Failing while locked out counts, and extends the lockout
+				{30 * time.Second, 3, true, time.Minute},
+				{5 * time.Minute, 1, false, time.Second},
+			},
+		},
+		{
+			name:    "no backoff",
+			max:     3,
+			backoff: 0,
+			lockout: time.Minute,
+			attempts: []attempt{
+				{0, 1, false, 0},
+				{0, 2, false, 0},
+				{0, 3, true, time.Minute},
+			},
+		},
+		{
+			name:    "never forgotten without lockout",
+			max:     0,
+			backoff: time.Second,
+			lockout: 0,
+			attempts: []attempt{
+				{0, 1, false, time.Second},
+				{24 * time.Hour, 2, false, 2 * time.Second},
+				{48 * time.Hour, 3, false, 4 * time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f LoginFailure
+			for i, a := range tt.attempts {
+				now := start.Add(a.after)
+				f.Fail(now, tt.max, tt.backoff, tt.lockout)
+				if f.Failures != a.failures || f.LockedOut != a.lockedOut || !f.LockedUntil.Equal(now.Add(a.wait)) {
+					t.Fatalf("after failure %v: failures = %v, locked out = %v, wait = %v, want %v, %v, %v",
+						i+1, f.Failures, f.LockedOut, f.LockedUntil.Sub(now), a.failures, a.lockedOut, a.wait)
+				}
+				if !f.LastFailedAt.Equal(now) {
+					t.Errorf("after failure %v: LastFailedAt = %v, want %v", i+1, f.LastFailedAt, now)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginFailureBackoffLimit(t *testing.T) {
+	// The backoff stops doubling, rather than overflowing after many failures
+	now := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	f := LoginFailure{Failures: 1000, LastFailedAt: now}
+	f.Fail(now, 0, time.Second, 0)
+	if wait := f.LockedUntil.Sub(now); wait != time.Second<<maxBackoffShift {
+		t.Errorf("wait = %v, want %v", wait, time.Second<<maxBackoffShift)
+	}
+}
+
+func TestLoginFailureBlocked(t *testing.T) {
+	until := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	f := LoginFailure{LockedUntil: until}
+	tests := []struct {
+		now  time.Time
+		want bool
+	}{
+		{until.Add(-time.Minute), true},
+		{until.Add(-time.Nanosecond), true},
+		{until, false},
+		{until.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		if got := f.Blocked(tt.now); got != tt.want {
+			t.Errorf("Blocked(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+	if (LoginFailure{}).Blocked(until) {
+		t.Error("Blocked without failures = true, want false")
+	}
+}
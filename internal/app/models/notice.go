@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"time"
+)
+
+// Kinds of notices
+const (
+	NoticeDueSoon = "due_soon"
+	NoticeOverdue = "overdue"
+)
+
+// Notification preferences of users
+const (
+	NotifyAll     = "all"
+	NotifyOverdue = "overdue"
+	NotifyNone    = "none"
+)
+
+// NotifyPreferences are all notification preferences, where the first one is
+// the default
+var NotifyPreferences = []string{NotifyAll, NotifyOverdue, NotifyNone}
+
+// ErrInvalidNotifyPreference occurs when the notification preference is
+// unknown
+var ErrInvalidNotifyPreference = errors.New("validate: invalid notification preference, expected all / overdue / none")
+
+// ErrInvalidEmail occurs when the email address is malformed
+var ErrInvalidEmail = errors.New("validate: invalid email address")
+
+// Notice is a reminder of a loan due soon or overdue sent to a user through a
+// channel, e.g. smtp, which is recorded so that it's sent only once for each
+// return date of the loan. A loan renewed gets new notices
+type Notice struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id" gorm:"NOT NULL; INDEX"`
+	RecordID    uint      `json:"record_id" gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+	Kind        string    `json:"kind" gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+	Channel     string    `json:"channel" gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+	ExtendTimes uint      `json:"extend_times" gorm:"NOT NULL; UNIQUE_INDEX:idx_notices_record"`
+	ReturnDate  time.Time `json:"return_date" gorm:"NOT NULL"`
+	SentAt      time.Time `json:"sent_at" gorm:"NOT NULL"`
+}
+
+// ValidateNotifyPreference checks if the notification preference is known
+func ValidateNotifyPreference(preference string) error {
+	for _, known := range NotifyPreferences {
+		if preference == known {
+			return nil
+		}
+	}
+	return ErrInvalidNotifyPreference
+}
+
+// ValidateEmail checks if the email address is well formed, where a blank one
+// is allowed
+func ValidateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
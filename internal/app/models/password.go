@@ -0,0 +1,108 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+)
+
+// ErrPasswordTooShort occurs when the password is shorter than the policy
+// requires
+var ErrPasswordTooShort = errors.New("validate: password too short")
+
+// ErrPasswordTooLong occurs when the password is longer than bcrypt hashes
+var ErrPasswordTooLong = errors.New("validate: password too long, expected at most 72 bytes")
+
+// ErrPasswordTooSimple occurs when the password has fewer kinds of characters
+// than the policy requires
+var ErrPasswordTooSimple = errors.New("validate: password too simple, please mix lowercase and uppercase letters, digits and symbols")
+
+// ErrPasswordTooCommon occurs when the password is in the banned list
+var ErrPasswordTooCommon = errors.New("validate: password too common")
+
+// ErrPasswordIsUsername occurs when the password is the username
+var ErrPasswordIsUsername = errors.New("validate: password can't be the username")
+
+// ErrPasswordUnchanged occurs when the new password is the current one
+var ErrPasswordUnchanged = errors.New("validate: new password must differ from the current one")
+
+// maxPasswordBytes is the maximum length of a password, beyond which bcrypt
+// ignores the rest
+const maxPasswordBytes = 72
+
+// temporaryPasswordLength is the length of the passwords set by admins when
+// resetting the passwords of users
+const temporaryPasswordLength = 16
+
+// temporaryPasswordChars are the characters of temporary passwords, without
+// those easily mistaken for each other
+const temporaryPasswordChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ValidatePassword checks if the password of the user meets the policy
+func ValidatePassword(password, username string, policy config.PasswordConfig) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	if uint(utf8.RuneCountInString(password)) < policy.MinLength {
+		return ErrPasswordTooShort
+	}
+	if passwordClasses(password) < policy.MinClasses {
+		return ErrPasswordTooSimple
+	}
+	TrimUsername(&username)
+	if strings.EqualFold(password, username) {
+		return ErrPasswordIsUsername
+	}
+	if policy.Banned[strings.ToLower(password)] {
+		return ErrPasswordTooCommon
+	}
+	return nil
+}
+
+// passwordClasses counts the kinds of characters in the password, among
+// lowercase letters, uppercase letters, digits and symbols
+func passwordClasses(password string) uint {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	var classes uint
+	for _, found := range []bool{lower, upper, digit, symbol} {
+		if found {
+			classes++
+		}
+	}
+	return classes
+}
+
+// NewTemporaryPassword generates a random password, which is set when an
+// admin resets the password of a user, and has to be changed on next login
+func NewTemporaryPassword() (string, error) {
+	max := big.NewInt(int64(len(temporaryPasswordChars)))
+	b := make([]byte, temporaryPasswordLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = temporaryPasswordChars[n.Int64()]
+	}
+	return string(b), nil
+}
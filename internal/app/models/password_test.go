@@ -0,0 +1,137 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+)
+
+func TestValidatePassword(t *testing.T) {
+	policy := config.PasswordConfig{
+		MinLength:  8,
+		MinClasses: 2,
+		Banned:     map[string]bool{"password1": true, "qwerty123": true},
+	}
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     error
+	}{
+		{"empty", "", "alice", ErrPasswordRequired},
+		{"valid", "Wonder1and", "alice", nil},
+		{"too short", "Abc123", "alice", ErrPasswordTooShort},
+		{"min length", "abcdefg1", "alice", nil},
+		// Length is counted in characters, not bytes
+		{"short in characters", "密码密码Ab1", "alice", ErrPasswordTooShort},
+		{"long enough in characters", "密码密码密码Ab", "alice", nil},
+		{"max bytes", strings.Repeat("a", 71) + "1", "alice", nil},
+		{"too long", strings.Repeat("a", 72) + "1", "alice", ErrPasswordTooLong},
+		{"too long in bytes", strings.Repeat("密", 24) + "1", "alice", ErrPasswordTooLong},
+		{"lowercase only", "abcdefghij", "alice", ErrPasswordTooSimple},
+		{"digits only", "1234567890", "alice", ErrPasswordTooSimple},
+		{"lowercase and uppercase", "abcdeFGHIJ", "alice", nil},
+		{"letters and a symbol", "abcdefghi!", "alice", nil},
+		{"spaces as symbols", "correct horse", "alice", nil},
+		{"username", "Alice-2020", "alice-2020", ErrPasswordIsUsername},
+		{"username with spaces", "Alice-2020", " Alice-2020 ", ErrPasswordIsUsername},
+		{"containing the username", "Alice-2020!", "alice-2020", nil},
+		{"banned", "password1", "alice", ErrPasswordTooCommon},
+		{"banned regardless of case", "QWERTY123", "alice", ErrPasswordTooCommon},
+		{"not banned", "password2", "alice", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password, tt.username, policy); err != tt.want {
+				t.Errorf("ValidatePassword(%q, %q) = %v, want %v", tt.password, tt.username, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   config.PasswordConfig
+		password string
+		want     error
+	}{
+		{"no policy", config.PasswordConfig{}, "a", nil},
+		{"default", config.DefaultPasswordConfig, "abcdefgh", ErrPasswordTooSimple},
+		{"longer", config.PasswordConfig{MinLength: 12}, "abcdefgh1234", nil},
+		{"longer but short", config.PasswordConfig{MinLength: 12}, "abcdefgh123", ErrPasswordTooShort},
+		{"all classes", config.PasswordConfig{MinClasses: 4}, "Abcdefg1!", nil},
+		{"all classes but a symbol", config.PasswordConfig{MinClasses: 4}, "Abcdefg12", ErrPasswordTooSimple},
+		{"more classes than there are", config.PasswordConfig{MinClasses: 5}, "Abcdefg1!", ErrPasswordTooSimple},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password, "alice", tt.policy); err != tt.want {
+				t.Errorf("ValidatePassword(%q) = %v, want %v", tt.password, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordBannedFile(t *testing.T) {
+	// The banned list is read from the file set in the config, skipping blank
+	// lines and comments
+	dir, err := ioutil.TempDir("", "realms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	banned := filepath.Join(dir, "banned.txt")
+	list := "# Common passwords\n\nPassword1\r\n  iloveyou1  \n#letmein1\n"
+	if err := ioutil.WriteFile(banned, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "auth_config.json")
+	cfg := `{"password": {"min_length": 8, "min_classes": 2, "banned_file": "` + filepath.ToSlash(banned) + `"}}`
+	if err := ioutil.WriteFile(file, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	authcfg, err := config.LoadAuthConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		want     error
+	}{
+		{"password1", ErrPasswordTooCommon},
+		{"PASSWORD1", ErrPasswordTooCommon},
+		{"iloveyou1", ErrPasswordTooCommon},
+		{"letmein1", nil},
+		{"# Common passwords", nil},
+	}
+	for _, tt := range tests {
+		if err := ValidatePassword(tt.password, "alice", authcfg.Password); err != tt.want {
+			t.Errorf("ValidatePassword(%q) = %v, want %v", tt.password, err, tt.want)
+		}
+	}
+}
+
+func TestNewTemporaryPassword(t *testing.T) {
+	// Temporary passwords are of the same length and characters, and differ
+	// from each other
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		password, err := NewTemporaryPassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != temporaryPasswordLength || strings.Trim(password, temporaryPasswordChars) != "" {
+			t.Errorf("password %q has characters other than %q, or isn't %v long", password, temporaryPasswordChars, temporaryPasswordLength)
+		}
+		if seen[password] {
+			t.Errorf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import "errors"
+
+// Patron categories of users
+const (
+	PatronStudent = "student"
+	PatronFaculty = "faculty"
+	PatronStaff   = "staff"
+	PatronGuest   = "guest"
+)
+
+// Item categories of copies
+const (
+	ItemRegular   = "regular"
+	ItemReserve   = "reserve"
+	ItemReference = "reference"
+)
+
+// PatronCategories are all patron categories, where the first one is the
+// default
+var PatronCategories = []string{PatronStudent, PatronFaculty, PatronStaff, PatronGuest}
+
+// ItemCategories are all item categories, where the first one is the default
+var ItemCategories = []string{ItemRegular, ItemReserve, ItemReference}
+
+// ErrInvalidPatronCategory occurs when the patron category is unknown
+var ErrInvalidPatronCategory = errors.New("validate: invalid patron category, expected student / faculty / staff / guest")
+
+// ErrInvalidItemCategory occurs when the item category is unknown
+var ErrInvalidItemCategory = errors.New("validate: invalid item category, expected regular / reserve / reference")
+
+// ErrLoanDaysRequired occurs when a circulating policy has no loan period
+var ErrLoanDaysRequired = errors.New("validate: loan days required")
+
+// Policy is the loan policy of an item category for a patron category
+// Copies of the item category are not lent to users of the patron category
+// unless Circulates is set, and are lent for LoanDays, which can be extended
+// by RenewDays for at most MaxRenewals times
+// MaxLoans is the maximum number of copies of the item category borrowed by
+// a user at a time, which is unlimited if set to 0
+// The settings in the library config are used if there's no policy for the
+// pair, where copies circulate without limits
+type Policy struct {
+	ID             uint   `json:"id"`
+	PatronCategory string `json:"patron_category" gorm:"NOT NULL; UNIQUE_INDEX:idx_policies_categories"`
+	ItemCategory   string `json:"item_category" gorm:"NOT NULL; UNIQUE_INDEX:idx_policies_categories"`
+	Circulates     bool   `json:"circulates" gorm:"NOT NULL"`
+	LoanDays       uint   `json:"loan_days" gorm:"NOT NULL"`
+	RenewDays      uint   `json:"renew_days" gorm:"NOT NULL"`
+	MaxRenewals    uint   `json:"max_renewals" gorm:"NOT NULL"`
+	MaxLoans       uint   `json:"max_loans" gorm:"NOT NULL"`
+}
+
+// ValidatePatronCategory checks if the patron category is known
+func ValidatePatronCategory(category string) error {
+	for _, known := range PatronCategories {
+		if category == known {
+			return nil
+		}
+	}
+	return ErrInvalidPatronCategory
+}
+
+// ValidateItemCategory checks if the item category is known
+func ValidateItemCategory(category string) error {
+	for _, known := range ItemCategories {
+		if category == known {
+			return nil
+		}
+	}
+	return ErrInvalidItemCategory
+}
+
+// Validate checks if the policy has valid categories, and a loan period if
+// the copies circulate
+func (p *Policy) Validate() error {
+	if err := ValidatePatronCategory(p.PatronCategory); err != nil {
+		return err
+	}
+	if err := ValidateItemCategory(p.ItemCategory); err != nil {
+		return err
+	}
+	if p.Circulates && p.LoanDays == 0 {
+		return ErrLoanDaysRequired
+	}
+	return nil
+}
@@ -6,11 +6,19 @@ import (
 
 // Record is stored when a user borrows a book from the library,
 // and is soft deleted when the book is returned
+// IssuedBy, RenewedBy, ReceivedBy are the IDs of the admins who perform the
+// checkout, the last renewal and the check-in at the circulation desk, which
+// are 0 if the user does it by himself/herself
 type Record struct {
 	ID          uint       `json:"id"`
 	UserID      uint       `json:"user_id" gorm:"NOT NULL"`
 	BookID      uint       `json:"book_id" gorm:"NOT NULL"`
+	CopyID      uint       `json:"copy_id" gorm:"NOT NULL"`
+	BorrowDate  time.Time  `json:"borrow_date"`
 	ReturnDate  time.Time  `json:"return_date" gorm:"NOT NULL"`
 	ExtendTimes uint       `json:"extend_times" gorm:"NOT NULL"`
+	IssuedBy    uint       `json:"issued_by"`
+	RenewedBy   uint       `json:"renewed_by"`
+	ReceivedBy  uint       `json:"received_by"`
 	DeletedAt   *time.Time `json:"real_return_date"`
 }
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Permissions granted to roles
+const (
+	PermCatalogWrite    = "catalog.write"
+	PermUsersManage     = "users.manage"
+	PermCirculationDesk = "circulation.desk"
+	PermReportsRead     = "reports.read"
+)
+
+// Built-in roles, which can't be changed or removed
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Permissions are all permissions
+var Permissions = []string{PermCatalogWrite, PermUsersManage, PermCirculationDesk, PermReportsRead}
+
+// ErrInvalidPermission occurs when the permission is unknown
+var ErrInvalidPermission = errors.New("validate: invalid permission, expected catalog.write / users.manage / circulation.desk / reports.read")
+
+// ErrInvalidRoleName occurs when the role name is blank or malformed
+var ErrInvalidRoleName = errors.New("validate: invalid role name, expected lowercase letters, digits, - and _")
+
+// Role is a named set of permissions granted to users
+// Permissions are stored in Grants as a comma-separated list, which is split
+// into Permissions when found
+type Role struct {
+	ID          uint     `json:"-"`
+	Name        string   `json:"name" gorm:"NOT NULL; UNIQUE"`
+	Grants      string   `json:"-" gorm:"NOT NULL"`
+	Permissions []string `json:"permissions" gorm:"-"`
+}
+
+// ValidatePermission checks if the permission is known
+func ValidatePermission(permission string) error {
+	for _, known := range Permissions {
+		if permission == known {
+			return nil
+		}
+	}
+	return ErrInvalidPermission
+}
+
+// IsBuiltin checks if the role is built in
+func (r Role) IsBuiltin() bool {
+	return r.Name == RoleUser || r.Name == RoleAdmin
+}
+
+// Has checks if the role grants the permission
+func (r Role) Has(permission string) bool {
+	for _, granted := range r.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers checks if the role grants all permissions of the other role, i.e.
+// a user of the role is allowed to grant the other role
+func (r Role) Covers(other Role) bool {
+	for _, permission := range other.Permissions {
+		if !r.Has(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks if the role has a valid name and known permissions, and
+// sorts the permissions without duplicates
+func (r *Role) Validate() error {
+	if r.Name == "" {
+		return ErrInvalidRoleName
+	}
+	for _, ch := range r.Name {
+		if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			return ErrInvalidRoleName
+		}
+	}
+	permissions, err := normalizePermissions(r.Permissions)
+	if err != nil {
+		return err
+	}
+	r.Permissions = permissions
+	return nil
+}
+
+// BeforeSave joins the permissions before saving the role
+func (r *Role) BeforeSave() error {
+	r.Grants = strings.Join(r.Permissions, ",")
+	return nil
+}
+
+// AfterFind splits the permissions after finding the role
+func (r *Role) AfterFind() error {
+	r.Permissions = splitPermissions(r.Grants)
+	return nil
+}
+
+// normalizePermissions checks if the permissions are known, and sorts them
+// without duplicates
+func normalizePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, permission := range permissions {
+		if err := ValidatePermission(permission); err != nil {
+			return nil, err
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			normalized = append(normalized, permission)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// splitPermissions splits a comma-separated list of permissions
+func splitPermissions(grants string) []string {
+	if grants == "" {
+		return []string{}
+	}
+	return strings.Split(grants, ",")
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hakula139/REALMS/internal/app/search"
+	"github.com/jinzhu/gorm"
+)
+
+// BookFieldWeights are the fields of a book indexed for full-text search,
+// along with their boosts in ranking
+var BookFieldWeights = map[string]float64{
+	"title":     3,
+	"author":    2,
+	"subjects":  1.5,
+	"series":    1,
+	"publisher": 1,
+	"language":  1,
+	"year":      1,
+	"isbn":      1,
+}
+
+// indexBatchSize is the number of books loaded at a time when building the
+// search index
+const indexBatchSize = 1000
+
+// IndexSetup builds the full-text search index of all books in the database
+func IndexSetup(db *gorm.DB) (*search.Index, error) {
+	idx := search.NewIndex(BookFieldWeights)
+	for offset := 0; ; offset += indexBatchSize {
+		var books []Book
+		err := db.Preload("Subjects").Order("id").
+			Limit(indexBatchSize).Offset(offset).Find(&books).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			idx.Add(book.Document())
+		}
+		if len(books) < indexBatchSize {
+			return idx, nil
+		}
+	}
+}
+
+// Document converts the book to a document in the search index
+// Subjects should be preloaded. The ISBN is indexed in both ISBN-13 and
+// ISBN-10, so that either one can be searched for
+func (b *Book) Document() search.Document {
+	fields := map[string]string{
+		"title":     b.Title,
+		"author":    b.Author,
+		"subjects":  strings.Join(b.SubjectNames(), "; "),
+		"series":    b.Series,
+		"publisher": b.Publisher,
+		"language":  b.Language,
+		"isbn":      b.ISBN + " " + ISBN10(b.ISBN),
+	}
+	if b.Year != 0 {
+		fields["year"] = strconv.Itoa(int(b.Year))
+	}
+	return search.Document{ID: b.ID, Fields: fields}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// tokenBytes is the number of random bytes of a session token or an API token
+const tokenBytes = 32
+
+// Session is a login session of a user kept on the server, which is referred
+// to by a random token in the cookie of the client
+// Only the hash of the token is stored, so that the sessions can't be taken
+// over by those who read the database. A session expires when idle for too
+// long or too old, and is revoked by removing it
+type Session struct {
+	ID         uint      `json:"id"`
+	TokenHash  string    `json:"-" gorm:"NOT NULL; UNIQUE"`
+	UserID     uint      `json:"user_id" gorm:"NOT NULL; INDEX"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" gorm:"NOT NULL"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"NOT NULL"`
+}
+
+// NewSessionToken generates a random session token, along with its hash
+func NewSessionToken() (string, string, error) {
+	return newToken("")
+}
+
+// HashToken hashes the session token or API token to look it up in the
+// database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken generates a random token with the prefix, along with its hash
+func newToken(prefix string) (string, string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := prefix + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// Expired checks if the session has been idle for longer than idle, or has
+// been created for longer than absolute, where a zero timeout is unlimited
+func (s Session) Expired(now time.Time, idle, absolute time.Duration) bool {
+	if idle > 0 && now.Sub(s.LastSeenAt) > idle {
+		return true
+	}
+	return absolute > 0 && now.Sub(s.CreatedAt) > absolute
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// apiTokenPrefix is the prefix of API tokens, which tells them apart from
+// other secrets, e.g. in the logs
+const apiTokenPrefix = "realms_"
+
+// apiTokenShownLength is the length of the beginning of an API token kept to
+// tell the tokens apart
+const apiTokenShownLength = len(apiTokenPrefix) + 6
+
+// ErrInvalidTokenName occurs when the token name is blank
+var ErrInvalidTokenName = errors.New("validate: token name can't be blank")
+
+// APIToken is a personal access token of a user, which is sent in the header
+// Authorization: Bearer <token> instead of logging in, e.g. by scripts
+// Only the hash of the token is stored, and the token itself is shown once
+// when created. A token grants the user privilege, along with the permissions
+// in Scopes that the role of the user still grants when used. The token
+// expires at ExpiresAt, or never if nil
+type APIToken struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id" gorm:"NOT NULL; INDEX"`
+	Name       string     `json:"name" gorm:"NOT NULL"`
+	Prefix     string     `json:"prefix" gorm:"NOT NULL"`
+	TokenHash  string     `json:"-" gorm:"NOT NULL; UNIQUE"`
+	Grants     string     `json:"-" gorm:"NOT NULL"`
+	Scopes     []string   `json:"scopes" gorm:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewAPIToken generates a random API token, along with its hash
+func NewAPIToken() (string, string, error) {
+	return newToken(apiTokenPrefix)
+}
+
+// Validate checks if the token has a name and known scopes, and sorts the
+// scopes without duplicates
+func (t *APIToken) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return ErrInvalidTokenName
+	}
+	scopes, err := normalizePermissions(t.Scopes)
+	if err != nil {
+		return err
+	}
+	t.Scopes = scopes
+	return nil
+}
+
+// SetToken saves the hash and the beginning of the token
+func (t *APIToken) SetToken(token, hash string) {
+	t.TokenHash = hash
+	t.Prefix = token
+	if len(token) > apiTokenShownLength {
+		t.Prefix = token[:apiTokenShownLength]
+	}
+}
+
+// Expired checks if the token has expired
+func (t APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// Restrict keeps the permissions of the role within the scopes of the token
+func (t APIToken) Restrict(role Role) Role {
+	scoped := Role{ID: role.ID, Name: role.Name, Permissions: []string{}}
+	for _, permission := range role.Permissions {
+		for _, scope := range t.Scopes {
+			if permission == scope {
+				scoped.Permissions = append(scoped.Permissions, permission)
+			}
+		}
+	}
+	return scoped
+}
+
+// BeforeSave joins the scopes before saving the token
+func (t *APIToken) BeforeSave() error {
+	t.Grants = strings.Join(t.Scopes, ",")
+	return nil
+}
+
+// AfterFind splits the scopes after finding the token
+func (t *APIToken) AfterFind() error {
+	t.Scopes = splitPermissions(t.Grants)
+	return nil
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"errors"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -13,17 +15,59 @@ var ErrUsernameRequired = errors.New("validate: username required")
 // ErrPasswordRequired occurs when the password field is left blank
 var ErrPasswordRequired = errors.New("validate: password required")
 
+// ErrInvalidPhone occurs when the phone number is malformed
+var ErrInvalidPhone = errors.New("validate: invalid phone number")
+
+// States of user accounts
+const (
+	UserActive    = "active"
+	UserPending   = "pending"
+	UserSuspended = "suspended"
+	UserBanned    = "banned"
+)
+
+// ErrInvalidState occurs when the account state to set is unknown, where a
+// user can't be set pending approval by hand
+var ErrInvalidState = errors.New("validate: invalid state, expected active / suspended / banned")
+
+// ErrInvalidSuspension occurs when a user is suspended without an end in the
+// future
+var ErrInvalidSuspension = errors.New("validate: suspension must end in the future")
+
+// maxPhoneLength is the maximum length of a phone number
+const maxPhoneLength = 32
+
 // User is a person who has access to the library
-// Level indicates the user's privilege
-// 0: Banned
-// 1: User
-// 2: Admin
-// 3: Super Admin
+// Role is the name of the role granting the user's permissions, see Role
+// Category is the patron category, which decides the loan policies applied
+// MaxLoans overrides the maximum number of books borrowed at a time in the
+// library config, which is used if set to 0
+// Email is the address to send notices to, and Notify is the notices the user
+// wants to receive, see NotifyPreferences
+// State is pending if the user registered and is waiting for approval,
+// suspended until SuspendedUntil, banned, or active otherwise. StateReason
+// and StateSetBy are why and by which admin the state was set, where
+// StateSetBy is 0 if set by the system, e.g. on registration
+// MustChangePassword is set when an admin resets the password, after which
+// the user has to set a new password before doing anything else
 type User struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username" gorm:"NOT NULL; UNIQUE"`
-	Password string `json:"password" gorm:"NOT NULL"`
-	Level    uint   `json:"level" gorm:"NOT NULL"`
+	ID          uint   `json:"id"`
+	Username    string `json:"username" gorm:"NOT NULL; UNIQUE"`
+	Password    string `json:"-" gorm:"NOT NULL"`
+	Role        string `json:"role" gorm:"NOT NULL; DEFAULT:'user'"`
+	Category    string `json:"category" gorm:"NOT NULL; DEFAULT:'student'"`
+	MaxLoans    uint   `json:"max_loans" gorm:"NOT NULL; DEFAULT:0"`
+	Email       string `json:"email"`
+	Notify      string `json:"notify" gorm:"NOT NULL; DEFAULT:'all'"`
+	DisplayName string `json:"display_name"`
+	Phone       string `json:"phone"`
+	State       string `json:"state" gorm:"NOT NULL; DEFAULT:'active'"`
+
+	StateReason    string     `json:"state_reason"`
+	StateSetBy     uint       `json:"state_set_by" gorm:"NOT NULL; DEFAULT:0"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+
+	MustChangePassword bool `json:"must_change_password" gorm:"NOT NULL; DEFAULT:false"`
 }
 
 func hash(pass string) ([]byte, error) {
@@ -63,13 +107,110 @@ func VerifyPassword(hash, pass string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass))
 }
 
-// IsAdmin checks if the user has admin privilege
-func (u *User) IsAdmin() bool {
-	return u.Level >= 2
+// decoyHash is a hash compared against when the user is not found, which is
+// generated on first use
+var decoyHash struct {
+	once sync.Once
+	hash []byte
+}
+
+// VerifyDecoyPassword takes as long as VerifyPassword, and is called when the
+// user is not found, so that usernames can't be told apart by response time
+func VerifyDecoyPassword(pass string) {
+	decoyHash.once.Do(func() {
+		decoyHash.hash, _ = hash("decoy")
+	})
+	bcrypt.CompareHashAndPassword(decoyHash.hash, []byte(pass))
+}
+
+// RoleName returns the role of the user, which is user if left blank
+func (u *User) RoleName() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// PatronCategory returns the patron category of the user, which is student if
+// left blank
+func (u *User) PatronCategory() string {
+	if u.Category == "" {
+		return PatronCategories[0]
+	}
+	return u.Category
+}
+
+// NotifyPreference returns the notices the user wants to receive, which is
+// all if left blank
+func (u *User) NotifyPreference() string {
+	if u.Notify == "" {
+		return NotifyPreferences[0]
+	}
+	return u.Notify
+}
+
+// IsPending checks if the user is waiting for approval
+func (u *User) IsPending() bool {
+	return u.State == UserPending
+}
+
+// AccountState returns the state of the user at the time, where a suspension
+// ended is active again
+func (u *User) AccountState(now time.Time) string {
+	switch u.State {
+	case UserPending, UserBanned:
+		return u.State
+	case UserSuspended:
+		if u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil) {
+			return UserSuspended
+		}
+	}
+	return UserActive
+}
+
+// ValidateState checks if the state can be set by an admin, and if a
+// suspension ends in the future
+func ValidateState(state string, until *time.Time, now time.Time) error {
+	switch state {
+	case UserActive, UserBanned:
+		return nil
+	case UserSuspended:
+		if until == nil || !until.After(now) {
+			return ErrInvalidSuspension
+		}
+		return nil
+	}
+	return ErrInvalidState
+}
+
+// ValidatePhone checks if the phone number consists of digits, spaces and
+// the symbols + - ( ) only, where a blank one is allowed
+func ValidatePhone(phone string) error {
+	if len(phone) > maxPhoneLength {
+		return ErrInvalidPhone
+	}
+	digits := 0
+	for _, ch := range phone {
+		switch {
+		case ch >= '0' && ch <= '9':
+			digits++
+		case strings.ContainsRune(" +-()", ch):
+		default:
+			return ErrInvalidPhone
+		}
+	}
+	if phone != "" && digits == 0 {
+		return ErrInvalidPhone
+	}
+	return nil
 }
 
-// BeforeSave trims the username and encrypts the password before saving user data
-func (u *User) BeforeSave() error {
+// BeforeCreate trims the username and encrypts the password before adding the
+// user
+// The password is only encrypted on creation, so that saving the user later
+// never encrypts the hash again. A new password is encrypted using
+// EncryptPassword, and saved skipping the hooks
+func (u *User) BeforeCreate() error {
 	TrimUsername(&u.Username)
 	return EncryptPassword(&u.Password)
 }
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"go.uber.org/zap"
+)
+
+// Log appends notices to a file as JSON lines, or writes them to the server
+// log if no file is given, which is useful when there's no mail server
+type Log struct {
+	mu     sync.Mutex
+	path   string
+	logger *zap.SugaredLogger
+}
+
+// NewLog creates a notifier writing to the file, where the directory is
+// created if missing
+func NewLog(cfg config.LogSinkConfig, logger *zap.SugaredLogger) (*Log, error) {
+	if cfg.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &Log{path: cfg.Path, logger: logger}, nil
+}
+
+// Channel returns log
+func (n *Log) Channel() string {
+	return ChannelLog
+}
+
+// Notify writes the message
+func (n *Log) Notify(msg Message) error {
+	if n.path == "" {
+		n.logger.Infof("Notice to user %v: %v", msg.UserID, msg.Subject)
+		return nil
+	}
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"go.uber.org/zap"
+)
+
+// Channels of notices
+const (
+	ChannelSMTP    = "smtp"
+	ChannelWebhook = "webhook"
+	ChannelLog     = "log"
+)
+
+// ErrNoAddress occurs when the user has no address to receive notices through
+// the channel, e.g. no email address
+var ErrNoAddress = errors.New("notify: no address")
+
+// ErrInvalidSink occurs when the sink in the config is unknown
+var ErrInvalidSink = errors.New("notify: invalid sink, expected smtp / webhook / log")
+
+// Message is a notice of a loan sent to a user
+// Kind is either due_soon or overdue, and ReturnDate is in the time zone of
+// the library
+type Message struct {
+	Kind       string    `json:"kind"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email,omitempty"`
+	RecordID   uint      `json:"record_id"`
+	BookID     uint      `json:"book_id"`
+	Title      string    `json:"title"`
+	ReturnDate time.Time `json:"return_date"`
+	Subject    string    `json:"subject"`
+	Text       string    `json:"text"`
+}
+
+// Notifier sends notices through a channel
+type Notifier interface {
+	// Channel returns the name of the channel, which is recorded along with
+	// the notices sent
+	Channel() string
+	// Notify sends the message, which fails with ErrNoAddress if the user
+	// can't be reached through the channel
+	Notify(msg Message) error
+}
+
+// New creates the notifiers of the sinks in the config
+func New(cfg config.NotifyConfig, logger *zap.SugaredLogger) ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, sink := range cfg.Sinks {
+		switch sink {
+		case ChannelSMTP:
+			notifiers = append(notifiers, NewSMTP(cfg.SMTP))
+		case ChannelWebhook:
+			notifiers = append(notifiers, NewWebhook(cfg.Webhook))
+		case ChannelLog:
+			notifier, err := NewLog(cfg.Log, logger)
+			if err != nil {
+				return nil, err
+			}
+			notifiers = append(notifiers, notifier)
+		default:
+			return nil, ErrInvalidSink
+		}
+	}
+	return notifiers, nil
+}
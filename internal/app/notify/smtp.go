@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+)
+
+// smtpTimeout is the time limit of sending an email, from connecting to the
+// mail server until the end of the conversation
+const smtpTimeout = 30 * time.Second
+
+// ErrNoAuth occurs when a username is set, but the mail server doesn't
+// support authentication
+var ErrNoAuth = errors.New("notify: mail server doesn't support AUTH")
+
+// ErrInvalidAddress occurs when an email address contains a line break
+var ErrInvalidAddress = errors.New("notify: invalid email address")
+
+// SMTP sends notices by email
+// The connection is upgraded with STARTTLS if the server supports it, so that
+// a local fake SMTP server without TLS works for testing as well
+type SMTP struct {
+	cfg     config.SMTPConfig
+	timeout time.Duration
+}
+
+// NewSMTP creates a notifier using the mail server
+func NewSMTP(cfg config.SMTPConfig) *SMTP {
+	return &SMTP{cfg: cfg, timeout: smtpTimeout}
+}
+
+// Channel returns smtp
+func (n *SMTP) Channel() string {
+	return ChannelSMTP
+}
+
+// Notify sends the message to the email address of the user
+func (n *SMTP) Notify(msg Message) error {
+	if msg.Email == "" {
+		return ErrNoAddress
+	}
+	from, err := mail.ParseAddress(n.cfg.From)
+	if err != nil {
+		return fmt.Errorf("notify: invalid sender address: %v", err)
+	}
+
+	var body bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", (&mail.Address{Name: msg.Username, Address: msg.Email}).String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/plain; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "8bit"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&body, "%s: %s\r\n", h.key, h.value)
+	}
+	body.WriteString("\r\n")
+	body.Write(bytes.ReplaceAll([]byte(msg.Text), []byte("\n"), []byte("\r\n")))
+	body.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	return n.send(auth, from.Address, msg.Email, body.Bytes())
+}
+
+// send sends the email like smtp.SendMail, but gives up once the time limit
+// is exceeded, so that an unresponsive mail server can't hold up the reminder
+func (n *SMTP) send(auth smtp.Auth, from, to string, body []byte) error {
+	if strings.ContainsAny(from+to, "\r\n") {
+		return ErrInvalidAddress
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, n.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(n.timeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return ErrNoAuth
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+)
+
+// fakeSMTP is a mail server on a local port, which receives a single email
+type fakeSMTP struct {
+	listener net.Listener
+	// commands are the commands received in order, excluding the data
+	commands chan []string
+	// data is the email received
+	data chan string
+}
+
+// newFakeSMTP starts a mail server, which greets the clients unless silent
+func newFakeSMTP(t *testing.T, silent bool) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &fakeSMTP{listener: listener, commands: make(chan []string, 1), data: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if silent {
+			// Holds the connection until the client gives up
+			conn.Read(make([]byte, 1))
+			return
+		}
+		s.serve(conn)
+	}()
+	return s
+}
+
+// serve speaks just enough SMTP to receive an email
+func (s *fakeSMTP) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	var commands []string
+	var data strings.Builder
+	defer func() {
+		s.commands <- commands
+		s.data <- data.String()
+	}()
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// config returns the config of a notifier using the server
+func (s *fakeSMTP) config() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return config.SMTPConfig{Host: host, Port: p, From: "REALMS <realms@example.com>"}
+}
+
+var testMessage = Message{
+	Kind:     "overdue",
+	UserID:   11,
+	Username: "alice",
+	Email:    "alice@example.com",
+	Subject:  "Overdue: «CS:APP»",
+	Text:     "Dear alice,\n\nPlease return it.\n",
+}
+
+func TestSMTPNotify(t *testing.T) {
+	server := newFakeSMTP(t, false)
+	n := NewSMTP(server.config())
+	if err := n.Notify(testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := <-server.commands
+	want := []string{"MAIL FROM:<realms@example.com>", "RCPT TO:<alice@example.com>", "DATA", "QUIT"}
+	if len(commands) != len(want)+1 || !strings.HasPrefix(commands[0], "EHLO ") {
+		t.Fatalf("commands = %q, want EHLO followed by %q", commands, want)
+	}
+	for i, command := range want {
+		if !strings.HasPrefix(commands[i+1], command) {
+			t.Errorf("command %v = %q, want %q", i+1, commands[i+1], command)
+		}
+	}
+
+	data := <-server.data
+	for _, line := range []string{
+		"From: \"REALMS\" <realms@example.com>\r\n",
+		"To: \"alice\" <alice@example.com>\r\n",
+		"Subject: =?utf-8?q?Overdue:_=C2=ABCS:APP=C2=BB?=\r\n",
+		"\r\n\r\nDear alice,\r\n\r\nPlease return it.\r\n",
+	} {
+		if !strings.Contains(data, line) {
+			t.Errorf("email doesn't contain %q:\n%v", line, data)
+		}
+	}
+}
+
+func TestSMTPNotifyTimeout(t *testing.T) {
+	server := newFakeSMTP(t, true)
+	n := NewSMTP(server.config())
+	n.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := n.Notify(testMessage)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Notify = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify took %v, want it to give up after %v", elapsed, n.timeout)
+	}
+}
+
+func TestSMTPNotifyNoAuth(t *testing.T) {
+	server := newFakeSMTP(t, false)
+	cfg := server.config()
+	cfg.Username, cfg.Password = "realms", "secret"
+	if err := NewSMTP(cfg).Notify(testMessage); err != ErrNoAuth {
+		t.Errorf("Notify = %v, want %v", err, ErrNoAuth)
+	}
+}
+
+func TestSMTPNotifyInvalid(t *testing.T) {
+	// None of these reaches the server, which is never started
+	cfg := config.SMTPConfig{Host: "127.0.0.1", Port: 1, From: "realms@example.com"}
+	msg := testMessage
+	msg.Email = ""
+	if err := NewSMTP(cfg).Notify(msg); err != ErrNoAddress {
+		t.Errorf("Notify without an address = %v, want %v", err, ErrNoAddress)
+	}
+	msg.Email = "alice@example.com\r\nRCPT TO:<bob@example.com>"
+	if err := NewSMTP(cfg).Notify(msg); err != ErrInvalidAddress {
+		t.Errorf("Notify with a line break = %v, want %v", err, ErrInvalidAddress)
+	}
+	cfg.From = "not an address"
+	if err := NewSMTP(cfg).Notify(testMessage); err == nil {
+		t.Error("Notify with an invalid sender = nil, want an error")
+	}
+}
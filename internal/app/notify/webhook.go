@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+)
+
+// webhookTimeout is the time limit of a webhook request
+const webhookTimeout = 10 * time.Second
+
+// SignatureHeader is the header of the HMAC-SHA256 signature of the body in
+// hex, which is sent if a secret is set
+const SignatureHeader = "X-Realms-Signature"
+
+// Webhook posts notices to a URL as JSON
+type Webhook struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook creates a notifier posting to the URL
+func NewWebhook(cfg config.WebhookConfig) *Webhook {
+	return &Webhook{cfg: cfg, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Channel returns webhook
+func (n *Webhook) Channel() string {
+	return ChannelWebhook
+}
+
+// Notify posts the message, which fails unless the response status is 2xx
+func (n *Webhook) Notify(msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.cfg.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("notify: webhook responded %v", res.Status)
+	}
+	return nil
+}
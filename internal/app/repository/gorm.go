@@ -0,0 +1,435 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Gorm is a repository backed by the database
+type Gorm struct {
+	db   *gorm.DB
+	inTx bool
+}
+
+var _ Repository = (*Gorm)(nil)
+
+// NewGorm creates a repository using the database connection, which can be a
+// transaction as well
+func NewGorm(db *gorm.DB) *Gorm {
+	return &Gorm{db: db}
+}
+
+// NewGormTx creates a repository bound to a transaction begun by the caller,
+// where Transaction runs fn in the transaction as well, since gorm can't nest
+// transactions
+func NewGormTx(tx *gorm.DB) *Gorm {
+	return &Gorm{db: tx, inTx: true}
+}
+
+// Transaction runs fn with a repository bound to a new transaction, which is
+// committed if fn returns nil, and rolled back otherwise
+// Deadlocks and lock timeouts are reported as ErrConflict
+func (r *Gorm) Transaction(fn func(repo Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Gorm{db: tx, inTx: true})
+	})
+	if isConflict(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// forUpdate locks the rows selected until the end of the transaction
+// SQLite has no row locks, where the whole database is locked by a write
+// transaction instead
+func (r *Gorm) forUpdate() *gorm.DB {
+	if !r.inTx || r.db.Dialect().GetName() == "sqlite3" {
+		return r.db
+	}
+	return r.db.Set("gorm:query_option", "FOR UPDATE")
+}
+
+// isConflict checks if the error is caused by lock contention
+func isConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// Deadlock found / lock wait timeout exceeded
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// serialization_failure / deadlock_detected / lock_not_available
+		return pqErr.Code == "40001" || pqErr.Code == "40P01" || pqErr.Code == "55P03"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// updated checks if exactly the rows read are updated
+func updated(chain *gorm.DB) error {
+	if chain.Error != nil {
+		return chain.Error
+	}
+	if chain.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// first finds the first entity matching the query, and converts the error if
+// not found
+func first(chain *gorm.DB, out interface{}) error {
+	err := chain.First(out).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// FindBook finds the book of given ID
+func (r *Gorm) FindBook(id uint) (models.Book, error) {
+	var book models.Book
+	err := first(r.db.Where("id = ?", id), &book)
+	return book, err
+}
+
+// CreateBook adds a new book, and sets its ID
+func (r *Gorm) CreateBook(book *models.Book) error {
+	return r.db.Create(book).Error
+}
+
+// FindCopy finds the copy of given ID in circulation
+func (r *Gorm) FindCopy(id uint) (models.Copy, error) {
+	var item models.Copy
+	err := first(r.forUpdate().Where("id = ?", id), &item)
+	return item, err
+}
+
+// FindCopyByBarcode finds the copy of given barcode in circulation
+func (r *Gorm) FindCopyByBarcode(barcode string) (models.Copy, error) {
+	var item models.Copy
+	err := first(r.db.Where("barcode = ?", barcode), &item)
+	return item, err
+}
+
+// FindAvailableCopy finds the available copy of the book with the lowest ID,
+// which has the given barcode if not blank, and is of one of the item
+// categories if not nil
+func (r *Gorm) FindAvailableCopy(bookID uint, barcode string, categories []string) (models.Copy, error) {
+	var item models.Copy
+	chain := r.forUpdate().Where("book_id = ? AND status = ?", bookID, models.CopyAvailable)
+	if barcode != "" {
+		chain = chain.Where("barcode = ?", barcode)
+	}
+	if categories != nil {
+		chain = chain.Where("category IN (?)", categories)
+	}
+	err := first(chain.Order("id"), &item)
+	return item, err
+}
+
+// CreateCopy adds a new copy, and sets its ID
+func (r *Gorm) CreateCopy(item *models.Copy) error {
+	return r.db.Create(item).Error
+}
+
+// SetCopyStatus changes the status of a copy, which fails with ErrConflict if
+// the status has been changed since read
+func (r *Gorm) SetCopyStatus(item *models.Copy, status string) error {
+	if item.Status == status {
+		return nil
+	}
+	err := updated(r.db.Model(&models.Copy{}).
+		Where("id = ? AND status = ?", item.ID, item.Status).
+		UpdateColumn("status", status))
+	if err == nil {
+		item.Status = status
+	}
+	return err
+}
+
+// FindUser finds the user of given ID
+func (r *Gorm) FindUser(id uint) (models.User, error) {
+	var user models.User
+	err := first(r.db.Where("id = ?", id), &user)
+	return user, err
+}
+
+// LockUser finds the user of given ID, and locks it until the end of the
+// transaction
+func (r *Gorm) LockUser(id uint) (models.User, error) {
+	var user models.User
+	err := first(r.forUpdate().Where("id = ?", id), &user)
+	return user, err
+}
+
+// CreateUser adds a new user, and sets its ID
+func (r *Gorm) CreateUser(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+// FindLoan finds the loan of the book by the user
+func (r *Gorm) FindLoan(userID, bookID uint) (models.Record, error) {
+	var record models.Record
+	err := first(r.db.Where("user_id = ? AND book_id = ?", userID, bookID), &record)
+	return record, err
+}
+
+// FindLoanByCopy finds the loan of the copy
+func (r *Gorm) FindLoanByCopy(copyID uint) (models.Record, error) {
+	var record models.Record
+	err := first(r.db.Where("copy_id = ?", copyID), &record)
+	return record, err
+}
+
+// CountOverdue counts the loans of the user past the return date
+func (r *Gorm) CountOverdue(userID uint, now time.Time) (uint, error) {
+	var count uint
+	err := r.db.Model(&models.Record{}).
+		Where("user_id = ? AND return_date < ?", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+// CountLoans counts the loans of the user, of copies in the item category if
+// not blank
+func (r *Gorm) CountLoans(userID uint, category string) (uint, error) {
+	var count uint
+	chain := r.db.Model(&models.Record{}).Where("records.user_id = ?", userID)
+	if category != "" {
+		chain = chain.Joins("JOIN copies ON copies.id = records.copy_id").
+			Where("copies.category = ?", category)
+	}
+	err := chain.Count(&count).Error
+	return count, err
+}
+
+// LoansDueBefore finds all loans whose return date is before the time
+func (r *Gorm) LoansDueBefore(t time.Time) ([]models.Record, error) {
+	var records []models.Record
+	err := r.db.Where("return_date < ?", t).Order("return_date, id").Find(&records).Error
+	return records, err
+}
+
+// CreateRecord adds a new loan, and sets its ID
+func (r *Gorm) CreateRecord(record *models.Record) error {
+	return r.db.Create(record).Error
+}
+
+// RenewRecord saves the return date, extend times and renewer of a loan,
+// which fails with ErrConflict if the loan has been renewed or returned since
+// read
+func (r *Gorm) RenewRecord(record *models.Record, prevExtendTimes uint) error {
+	return updated(r.db.Model(&models.Record{}).
+		Where("id = ? AND extend_times = ?", record.ID, prevExtendTimes).
+		UpdateColumns(map[string]interface{}{
+			"return_date":  record.ReturnDate,
+			"extend_times": record.ExtendTimes,
+			"renewed_by":   record.RenewedBy,
+		}))
+}
+
+// CloseRecord saves the receiver of a loan, and marks it as returned, which
+// fails with ErrConflict if it has been returned since read
+func (r *Gorm) CloseRecord(record *models.Record, now time.Time) error {
+	err := updated(r.db.Model(&models.Record{}).
+		Where("id = ?", record.ID).
+		UpdateColumns(map[string]interface{}{
+			"received_by": record.ReceivedBy,
+			"deleted_at":  now,
+		}))
+	if err == nil {
+		record.DeletedAt = &now
+	}
+	return err
+}
+
+// FindReadyHold finds the hold of the user on the book ready for pickup
+func (r *Gorm) FindReadyHold(userID, bookID uint) (models.Hold, error) {
+	var hold models.Hold
+	err := first(r.forUpdate().Where("user_id = ? AND book_id = ? AND status = ?",
+		userID, bookID, models.HoldReady), &hold)
+	return hold, err
+}
+
+// NextWaitingHold finds the first hold waiting in line for the book
+func (r *Gorm) NextWaitingHold(bookID uint) (models.Hold, error) {
+	var hold models.Hold
+	err := first(r.forUpdate().Order("id").Where("book_id = ? AND status = ?",
+		bookID, models.HoldWaiting), &hold)
+	return hold, err
+}
+
+// ExpiredHolds finds the holds ready for pickup past the pickup deadline
+func (r *Gorm) ExpiredHolds(now time.Time) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.forUpdate().Order("id").
+		Where("status = ? AND pickup_deadline < ?", models.HoldReady, now).
+		Find(&holds).Error
+	return holds, err
+}
+
+// CreateHold adds a new hold, and sets its ID
+func (r *Gorm) CreateHold(hold *models.Hold) error {
+	return r.db.Create(hold).Error
+}
+
+// UpdateHold saves the status, the copy kept and the pickup deadline of a hold
+func (r *Gorm) UpdateHold(hold *models.Hold) error {
+	return r.db.Model(hold).Updates(map[string]interface{}{
+		"status":          hold.Status,
+		"copy_id":         hold.CopyID,
+		"pickup_deadline": hold.PickupDeadline,
+	}).Error
+}
+
+// FulfillHolds marks the active holds of the user on the book as fulfilled
+func (r *Gorm) FulfillHolds(userID, bookID uint) error {
+	return r.db.Model(&models.Hold{}).
+		Where("user_id = ? AND book_id = ? AND status IN (?)",
+			userID, bookID, []string{models.HoldWaiting, models.HoldReady}).
+		Update("status", models.HoldFulfilled).Error
+}
+
+// UnpaidFines returns the total amount of unpaid fines of the user
+func (r *Gorm) UnpaidFines(userID uint) (float64, error) {
+	var total float64
+	row := r.db.Model(&models.Fine{}).
+		Where("user_id = ? AND status = ?", userID, models.FineUnpaid).
+		Select("COALESCE(SUM(amount), 0)").Row()
+	err := row.Scan(&total)
+	return total, err
+}
+
+// CreateFine adds a new fine, and sets its ID
+func (r *Gorm) CreateFine(fine *models.Fine) error {
+	return r.db.Create(fine).Error
+}
+
+// FindPolicy finds the policy of the item category for the patron category
+func (r *Gorm) FindPolicy(patronCategory, itemCategory string) (models.Policy, error) {
+	var policy models.Policy
+	err := first(r.db.Where("patron_category = ? AND item_category = ?",
+		patronCategory, itemCategory), &policy)
+	return policy, err
+}
+
+// CreatePolicy adds a new policy, and sets its ID
+func (r *Gorm) CreatePolicy(policy *models.Policy) error {
+	return r.db.Create(policy).Error
+}
+
+// OpeningHours finds the opening hours of all weekdays set
+func (r *Gorm) OpeningHours() ([]models.OpeningHours, error) {
+	var hours []models.OpeningHours
+	err := r.db.Order("weekday").Find(&hours).Error
+	return hours, err
+}
+
+// SetOpeningHours replaces the opening hours of the weekday
+func (r *Gorm) SetOpeningHours(hours *models.OpeningHours) error {
+	var prev models.OpeningHours
+	err := first(r.db.Where("weekday = ?", hours.Weekday), &prev)
+	if err == ErrNotFound {
+		return r.db.Create(hours).Error
+	}
+	if err != nil {
+		return err
+	}
+	hours.ID = prev.ID
+	return r.db.Model(hours).Updates(map[string]interface{}{
+		"opens":  hours.Opens,
+		"closes": hours.Closes,
+		"closed": hours.Closed,
+	}).Error
+}
+
+// ClosedDays finds the closed days from one date to another inclusive
+func (r *Gorm) ClosedDays(from, to string) ([]models.ClosedDay, error) {
+	var days []models.ClosedDay
+	err := r.db.Where("date BETWEEN ? AND ?", from, to).Order("date").Find(&days).Error
+	return days, err
+}
+
+// CreateClosedDay adds a new closed day, and sets its ID
+func (r *Gorm) CreateClosedDay(day *models.ClosedDay) error {
+	return r.db.Create(day).Error
+}
+
+// NoticeSent checks if the notice of the kind has been sent for the loan
+// through the channel since it was last renewed
+func (r *Gorm) NoticeSent(record models.Record, kind, channel string) (bool, error) {
+	var count uint
+	err := r.db.Model(&models.Notice{}).
+		Where("record_id = ? AND kind = ? AND channel = ? AND extend_times = ?",
+			record.ID, kind, channel, record.ExtendTimes).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateNotice adds a new notice, and sets its ID
+func (r *Gorm) CreateNotice(notice *models.Notice) error {
+	return r.db.Create(notice).Error
+}
+
+// FindSession finds the session of given token hash
+func (r *Gorm) FindSession(tokenHash string) (models.Session, error) {
+	var session models.Session
+	err := first(r.db.Where("token_hash = ?", tokenHash), &session)
+	return session, err
+}
+
+// CreateSession adds a new session, and sets its ID
+func (r *Gorm) CreateSession(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// TouchSession saves the time the session was last seen
+func (r *Gorm) TouchSession(id uint, t time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).UpdateColumn("last_seen_at", t).Error
+}
+
+// DeleteSession removes the session of given ID
+func (r *Gorm) DeleteSession(id uint) error {
+	return r.db.Where("id = ?", id).Delete(&models.Session{}).Error
+}
+
+// DeleteUserSessions removes all sessions of the user, and returns the number
+// of sessions removed
+func (r *Gorm) DeleteUserSessions(userID uint) (uint, error) {
+	chain := r.db.Where("user_id = ?", userID).Delete(&models.Session{})
+	return uint(chain.RowsAffected), chain.Error
+}
+
+// DeleteOtherSessions removes the sessions of the user except the one of given
+// ID, and returns the number of sessions removed
+func (r *Gorm) DeleteOtherSessions(userID, keepID uint) (uint, error) {
+	chain := r.db.Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{})
+	return uint(chain.RowsAffected), chain.Error
+}
+
+// DeleteStaleSessions removes the sessions last seen before idleBefore, or
+// created before createdBefore, where a zero time is ignored
+func (r *Gorm) DeleteStaleSessions(idleBefore, createdBefore time.Time) error {
+	if !idleBefore.IsZero() {
+		if err := r.db.Where("last_seen_at < ?", idleBefore).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+	}
+	if !createdBefore.IsZero() {
+		return r.db.Where("created_at < ?", createdBefore).Delete(&models.Session{}).Error
+	}
+	return nil
+}
@@ -0,0 +1,668 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/models"
+)
+
+// Memory is a repository kept in memory, which is mainly used for testing
+// Entities are copied in and out, and IDs are generated in ascending order if
+// not specified. Transactions are serialized, and rolled back by restoring a
+// snapshot of all entities
+type Memory struct {
+	txMu       sync.Mutex
+	mu         sync.Mutex
+	lastIDs    map[string]uint
+	books      map[uint]models.Book
+	copies     map[uint]models.Copy
+	users      map[uint]models.User
+	records    map[uint]models.Record
+	holds      map[uint]models.Hold
+	fines      map[uint]models.Fine
+	policies   map[uint]models.Policy
+	hours      map[uint]models.OpeningHours
+	closedDays map[uint]models.ClosedDay
+	notices    map[uint]models.Notice
+	sessions   map[uint]models.Session
+}
+
+var _ Repository = (*Memory)(nil)
+
+// NewMemory creates an empty repository in memory
+func NewMemory() *Memory {
+	return &Memory{
+		lastIDs:    make(map[string]uint),
+		books:      make(map[uint]models.Book),
+		copies:     make(map[uint]models.Copy),
+		users:      make(map[uint]models.User),
+		records:    make(map[uint]models.Record),
+		holds:      make(map[uint]models.Hold),
+		fines:      make(map[uint]models.Fine),
+		policies:   make(map[uint]models.Policy),
+		hours:      make(map[uint]models.OpeningHours),
+		closedDays: make(map[uint]models.ClosedDay),
+		notices:    make(map[uint]models.Notice),
+		sessions:   make(map[uint]models.Session),
+	}
+}
+
+// Transaction runs fn with the repository in a transaction, which is rolled
+// back if fn returns an error
+func (r *Memory) Transaction(fn func(repo Repository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	r.mu.Lock()
+	snapshot := r.clone()
+	r.mu.Unlock()
+
+	if err := fn(r); err != nil {
+		r.mu.Lock()
+		r.lastIDs, r.books, r.copies = snapshot.lastIDs, snapshot.books, snapshot.copies
+		r.users, r.records = snapshot.users, snapshot.records
+		r.holds, r.fines, r.policies = snapshot.holds, snapshot.fines, snapshot.policies
+		r.hours, r.closedDays, r.notices = snapshot.hours, snapshot.closedDays, snapshot.notices
+		r.sessions = snapshot.sessions
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// clone copies all entities
+func (r *Memory) clone() *Memory {
+	m := NewMemory()
+	for k, v := range r.lastIDs {
+		m.lastIDs[k] = v
+	}
+	for k, v := range r.books {
+		m.books[k] = v
+	}
+	for k, v := range r.copies {
+		m.copies[k] = v
+	}
+	for k, v := range r.users {
+		m.users[k] = v
+	}
+	for k, v := range r.records {
+		m.records[k] = v
+	}
+	for k, v := range r.holds {
+		m.holds[k] = v
+	}
+	for k, v := range r.fines {
+		m.fines[k] = v
+	}
+	for k, v := range r.policies {
+		m.policies[k] = v
+	}
+	for k, v := range r.hours {
+		m.hours[k] = v
+	}
+	for k, v := range r.closedDays {
+		m.closedDays[k] = v
+	}
+	for k, v := range r.notices {
+		m.notices[k] = v
+	}
+	for k, v := range r.sessions {
+		m.sessions[k] = v
+	}
+	return m
+}
+
+// nextID returns a new ID of the table if id is 0
+func (r *Memory) nextID(table string, id uint) uint {
+	if id == 0 {
+		id = r.lastIDs[table] + 1
+	}
+	if id > r.lastIDs[table] {
+		r.lastIDs[table] = id
+	}
+	return id
+}
+
+// sortIDs sorts the IDs in ascending order
+func sortIDs(ids []uint) []uint {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// contains checks if the string is in the list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FindBook finds the book of given ID
+func (r *Memory) FindBook(id uint) (models.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	book, ok := r.books[id]
+	if !ok {
+		return book, ErrNotFound
+	}
+	return book, nil
+}
+
+// CreateBook adds a new book, and sets its ID
+func (r *Memory) CreateBook(book *models.Book) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	book.ID = r.nextID("books", book.ID)
+	r.books[book.ID] = *book
+	return nil
+}
+
+// FindCopy finds the copy of given ID in circulation
+func (r *Memory) FindCopy(id uint) (models.Copy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.copies[id]
+	if !ok || item.DeletedAt != nil {
+		return models.Copy{}, ErrNotFound
+	}
+	return item, nil
+}
+
+// FindCopyByBarcode finds the copy of given barcode in circulation
+func (r *Memory) FindCopyByBarcode(barcode string) (models.Copy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, item := range r.copies {
+		if item.Barcode == barcode && item.DeletedAt == nil {
+			return item, nil
+		}
+	}
+	return models.Copy{}, ErrNotFound
+}
+
+// FindAvailableCopy finds the available copy of the book with the lowest ID,
+// which has the given barcode if not blank, and is of one of the item
+// categories if not nil
+func (r *Memory) FindAvailableCopy(bookID uint, barcode string, categories []string) (models.Copy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint
+	for id := range r.copies {
+		ids = append(ids, id)
+	}
+	for _, id := range sortIDs(ids) {
+		item := r.copies[id]
+		if item.BookID == bookID && item.Status == models.CopyAvailable && item.DeletedAt == nil &&
+			(barcode == "" || item.Barcode == barcode) &&
+			(categories == nil || contains(categories, item.ItemCategory())) {
+			return item, nil
+		}
+	}
+	return models.Copy{}, ErrNotFound
+}
+
+// CreateCopy adds a new copy, and sets its ID
+func (r *Memory) CreateCopy(item *models.Copy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item.ID = r.nextID("copies", item.ID)
+	r.copies[item.ID] = *item
+	return nil
+}
+
+// SetCopyStatus changes the status of a copy, which fails with ErrConflict if
+// the status has been changed since read
+func (r *Memory) SetCopyStatus(item *models.Copy, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.copies[item.ID]
+	if !ok || stored.DeletedAt != nil || stored.Status != item.Status {
+		return ErrConflict
+	}
+	stored.Status = status
+	r.copies[item.ID] = stored
+	item.Status = status
+	return nil
+}
+
+// FindUser finds the user of given ID
+func (r *Memory) FindUser(id uint) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return user, ErrNotFound
+	}
+	return user, nil
+}
+
+// LockUser finds the user of given ID, where nothing is locked since
+// transactions are serialized
+func (r *Memory) LockUser(id uint) (models.User, error) {
+	return r.FindUser(id)
+}
+
+// CreateUser adds a new user, and sets its ID
+func (r *Memory) CreateUser(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = r.nextID("users", user.ID)
+	r.users[user.ID] = *user
+	return nil
+}
+
+// findLoan finds the first loan matching the condition
+func (r *Memory) findLoan(match func(models.Record) bool) (models.Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint
+	for id := range r.records {
+		ids = append(ids, id)
+	}
+	for _, id := range sortIDs(ids) {
+		if record := r.records[id]; record.DeletedAt == nil && match(record) {
+			return record, nil
+		}
+	}
+	return models.Record{}, ErrNotFound
+}
+
+// FindLoan finds the loan of the book by the user
+func (r *Memory) FindLoan(userID, bookID uint) (models.Record, error) {
+	return r.findLoan(func(record models.Record) bool {
+		return record.UserID == userID && record.BookID == bookID
+	})
+}
+
+// FindLoanByCopy finds the loan of the copy
+func (r *Memory) FindLoanByCopy(copyID uint) (models.Record, error) {
+	return r.findLoan(func(record models.Record) bool {
+		return record.CopyID == copyID
+	})
+}
+
+// CountOverdue counts the loans of the user past the return date
+func (r *Memory) CountOverdue(userID uint, now time.Time) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count uint
+	for _, record := range r.records {
+		if record.UserID == userID && record.DeletedAt == nil && record.ReturnDate.Before(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountLoans counts the loans of the user, of copies in the item category if
+// not blank
+func (r *Memory) CountLoans(userID uint, category string) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count uint
+	for _, record := range r.records {
+		if record.UserID != userID || record.DeletedAt != nil {
+			continue
+		}
+		if item := r.copies[record.CopyID]; category == "" || item.ItemCategory() == category {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LoansDueBefore finds all loans whose return date is before the time
+func (r *Memory) LoansDueBefore(t time.Time) ([]models.Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []models.Record
+	for _, record := range r.records {
+		if record.DeletedAt == nil && record.ReturnDate.Before(t) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].ReturnDate.Equal(records[j].ReturnDate) {
+			return records[i].ReturnDate.Before(records[j].ReturnDate)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+// CreateRecord adds a new loan, and sets its ID
+func (r *Memory) CreateRecord(record *models.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record.ID = r.nextID("records", record.ID)
+	r.records[record.ID] = *record
+	return nil
+}
+
+// RenewRecord saves the return date, extend times and renewer of a loan,
+// which fails with ErrConflict if the loan has been renewed or returned since
+// read
+func (r *Memory) RenewRecord(record *models.Record, prevExtendTimes uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.records[record.ID]
+	if !ok || stored.DeletedAt != nil || stored.ExtendTimes != prevExtendTimes {
+		return ErrConflict
+	}
+	stored.ReturnDate = record.ReturnDate
+	stored.ExtendTimes = record.ExtendTimes
+	stored.RenewedBy = record.RenewedBy
+	r.records[record.ID] = stored
+	return nil
+}
+
+// CloseRecord saves the receiver of a loan, and marks it as returned, which
+// fails with ErrConflict if it has been returned since read
+func (r *Memory) CloseRecord(record *models.Record, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.records[record.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrConflict
+	}
+	record.DeletedAt = &now
+	stored.ReceivedBy = record.ReceivedBy
+	stored.DeletedAt = record.DeletedAt
+	r.records[record.ID] = stored
+	return nil
+}
+
+// findHolds finds the holds matching the condition in ascending order of ID
+func (r *Memory) findHolds(match func(models.Hold) bool) []models.Hold {
+	var ids []uint
+	for id := range r.holds {
+		ids = append(ids, id)
+	}
+	var holds []models.Hold
+	for _, id := range sortIDs(ids) {
+		if hold := r.holds[id]; match(hold) {
+			holds = append(holds, hold)
+		}
+	}
+	return holds
+}
+
+// FindReadyHold finds the hold of the user on the book ready for pickup
+func (r *Memory) FindReadyHold(userID, bookID uint) (models.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	holds := r.findHolds(func(hold models.Hold) bool {
+		return hold.UserID == userID && hold.BookID == bookID && hold.Status == models.HoldReady
+	})
+	if len(holds) == 0 {
+		return models.Hold{}, ErrNotFound
+	}
+	return holds[0], nil
+}
+
+// NextWaitingHold finds the first hold waiting in line for the book
+func (r *Memory) NextWaitingHold(bookID uint) (models.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	holds := r.findHolds(func(hold models.Hold) bool {
+		return hold.BookID == bookID && hold.Status == models.HoldWaiting
+	})
+	if len(holds) == 0 {
+		return models.Hold{}, ErrNotFound
+	}
+	return holds[0], nil
+}
+
+// ExpiredHolds finds the holds ready for pickup past the pickup deadline
+func (r *Memory) ExpiredHolds(now time.Time) ([]models.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findHolds(func(hold models.Hold) bool {
+		return hold.Status == models.HoldReady && hold.PickupDeadline != nil &&
+			hold.PickupDeadline.Before(now)
+	}), nil
+}
+
+// CreateHold adds a new hold, and sets its ID
+func (r *Memory) CreateHold(hold *models.Hold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hold.ID = r.nextID("holds", hold.ID)
+	if hold.CreatedAt.IsZero() {
+		hold.CreatedAt = time.Now()
+	}
+	r.holds[hold.ID] = *hold
+	return nil
+}
+
+// UpdateHold saves the status, the copy kept and the pickup deadline of a hold
+func (r *Memory) UpdateHold(hold *models.Hold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.holds[hold.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status = hold.Status
+	stored.CopyID = hold.CopyID
+	stored.PickupDeadline = hold.PickupDeadline
+	r.holds[hold.ID] = stored
+	return nil
+}
+
+// FulfillHolds marks the active holds of the user on the book as fulfilled
+func (r *Memory) FulfillHolds(userID, bookID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, hold := range r.holds {
+		if hold.UserID == userID && hold.BookID == bookID && hold.IsActive() {
+			hold.Status = models.HoldFulfilled
+			r.holds[id] = hold
+		}
+	}
+	return nil
+}
+
+// UnpaidFines returns the total amount of unpaid fines of the user
+func (r *Memory) UnpaidFines(userID uint) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total float64
+	for _, fine := range r.fines {
+		if fine.UserID == userID && fine.Status == models.FineUnpaid {
+			total += fine.Amount
+		}
+	}
+	return total, nil
+}
+
+// CreateFine adds a new fine, and sets its ID
+func (r *Memory) CreateFine(fine *models.Fine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fine.ID = r.nextID("fines", fine.ID)
+	if fine.CreatedAt.IsZero() {
+		fine.CreatedAt = time.Now()
+	}
+	r.fines[fine.ID] = *fine
+	return nil
+}
+
+// FindPolicy finds the policy of the item category for the patron category
+func (r *Memory) FindPolicy(patronCategory, itemCategory string) (models.Policy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, policy := range r.policies {
+		if policy.PatronCategory == patronCategory && policy.ItemCategory == itemCategory {
+			return policy, nil
+		}
+	}
+	return models.Policy{}, ErrNotFound
+}
+
+// CreatePolicy adds a new policy, and sets its ID
+func (r *Memory) CreatePolicy(policy *models.Policy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	policy.ID = r.nextID("policies", policy.ID)
+	r.policies[policy.ID] = *policy
+	return nil
+}
+
+// OpeningHours finds the opening hours of all weekdays set
+func (r *Memory) OpeningHours() ([]models.OpeningHours, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var hours []models.OpeningHours
+	for weekday := uint(0); weekday < 7; weekday++ {
+		if h, ok := r.hours[weekday]; ok {
+			hours = append(hours, h)
+		}
+	}
+	return hours, nil
+}
+
+// SetOpeningHours replaces the opening hours of the weekday
+func (r *Memory) SetOpeningHours(hours *models.OpeningHours) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev, ok := r.hours[hours.Weekday]; ok {
+		hours.ID = prev.ID
+	}
+	hours.ID = r.nextID("opening_hours", hours.ID)
+	r.hours[hours.Weekday] = *hours
+	return nil
+}
+
+// ClosedDays finds the closed days from one date to another inclusive
+func (r *Memory) ClosedDays(from, to string) ([]models.ClosedDay, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var days []models.ClosedDay
+	for _, day := range r.closedDays {
+		if day.Date >= from && day.Date <= to {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days, nil
+}
+
+// CreateClosedDay adds a new closed day, and sets its ID
+func (r *Memory) CreateClosedDay(day *models.ClosedDay) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	day.ID = r.nextID("closed_days", day.ID)
+	r.closedDays[day.ID] = *day
+	return nil
+}
+
+// NoticeSent checks if the notice of the kind has been sent for the loan
+// through the channel since it was last renewed
+func (r *Memory) NoticeSent(record models.Record, kind, channel string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, notice := range r.notices {
+		if notice.RecordID == record.ID && notice.Kind == kind &&
+			notice.Channel == channel && notice.ExtendTimes == record.ExtendTimes {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateNotice adds a new notice, and sets its ID
+func (r *Memory) CreateNotice(notice *models.Notice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	notice.ID = r.nextID("notices", notice.ID)
+	r.notices[notice.ID] = *notice
+	return nil
+}
+
+// FindSession finds the session of given token hash
+func (r *Memory) FindSession(tokenHash string) (models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.TokenHash == tokenHash {
+			return session, nil
+		}
+	}
+	return models.Session{}, ErrNotFound
+}
+
+// CreateSession adds a new session, and sets its ID
+func (r *Memory) CreateSession(session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.ID = r.nextID("sessions", session.ID)
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+// TouchSession saves the time the session was last seen
+func (r *Memory) TouchSession(id uint, t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = t
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+// DeleteSession removes the session of given ID
+func (r *Memory) DeleteSession(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+	return nil
+}
+
+// DeleteUserSessions removes all sessions of the user, and returns the number
+// of sessions removed
+func (r *Memory) DeleteUserSessions(userID uint) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count uint
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteOtherSessions removes the sessions of the user except the one of
+// given ID, and returns the number of sessions removed
+func (r *Memory) DeleteOtherSessions(userID, keepID uint) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count uint
+	for id, session := range r.sessions {
+		if session.UserID == userID && id != keepID {
+			delete(r.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteStaleSessions removes the sessions last seen before idleBefore, or
+// created before createdBefore, where a zero time is ignored
+func (r *Memory) DeleteStaleSessions(idleBefore, createdBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.sessions {
+		if session.LastSeenAt.Before(idleBefore) || session.CreatedAt.Before(createdBefore) {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
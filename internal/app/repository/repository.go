@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/models"
+)
+
+// ErrNotFound occurs when the queried entity is not found
+var ErrNotFound = errors.New("repository: not found")
+
+// ErrConflict occurs when the entity has been changed by a concurrent request
+// since read, or the transaction failed due to lock contention
+var ErrConflict = errors.New("database: conflicting update, please try again")
+
+// BookRepository stores books and their copies
+type BookRepository interface {
+	// FindBook finds the book of given ID
+	FindBook(id uint) (models.Book, error)
+	// CreateBook adds a new book, and sets its ID
+	CreateBook(book *models.Book) error
+
+	// FindCopy finds the copy of given ID in circulation
+	FindCopy(id uint) (models.Copy, error)
+	// FindCopyByBarcode finds the copy of given barcode in circulation
+	FindCopyByBarcode(barcode string) (models.Copy, error)
+	// FindAvailableCopy finds the available copy of the book with the lowest
+	// ID, which has the given barcode if not blank, and is of one of the item
+	// categories if not nil
+	FindAvailableCopy(bookID uint, barcode string, categories []string) (models.Copy, error)
+	// CreateCopy adds a new copy, and sets its ID
+	CreateCopy(item *models.Copy) error
+	// SetCopyStatus changes the status of a copy, which fails with ErrConflict
+	// if the status has been changed since read
+	SetCopyStatus(item *models.Copy, status string) error
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	// FindUser finds the user of given ID
+	FindUser(id uint) (models.User, error)
+	// LockUser finds the user of given ID, and locks it until the end of the
+	// transaction, so that the requests of the same user are serialized
+	LockUser(id uint) (models.User, error)
+	// CreateUser adds a new user, and sets its ID
+	CreateUser(user *models.User) error
+}
+
+// RecordRepository stores borrowing records, where a loan is a record whose
+// book has not been returned yet
+type RecordRepository interface {
+	// FindLoan finds the loan of the book by the user
+	FindLoan(userID, bookID uint) (models.Record, error)
+	// FindLoanByCopy finds the loan of the copy
+	FindLoanByCopy(copyID uint) (models.Record, error)
+	// CountOverdue counts the loans of the user past the return date
+	CountOverdue(userID uint, now time.Time) (uint, error)
+	// CountLoans counts the loans of the user, of copies in the item category
+	// if not blank
+	CountLoans(userID uint, category string) (uint, error)
+	// LoansDueBefore finds all loans whose return date is before the time,
+	// sorted by return date
+	LoansDueBefore(t time.Time) ([]models.Record, error)
+	// CreateRecord adds a new loan, and sets its ID
+	CreateRecord(record *models.Record) error
+	// RenewRecord saves the return date, extend times and renewer of a loan,
+	// which fails with ErrConflict if the loan has been renewed or returned
+	// since read, i.e. its extend times is no longer prevExtendTimes
+	RenewRecord(record *models.Record, prevExtendTimes uint) error
+	// CloseRecord saves the receiver of a loan, and marks it as returned, which
+	// fails with ErrConflict if it has been returned since read
+	CloseRecord(record *models.Record, now time.Time) error
+}
+
+// HoldRepository stores the holds on books
+type HoldRepository interface {
+	// FindReadyHold finds the hold of the user on the book ready for pickup
+	FindReadyHold(userID, bookID uint) (models.Hold, error)
+	// NextWaitingHold finds the first hold waiting in line for the book
+	NextWaitingHold(bookID uint) (models.Hold, error)
+	// ExpiredHolds finds the holds ready for pickup past the pickup deadline
+	ExpiredHolds(now time.Time) ([]models.Hold, error)
+	// CreateHold adds a new hold, and sets its ID
+	CreateHold(hold *models.Hold) error
+	// UpdateHold saves the status, the copy kept and the pickup deadline of a
+	// hold
+	UpdateHold(hold *models.Hold) error
+	// FulfillHolds marks the active holds of the user on the book as fulfilled
+	FulfillHolds(userID, bookID uint) error
+}
+
+// FineRepository stores the fines charged to users
+type FineRepository interface {
+	// UnpaidFines returns the total amount of unpaid fines of the user
+	UnpaidFines(userID uint) (float64, error)
+	// CreateFine adds a new fine, and sets its ID
+	CreateFine(fine *models.Fine) error
+}
+
+// PolicyRepository stores the loan policies
+type PolicyRepository interface {
+	// FindPolicy finds the policy of the item category for the patron category
+	FindPolicy(patronCategory, itemCategory string) (models.Policy, error)
+	// CreatePolicy adds a new policy, and sets its ID
+	CreatePolicy(policy *models.Policy) error
+}
+
+// CalendarRepository stores the opening hours and the closed days of the
+// library
+type CalendarRepository interface {
+	// OpeningHours finds the opening hours of all weekdays set
+	OpeningHours() ([]models.OpeningHours, error)
+	// SetOpeningHours replaces the opening hours of the weekday
+	SetOpeningHours(hours *models.OpeningHours) error
+	// ClosedDays finds the closed days from one date to another inclusive, in
+	// the format 2006-01-02
+	ClosedDays(from, to string) ([]models.ClosedDay, error)
+	// CreateClosedDay adds a new closed day, and sets its ID
+	CreateClosedDay(day *models.ClosedDay) error
+}
+
+// NoticeRepository stores the notices sent to users
+type NoticeRepository interface {
+	// NoticeSent checks if the notice of the kind has been sent for the loan
+	// through the channel since it was last renewed
+	NoticeSent(record models.Record, kind, channel string) (bool, error)
+	// CreateNotice adds a new notice, and sets its ID
+	CreateNotice(notice *models.Notice) error
+}
+
+// SessionRepository stores the login sessions of users
+type SessionRepository interface {
+	// FindSession finds the session of given token hash
+	FindSession(tokenHash string) (models.Session, error)
+	// CreateSession adds a new session, and sets its ID
+	CreateSession(session *models.Session) error
+	// TouchSession saves the time the session was last seen
+	TouchSession(id uint, t time.Time) error
+	// DeleteSession removes the session of given ID
+	DeleteSession(id uint) error
+	// DeleteUserSessions removes all sessions of the user, and returns the
+	// number of sessions removed
+	DeleteUserSessions(userID uint) (uint, error)
+	// DeleteOtherSessions removes the sessions of the user except the one of
+	// given ID, and returns the number of sessions removed
+	DeleteOtherSessions(userID, keepID uint) (uint, error)
+	// DeleteStaleSessions removes the sessions last seen before idleBefore,
+	// or created before createdBefore, where a zero time is ignored
+	DeleteStaleSessions(idleBefore, createdBefore time.Time) error
+}
+
+// Transactor runs operations in a transaction
+type Transactor interface {
+	// Transaction runs fn with a repository bound to a new transaction, which
+	// is committed if fn returns nil, and rolled back otherwise
+	Transaction(fn func(repo Repository) error) error
+}
+
+// Repository is a storage of all above
+type Repository interface {
+	Transactor
+	BookRepository
+	UserRepository
+	RecordRepository
+	HoldRepository
+	FineRepository
+	PolicyRepository
+	CalendarRepository
+	NoticeRepository
+	SessionRepository
+}
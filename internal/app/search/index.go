@@ -0,0 +1,203 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Document is an item to be indexed, which consists of several text fields
+type Document struct {
+	ID     uint
+	Fields map[string]string
+}
+
+// Result is a document matching the query, along with its relevance score
+type Result struct {
+	ID    uint
+	Score float64
+}
+
+// Index is an inverted index mapping terms to the documents containing them
+// It's safe for concurrent use
+type Index struct {
+	mu sync.RWMutex
+
+	// weights are the boosts of the fields, only these fields are indexed
+	weights map[string]float64
+	// postings maps a term to the positions of it in each field of each
+	// document
+	postings map[string]map[uint]map[string][]int
+	// terms are all indexed terms in sorted order, used in prefix matching
+	terms []string
+	// docs maps a document to the terms in it and the lengths of its fields
+	docs map[uint]*docEntry
+	// fieldLens are the total lengths of the fields in all documents
+	fieldLens map[string]int
+}
+
+type docEntry struct {
+	terms []string
+	lens  map[string]int
+}
+
+// NewIndex creates an empty index over the fields with given weights
+func NewIndex(weights map[string]float64) *Index {
+	return &Index{
+		weights:   weights,
+		postings:  make(map[string]map[uint]map[string][]int),
+		docs:      make(map[uint]*docEntry),
+		fieldLens: make(map[string]int),
+	}
+}
+
+// Add adds a document to the index, replacing the previous one of the same ID
+func (idx *Index) Add(doc Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(doc.ID)
+	entry := &docEntry{lens: make(map[string]int)}
+	for field, text := range doc.Fields {
+		if _, ok := idx.weights[field]; !ok {
+			continue
+		}
+		terms := tokenize(text)
+		for pos, term := range terms {
+			docs, ok := idx.postings[term]
+			if !ok {
+				docs = make(map[uint]map[string][]int)
+				idx.postings[term] = docs
+				idx.insertTerm(term)
+			}
+			if docs[doc.ID] == nil {
+				docs[doc.ID] = make(map[string][]int)
+				entry.terms = append(entry.terms, term)
+			}
+			docs[doc.ID][field] = append(docs[doc.ID][field], pos)
+		}
+		entry.lens[field] = len(terms)
+		idx.fieldLens[field] += len(terms)
+	}
+	idx.docs[doc.ID] = entry
+}
+
+// Remove removes a document from the index
+func (idx *Index) Remove(id uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// Len returns the number of documents in the index
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search finds the documents matching the query, ranked by relevance
+// Results with the same score are sorted by ID
+func (idx *Index) Search(query string) ([]Result, error) {
+	root, err := parse(query, idx.weights)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	scores := root.eval(idx)
+	idx.mu.RUnlock()
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+func (idx *Index) remove(id uint) {
+	entry, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for field, n := range entry.lens {
+		idx.fieldLens[field] -= n
+	}
+	delete(idx.docs, id)
+
+	for _, term := range entry.terms {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+			idx.deleteTerm(term)
+		}
+	}
+}
+
+func (idx *Index) insertTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+}
+
+func (idx *Index) deleteTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	if i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+// expand returns the indexed terms starting with the prefix
+func (idx *Index) expand(prefix string) []string {
+	var terms []string
+	for i := sort.SearchStrings(idx.terms, prefix); i < len(idx.terms); i++ {
+		if !strings.HasPrefix(idx.terms[i], prefix) {
+			break
+		}
+		terms = append(terms, idx.terms[i])
+	}
+	return terms
+}
+
+// all returns all documents with a zero score
+func (idx *Index) all() map[uint]float64 {
+	scores := make(map[uint]float64, len(idx.docs))
+	for id := range idx.docs {
+		scores[id] = 0
+	}
+	return scores
+}
+
+// score computes the BM25 score of a term in a field of a document, boosted
+// by the weight of the field
+func (idx *Index) score(term string, id uint, field string) float64 {
+	docs := idx.postings[term]
+	tf := float64(len(docs[id][field]))
+	if tf == 0 {
+		return 0
+	}
+	n := float64(len(idx.docs))
+	df := float64(len(docs))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	avg := float64(idx.fieldLens[field]) / n
+	norm := 1.0
+	if avg > 0 {
+		norm = 1 - b + b*float64(idx.docs[id].lens[field])/avg
+	}
+	return idx.weights[field] * idf * tf * (k1 + 1) / (tf + k1*norm)
+}
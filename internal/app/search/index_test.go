@@ -0,0 +1,134 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestIndex indexes a few books by title and author
+func newTestIndex() *Index {
+	idx := NewIndex(testWeights)
+	for _, doc := range []Document{
+		{1, map[string]string{"title": "The Go Programming Language", "author": "Alan Donovan; Brian Kernighan"}},
+		{2, map[string]string{"title": "The C Programming Language", "author": "Brian Kernighan; Dennis Ritchie"}},
+		{3, map[string]string{"title": "Programming Pearls", "author": "Jon Bentley"}},
+		{4, map[string]string{"title": "Go in Action", "author": "William Kennedy"}},
+	} {
+		idx.Add(doc)
+	}
+	return idx
+}
+
+// searchIDs returns the IDs of the results in order
+func searchIDs(t *testing.T, idx *Index, query string) []uint {
+	t.Helper()
+	results, err := idx.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []uint
+	}{
+		{"term", "kernighan", []uint{1, 2}},
+		{"shorter field first", "go", []uint{4, 1}},
+		{"rarer term first", "go OR pearls", []uint{3, 4, 1}},
+		{"all terms", "go programming", []uint{1}},
+		{"prefix", "program*", []uint{3, 1, 2}},
+		{"prefix matching a whole term", "pearls*", []uint{3}},
+		{"prefix matching nothing", "rust*", nil},
+		{"phrase", `"programming language"`, []uint{1, 2}},
+		{"phrase out of order", `"language programming"`, nil},
+		{"field", "author:kennedy", []uint{4}},
+		{"wrong field", "title:kernighan", nil},
+		{"field phrase", `author:"brian kernighan"`, []uint{1, 2}},
+		{"exclusion", "programming -go", []uint{3, 2}},
+		{"exclusion only", "-go", []uint{2, 3}},
+		{"grouping", "(go OR c) AND language", []uint{2, 1}},
+	}
+	idx := newTestIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIDs(t, idx, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPrefixScoresBestTerm(t *testing.T) {
+	idx := NewIndex(testWeights)
+	idx.Add(Document{1, map[string]string{"title": "Programs"}})
+	idx.Add(Document{2, map[string]string{"title": "Programming Programs Programmers"}})
+
+	results, err := idx.Search("program*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search = %v, want 2 results", results)
+	}
+	best := 0.0
+	for _, term := range []string{"programming", "programs", "programmers"} {
+		if score := idx.score(term, 2, "title"); score > best {
+			best = score
+		}
+	}
+	for _, result := range results {
+		if result.ID == 2 && result.Score != best {
+			t.Errorf("score of book 2 = %v, want %v", result.Score, best)
+		}
+	}
+}
+
+func TestIndexAddRemove(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Remove(1)
+	if got := searchIDs(t, idx, "kernighan"); !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("after Remove, Search = %v, want [2]", got)
+	}
+	if got := searchIDs(t, idx, "donovan"); got != nil {
+		t.Errorf("after Remove, Search = %v, want none", got)
+	}
+
+	idx.Add(Document{2, map[string]string{"title": "Structure and Interpretation of Computer Programs"}})
+	if got := searchIDs(t, idx, "c"); got != nil {
+		t.Errorf("after replacing, Search = %v, want none", got)
+	}
+	if got := searchIDs(t, idx, "computer"); !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("after replacing, Search = %v, want [2]", got)
+	}
+	if got := idx.Len(); got != 3 {
+		t.Errorf("Len = %v, want 3", got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The Go Programming Language", []string{"the", "go", "programming", "language"}},
+		{"C++ & Go, 2nd ed.", []string{"c", "go", "2nd", "ed"}},
+		{"978-1-9850-8659-3", []string{"9781985086593"}},
+		{"0-13-409266-X", []string{"013409266x"}},
+		{"Arpaci-Dusseau", []string{"arpaci", "dusseau"}},
+		{"Café 東京", []string{"café", "東京"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
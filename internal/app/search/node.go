@@ -0,0 +1,157 @@
+package search
+
+// node is a part of the parsed query, which evaluates to the matching
+// documents and their scores
+// Nodes are evaluated with the read lock of the index held
+type node interface {
+	eval(idx *Index) map[uint]float64
+}
+
+// termNode matches a term, in the given field or any field if left blank
+type termNode struct {
+	field string
+	term  string
+}
+
+func (n termNode) eval(idx *Index) map[uint]float64 {
+	scores := make(map[uint]float64)
+	for id, fields := range idx.postings[n.term] {
+		for field := range fields {
+			if n.field == "" || n.field == field {
+				scores[id] += idx.score(n.term, id, field)
+			}
+		}
+	}
+	return scores
+}
+
+// prefixNode matches all terms starting with the prefix
+// Each document is scored by its best matching term. The expanded terms are
+// distinct, so their postings are visited at most once
+type prefixNode struct {
+	field  string
+	prefix string
+}
+
+func (n prefixNode) eval(idx *Index) map[uint]float64 {
+	scores := make(map[uint]float64)
+	for _, term := range idx.expand(n.prefix) {
+		for id, score := range (termNode{n.field, term}).eval(idx) {
+			if score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+	return scores
+}
+
+// phraseNode matches the terms appearing next to each other in order
+type phraseNode struct {
+	field string
+	terms []string
+}
+
+func (n phraseNode) eval(idx *Index) map[uint]float64 {
+	scores := make(map[uint]float64)
+	for id, fields := range idx.postings[n.terms[0]] {
+		for field, positions := range fields {
+			if n.field != "" && n.field != field {
+				continue
+			}
+			if !n.matchAt(idx, id, field, positions) {
+				continue
+			}
+			for _, term := range n.terms {
+				scores[id] += idx.score(term, id, field)
+			}
+		}
+	}
+	return scores
+}
+
+// matchAt checks if the phrase starts at any of the positions of its first
+// term in a field of the document
+func (n phraseNode) matchAt(idx *Index, id uint, field string, positions []int) bool {
+	for _, start := range positions {
+		matched := true
+		for i, term := range n.terms[1:] {
+			if !containsInt(idx.postings[term][id][field], start+i+1) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// andNode matches the documents matching all of must and none of not
+// The scores of must are summed up
+type andNode struct {
+	must []node
+	not  []node
+}
+
+func (n andNode) eval(idx *Index) map[uint]float64 {
+	var scores map[uint]float64
+	if len(n.must) == 0 {
+		scores = idx.all()
+	}
+	for i, child := range n.must {
+		childScores := child.eval(idx)
+		if i == 0 {
+			scores = childScores
+			continue
+		}
+		for id := range scores {
+			if score, ok := childScores[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	for _, child := range n.not {
+		for id := range child.eval(idx) {
+			delete(scores, id)
+		}
+	}
+	return scores
+}
+
+// orNode matches the documents matching any of the children
+// The scores of matched children are summed up
+type orNode struct {
+	children []node
+}
+
+func (n orNode) eval(idx *Index) map[uint]float64 {
+	scores := make(map[uint]float64)
+	for _, child := range n.children {
+		for id, score := range child.eval(idx) {
+			scores[id] += score
+		}
+	}
+	return scores
+}
+
+// notNode matches the documents not matching the child
+// It's only evaluated on its own when not part of an AND, e.g. "a OR -b"
+type notNode struct {
+	child node
+}
+
+func (n notNode) eval(idx *Index) map[uint]float64 {
+	return andNode{not: []node{n.child}}.eval(idx)
+}
+
+func containsInt(list []int, n int) bool {
+	for _, elem := range list {
+		if elem == n {
+			return true
+		}
+	}
+	return false
+}
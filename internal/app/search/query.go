@@ -0,0 +1,282 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrEmptyQuery occurs when the query contains no terms
+var ErrEmptyQuery = errors.New("search: empty query")
+
+// ErrInvalidQuery occurs when the query can't be parsed
+var ErrInvalidQuery = errors.New("search: invalid query")
+
+// maxTerms is the maximum number of words and phrases in a query
+// Each of them is evaluated in at most one pass over the postings of the
+// index, even a prefix matching every term, so this bounds the cost of a query
+const maxTerms = 32
+
+// maxDepth is the maximum nesting depth of parentheses in a query, which bounds
+// the recursion in parsing and evaluating it
+const maxDepth = 16
+
+// Query syntax:
+//
+//   go programming      books containing both terms
+//   go OR golang        books containing either term
+//   go -python          books containing go but not python (or NOT python)
+//   prog*               terms starting with prog
+//   "the go language"   the exact phrase
+//   author:kernighan    the term in the author field only
+//   (go OR c) AND unix  grouping, AND is optional
+//
+// In a query, expr := and { "OR" and }
+//             and  := unary { [ "AND" ] unary }
+//             unary := ( "NOT" | "-" ) unary | primary
+//             primary := "(" expr ")" | [ field ":" ] ( phrase | word [ "*" ] )
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokLParen
+	tokRParen
+	tokNot
+	tokAnd
+	tokOr
+)
+
+type token struct {
+	kind  tokenKind
+	field string
+	text  string
+}
+
+// lex splits the query into tokens
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, token{kind: tokNot})
+			i++
+		case r == '"':
+			text, n, err := lexPhrase(runes[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: text})
+			i += n
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) &&
+				runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokAnd})
+				continue
+			case "OR":
+				tokens = append(tokens, token{kind: tokOr})
+				continue
+			case "NOT":
+				tokens = append(tokens, token{kind: tokNot})
+				continue
+			}
+
+			// Field qualifier, followed by a word or a phrase
+			field := ""
+			if j := strings.Index(word, ":"); j > 0 {
+				field, word = strings.ToLower(word[:j]), word[j+1:]
+			}
+			if word == "" && i < len(runes) && runes[i] == '"' {
+				text, n, err := lexPhrase(runes[i:])
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, token{kind: tokPhrase, field: field, text: text})
+				i += n
+				continue
+			}
+			tokens = append(tokens, token{kind: tokWord, field: field, text: word})
+		}
+	}
+	return tokens, nil
+}
+
+// lexPhrase reads a quoted phrase, and returns its text and length
+func lexPhrase(runes []rune) (string, int, error) {
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			return string(runes[1:i]), i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields map[string]float64
+	// terms is the number of words and phrases parsed so far
+	terms int
+	// depth is the number of parentheses enclosing the current token
+	depth int
+}
+
+// parse parses the query into a tree of nodes
+func parse(query string, fields map[string]float64) (node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, fields: fields}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected ')'", ErrInvalidQuery)
+	}
+	if root == nil {
+		return nil, ErrEmptyQuery
+	}
+	return root, nil
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *parser) parseOr() (node, error) {
+	var children []node
+	for {
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, child)
+		}
+		if tok, ok := p.peek(); !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+	}
+	switch len(children) {
+	case 0:
+		return nil, nil
+	case 1:
+		return children[0], nil
+	}
+	return orNode{children}, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	var must, not []node
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokRParen {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+			continue
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch child := child.(type) {
+		case nil:
+		case notNode:
+			not = append(not, child.child)
+		default:
+			must = append(must, child)
+		}
+	}
+	if len(must) == 1 && len(not) == 0 {
+		return must[0], nil
+	}
+	if len(must) == 0 && len(not) == 0 {
+		return nil, nil
+	}
+	return andNode{must, not}, nil
+}
+
+// parseUnary folds consecutive negations in a loop, so that a long run of them
+// doesn't recurse
+func (p *parser) parseUnary() (node, error) {
+	negated := false
+	for tok, _ := p.peek(); tok.kind == tokNot; tok, _ = p.peek() {
+		negated = !negated
+		p.pos++
+	}
+	child, err := p.parsePrimary()
+	if err != nil || child == nil || !negated {
+		return child, err
+	}
+	return notNode{child}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, nil
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokLParen:
+		if p.depth++; p.depth > maxDepth {
+			return nil, fmt.Errorf("%w: nested more than %v levels", ErrInvalidQuery, maxDepth)
+		}
+		child, err := p.parseOr()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		if tok, ok := p.peek(); !ok || tok.kind != tokRParen {
+			return nil, fmt.Errorf("%w: missing ')'", ErrInvalidQuery)
+		}
+		p.pos++
+		return child, nil
+	case tokWord, tokPhrase:
+		if _, ok := p.fields[tok.field]; tok.field != "" && !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, tok.field)
+		}
+		if p.terms++; p.terms > maxTerms {
+			return nil, fmt.Errorf("%w: more than %v terms", ErrInvalidQuery, maxTerms)
+		}
+		prefix := tok.kind == tokWord && strings.HasSuffix(tok.text, "*")
+		terms := tokenize(tok.text)
+		switch {
+		case len(terms) == 0:
+			return nil, nil
+		case len(terms) > 1:
+			return phraseNode{tok.field, terms}, nil
+		case prefix:
+			return prefixNode{tok.field, terms[0]}, nil
+		}
+		return termNode{tok.field, terms[0]}, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected operator", ErrInvalidQuery)
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testWeights are the fields indexed in the tests
+var testWeights = map[string]float64{"title": 2, "author": 1}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  node
+	}{
+		{"go", termNode{"", "go"}},
+		{"Go Programming", andNode{must: []node{termNode{"", "go"}, termNode{"", "programming"}}}},
+		{"go AND c", andNode{must: []node{termNode{"", "go"}, termNode{"", "c"}}}},
+		{"go OR golang", orNode{[]node{termNode{"", "go"}, termNode{"", "golang"}}}},
+		{"go -python", andNode{[]node{termNode{"", "go"}}, []node{termNode{"", "python"}}}},
+		{"go NOT python", andNode{[]node{termNode{"", "go"}}, []node{termNode{"", "python"}}}},
+		{"NOT NOT go", termNode{"", "go"}},
+		{"go OR -c", orNode{[]node{termNode{"", "go"}, andNode{not: []node{termNode{"", "c"}}}}}},
+		{"prog*", prefixNode{"", "prog"}},
+		{`"The Go  Language"`, phraseNode{"", []string{"the", "go", "language"}}},
+		{"author:Kernighan", termNode{"author", "kernighan"}},
+		{`title:"go language"`, phraseNode{"title", []string{"go", "language"}}},
+		{"title:prog*", prefixNode{"title", "prog"}},
+		{"(go OR c) AND unix", andNode{must: []node{
+			orNode{[]node{termNode{"", "go"}, termNode{"", "c"}}},
+			termNode{"", "unix"},
+		}}},
+		{"978-1-9850-8659-3", termNode{"", "9781985086593"}},
+		{"go ???", termNode{"", "go"}},
+		{strings.Repeat("(", maxDepth) + "go" + strings.Repeat(")", maxDepth), termNode{"", "go"}},
+		{strings.Repeat("-", 1000) + "go", termNode{"", "go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parse(tt.query, testWeights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse(%q) = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{"blank", "  ", ErrEmptyQuery},
+		{"no terms", "??? !!!", ErrEmptyQuery},
+		{"operators only", "OR AND", ErrEmptyQuery},
+		{"unterminated phrase", `"go language`, ErrInvalidQuery},
+		{"missing ')'", "(go OR c", ErrInvalidQuery},
+		{"unexpected ')'", "go)", ErrInvalidQuery},
+		{"unknown field", "isbn:123", ErrInvalidQuery},
+		{"too many terms", strings.Repeat("go ", maxTerms+1), ErrInvalidQuery},
+		{"nested too deep", strings.Repeat("(", maxDepth+1) + "go" + strings.Repeat(")", maxDepth+1), ErrInvalidQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse(tt.query, testWeights); !errors.Is(err, tt.want) {
+				t.Errorf("parse(%q) = %v, want %v", tt.query, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := parse(strings.Repeat("go ", maxTerms), testWeights); err != nil {
+		t.Errorf("parse(%v terms) = %v, want nil", maxTerms, err)
+	}
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenize splits the text into lowercase terms of letters and digits
+// Hyphens between digits are dropped, so that an ISBN like 978-1-9850-8659-3
+// is kept as a single term
+func tokenize(text string) []string {
+	var terms []string
+	var term strings.Builder
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			term.WriteRune(unicode.ToLower(r))
+			continue
+		case r == '-' && i > 0 && i+1 < len(runes) &&
+			unicode.IsDigit(runes[i-1]) && isISBNRune(runes[i+1]):
+			continue
+		}
+		if term.Len() > 0 {
+			terms = append(terms, term.String())
+			term.Reset()
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+func isISBNRune(r rune) bool {
+	return unicode.IsDigit(r) || r == 'X' || r == 'x'
+}
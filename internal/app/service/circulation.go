@@ -0,0 +1,644 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/calendar"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"go.uber.org/zap"
+)
+
+const day = time.Hour * 24
+
+// ErrBookNotFound occurs when the queried book is not found
+var ErrBookNotFound = errors.New("database: book not found")
+
+// ErrUserNotFound occurs when the user is not found
+var ErrUserNotFound = errors.New("database: user not found")
+
+// ErrExceedMaxOverdueBooks occurs when the user has too many overdue books,
+// thus being suspended
+var ErrExceedMaxOverdueBooks = errors.New("library: too many overdue books")
+
+// ErrExceedMaxUnpaidFines occurs when the user has too many unpaid fines,
+// thus being suspended
+var ErrExceedMaxUnpaidFines = errors.New("library: too many unpaid fines")
+
+// ErrAccountInactive occurs when lending to a user pending approval,
+// suspended or banned, or renewing a loan of the user
+var ErrAccountInactive = errors.New("library: account not active")
+
+// ErrExceedMaxActiveLoans occurs when the user has borrowed as many books as
+// allowed at a time
+var ErrExceedMaxActiveLoans = errors.New("library: too many books borrowed")
+
+// ErrBookBorrowed occurs when the user wants to borrow a book which has been
+// borrowed before
+var ErrBookBorrowed = errors.New("library: book already borrowed")
+
+// ErrBookNotBorrowed occurs when the user wants to return a book which has
+// not been borrowed before
+var ErrBookNotBorrowed = errors.New("library: book not borrowed")
+
+// ErrNoCopyAvailable occurs when all copies of the book are on loan or out of
+// circulation
+var ErrNoCopyAvailable = errors.New("library: no copy available")
+
+// ErrExceedMaxExtendTimes occurs when the user has extended the deadline too
+// many times
+var ErrExceedMaxExtendTimes = errors.New("library: extended too many times")
+
+// ErrNotForLoan occurs when the copies available are not lent to the user by
+// the loan policy of their item category
+var ErrNotForLoan = errors.New("library: copy not for loan")
+
+// ErrExceedCategoryLoans occurs when the user has borrowed too many copies of
+// the item category
+var ErrExceedCategoryLoans = errors.New("library: too many books of the category borrowed")
+
+// Quota is the number of books borrowed by a user, and the number of books
+// allowed at a time, where Limit is 0 and Remaining is nil if unlimited
+type Quota struct {
+	Loans     uint  `json:"loans"`
+	Limit     uint  `json:"limit"`
+	Remaining *uint `json:"remaining"`
+}
+
+// Circulation holds the rules of lending, renewing and returning books, and of
+// the hold queues
+// staffID in the methods is the ID of the admin who performs the operation at
+// the circulation desk, or 0 if the user does it by himself/herself
+// Each operation runs in a transaction if Tx is set, where the user is locked
+// first, and the copy and the record are updated only if unchanged since read.
+// Otherwise repository.ErrConflict is returned, and nothing is changed
+// The loan period and renewals are decided by the loan policy of the copy's
+// item category for the user's patron category, see Policy, and the books fall
+// due at the closing time of an open day in the library calendar
+type Circulation struct {
+	Tx       repository.Transactor
+	Books    repository.BookRepository
+	Users    repository.UserRepository
+	Records  repository.RecordRepository
+	Holds    repository.HoldRepository
+	Fines    repository.FineRepository
+	Policies repository.PolicyRepository
+	Calendar repository.CalendarRepository
+
+	Config config.LibraryConfig
+	Logger *zap.SugaredLogger
+	// Now returns the current time, which is the local time by default
+	Now func() time.Time
+}
+
+// NewCirculation creates a circulation service using the repository
+func NewCirculation(repo repository.Repository, libcfg config.LibraryConfig, logger *zap.SugaredLogger) *Circulation {
+	return &Circulation{
+		Tx:       repo,
+		Books:    repo,
+		Users:    repo,
+		Records:  repo,
+		Holds:    repo,
+		Fines:    repo,
+		Policies: repo,
+		Calendar: repo,
+		Config:   libcfg,
+		Logger:   logger,
+		Now:      func() time.Time { return time.Now().Local() },
+	}
+}
+
+// transaction runs fn with a copy of the service bound to a new transaction,
+// or runs it directly if already in a transaction
+func (s *Circulation) transaction(fn func(tx *Circulation) error) error {
+	if s.Tx == nil {
+		return fn(s)
+	}
+	return s.Tx.Transaction(func(repo repository.Repository) error {
+		tx := *s
+		tx.Tx = nil
+		tx.Books, tx.Users, tx.Records, tx.Holds, tx.Fines = repo, repo, repo, repo, repo
+		tx.Policies, tx.Calendar = repo, repo
+		return fn(&tx)
+	})
+}
+
+// notFound replaces the error if the entity is not found
+func notFound(err error, replacement error) error {
+	if err == repository.ErrNotFound {
+		return replacement
+	}
+	return err
+}
+
+// Policy returns the loan policy of the item category for the patron category
+// The settings in the library config are used if not set, where copies
+// circulate without limits
+func (s *Circulation) Policy(patronCategory, itemCategory string) (models.Policy, error) {
+	policy, err := s.Policies.FindPolicy(patronCategory, itemCategory)
+	if err == repository.ErrNotFound {
+		return models.Policy{
+			PatronCategory: patronCategory,
+			ItemCategory:   itemCategory,
+			Circulates:     true,
+			LoanDays:       s.Config.BorrowExpireDays,
+			RenewDays:      s.Config.DdlExtendDays,
+			MaxRenewals:    s.Config.MaxExtendTimes,
+		}, nil
+	}
+	return policy, err
+}
+
+// DueDate returns the due date of a loan for days from the time, which is
+// counted on the calendar in the time zone of the library, and rolled forward
+// past the closed days. The book falls due at the closing time of the day
+func (s *Circulation) DueDate(from time.Time, days uint) (time.Time, error) {
+	loc, err := s.Config.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	cal := calendar.Calendar{
+		Location: loc,
+		Hours:    make(map[time.Weekday]calendar.Hours),
+		Closed:   make(map[string]bool),
+	}
+
+	hours, err := s.Calendar.OpeningHours()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, h := range hours {
+		cal.Hours[time.Weekday(h.Weekday)] = calendar.Hours{Opens: h.Opens, Closes: h.Closes, Closed: h.Closed}
+	}
+
+	first := from.In(loc).AddDate(0, 0, int(days))
+	last := first.AddDate(0, 0, calendar.MaxRollDays)
+	closed, err := s.Calendar.ClosedDays(first.Format(calendar.DateLayout), last.Format(calendar.DateLayout))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, d := range closed {
+		cal.Closed[d.Date] = true
+	}
+
+	return cal.DueDate(from, days)
+}
+
+// lendable checks the item categories of which the user may borrow a copy by
+// the loan policies, and returns the policies of them, along with the reasons
+// why the others are not allowed
+func (s *Circulation) lendable(user models.User) (map[string]models.Policy, map[string]error, error) {
+	policies := make(map[string]models.Policy)
+	reasons := make(map[string]error)
+	for _, category := range models.ItemCategories {
+		policy, err := s.Policy(user.PatronCategory(), category)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !policy.Circulates {
+			reasons[category] = ErrNotForLoan
+			continue
+		}
+		if policy.MaxLoans > 0 {
+			count, err := s.Records.CountLoans(user.ID, category)
+			if err != nil {
+				return nil, nil, err
+			}
+			if count >= policy.MaxLoans {
+				reasons[category] = ErrExceedCategoryLoans
+				continue
+			}
+		}
+		policies[category] = policy
+	}
+	return policies, reasons, nil
+}
+
+// Quota returns the loan quota of the user
+func (s *Circulation) Quota(userID uint) (Quota, error) {
+	user, err := s.Users.FindUser(userID)
+	if err != nil {
+		return Quota{}, notFound(err, ErrUserNotFound)
+	}
+	return s.quota(user)
+}
+
+// quota counts the loans of the user, where the limit of the user overrides
+// the one in the library config
+func (s *Circulation) quota(user models.User) (Quota, error) {
+	var quota Quota
+	count, err := s.Records.CountLoans(user.ID, "")
+	if err != nil {
+		return quota, err
+	}
+	quota.Loans = count
+	quota.Limit = user.MaxLoans
+	if quota.Limit == 0 {
+		quota.Limit = s.Config.MaxActiveLoans
+	}
+	if quota.Limit > 0 {
+		remaining := uint(0)
+		if count < quota.Limit {
+			remaining = quota.Limit - count
+		}
+		quota.Remaining = &remaining
+	}
+	return quota, nil
+}
+
+// CheckBorrower checks if the user is suspended, due to too many overdue books
+// or unpaid fines
+func (s *Circulation) CheckBorrower(userID uint) error {
+	count, err := s.Records.CountOverdue(userID, s.Now())
+	if err != nil {
+		return err
+	}
+	if count >= s.Config.MaxOverdueBooks {
+		return ErrExceedMaxOverdueBooks
+	}
+
+	unpaid, err := s.Fines.UnpaidFines(userID)
+	if err != nil {
+		return err
+	}
+	if unpaid > s.Config.MaxUnpaidFines {
+		return ErrExceedMaxUnpaidFines
+	}
+	return nil
+}
+
+// Lend lends a copy of the book to the user, enforcing the library rules
+// The copy kept for the user is lent if any, otherwise an available copy
+// barcode specifies the copy to lend, any copy is used if left blank
+// borrowDate is set to the current time if zero
+func (s *Circulation) Lend(
+	userID uint,
+	bookID uint,
+	barcode string,
+	borrowDate time.Time,
+	staffID uint,
+) (models.Record, error) {
+	var record models.Record
+	err := s.transaction(func(tx *Circulation) error {
+		var err error
+		record, err = tx.lend(userID, bookID, barcode, borrowDate, staffID)
+		return err
+	})
+	return record, err
+}
+
+func (s *Circulation) lend(
+	userID uint,
+	bookID uint,
+	barcode string,
+	borrowDate time.Time,
+	staffID uint,
+) (models.Record, error) {
+	var record models.Record
+
+	// Serializes the requests of the user, and checks if the user should be
+	// suspended
+	user, err := s.Users.LockUser(userID)
+	if err != nil {
+		return record, notFound(err, ErrUserNotFound)
+	}
+	if user.AccountState(s.Now()) != models.UserActive {
+		return record, ErrAccountInactive
+	}
+	if err := s.CheckBorrower(userID); err != nil {
+		return record, err
+	}
+
+	// Checks if the book exists
+	if _, err := s.Books.FindBook(bookID); err != nil {
+		return record, notFound(err, ErrBookNotFound)
+	}
+
+	// Checks if the book has been borrowed before
+	if _, err := s.Records.FindLoan(userID, bookID); err != repository.ErrNotFound {
+		if err == nil {
+			err = ErrBookBorrowed
+		}
+		return record, err
+	}
+
+	// Checks if the user has borrowed as many books as allowed, which is
+	// counted after the user is locked, so that concurrent requests can't
+	// exceed the limit
+	quota, err := s.quota(user)
+	if err != nil {
+		return record, err
+	}
+	if quota.Remaining != nil && *quota.Remaining == 0 {
+		return record, ErrExceedMaxActiveLoans
+	}
+
+	// Finds the copy kept for the user, or an available copy of the book,
+	// which is allowed to lend to the user by the loan policies
+	if err := s.expireHolds(); err != nil {
+		return record, err
+	}
+	policies, reasons, err := s.lendable(user)
+	if err != nil {
+		return record, err
+	}
+	barcode = strings.TrimSpace(barcode)
+	var item models.Copy
+	hold, err := s.Holds.FindReadyHold(userID, bookID)
+	if err == nil {
+		item, err = s.Books.FindCopy(hold.CopyID)
+		if err == nil && barcode != "" && item.Barcode != barcode {
+			err = repository.ErrNotFound
+		}
+		if _, ok := policies[item.ItemCategory()]; err == nil && !ok {
+			err = repository.ErrNotFound
+		}
+	}
+	if err != nil {
+		categories := make([]string, 0, len(policies))
+		for _, category := range models.ItemCategories {
+			if _, ok := policies[category]; ok {
+				categories = append(categories, category)
+			}
+		}
+		if item, err = s.Books.FindAvailableCopy(bookID, barcode, categories); err != nil {
+			return record, s.unlendable(err, bookID, barcode, reasons)
+		}
+	}
+	policy := policies[item.ItemCategory()]
+
+	// Calculates return date
+	if borrowDate.IsZero() {
+		borrowDate = s.Now()
+	}
+	returnDate, err := s.DueDate(borrowDate, policy.LoanDays)
+	if err != nil {
+		return record, err
+	}
+	record = models.Record{
+		UserID:      userID,
+		BookID:      bookID,
+		CopyID:      item.ID,
+		BorrowDate:  borrowDate,
+		ReturnDate:  returnDate,
+		ExtendTimes: 0,
+		IssuedBy:    staffID,
+	}
+	if err := s.Records.CreateRecord(&record); err != nil {
+		return record, err
+	}
+	if err := s.Books.SetCopyStatus(&item, models.CopyOnLoan); err != nil {
+		return record, err
+	}
+	if err := s.Holds.FulfillHolds(userID, bookID); err != nil {
+		return record, err
+	}
+
+	// Passes the copy kept for the user on to the next user in line, if the
+	// user has borrowed another copy instead
+	if hold.CopyID != 0 && hold.CopyID != item.ID {
+		held, err := s.Books.FindCopy(hold.CopyID)
+		if err == nil && held.Status == models.CopyOnHold {
+			if err := s.assignCopy(held); err != nil {
+				return record, err
+			}
+		}
+	}
+
+	if staffID == 0 {
+		s.Logger.Infof("User %v borrowed copy %v of book %v", userID, item.ID, bookID)
+	} else {
+		s.Logger.Infof("Admin %v lent copy %v of book %v to user %v", staffID, item.ID, bookID, userID)
+	}
+
+	return record, nil
+}
+
+// unlendable explains why no copy of the book is found for the user, where the
+// copy is not allowed by the loan policies if found regardless of them
+func (s *Circulation) unlendable(err error, bookID uint, barcode string, reasons map[string]error) error {
+	if err != repository.ErrNotFound {
+		return err
+	}
+	if len(reasons) > 0 {
+		item, err := s.Books.FindAvailableCopy(bookID, barcode, nil)
+		if err == nil {
+			return reasons[item.ItemCategory()]
+		}
+		if err != repository.ErrNotFound {
+			return err
+		}
+	}
+	return ErrNoCopyAvailable
+}
+
+// Renew extends the deadline of a loan, enforcing the library rules
+func (s *Circulation) Renew(record *models.Record, staffID uint) error {
+	return s.transaction(func(tx *Circulation) error {
+		return tx.renew(record, staffID)
+	})
+}
+
+func (s *Circulation) renew(record *models.Record, staffID uint) error {
+	user, err := s.Users.LockUser(record.UserID)
+	if err != nil {
+		return notFound(err, ErrUserNotFound)
+	}
+	if user.AccountState(s.Now()) != models.UserActive {
+		return ErrAccountInactive
+	}
+
+	// Finds the loan policy, where the copies of records before copies were
+	// introduced are regular ones
+	item, err := s.Books.FindCopy(record.CopyID)
+	if err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	policy, err := s.Policy(user.PatronCategory(), item.ItemCategory())
+	if err != nil {
+		return err
+	}
+	if !policy.Circulates {
+		return ErrNotForLoan
+	}
+
+	// Checks if the user has extended the deadline too many times
+	if record.ExtendTimes >= policy.MaxRenewals {
+		return ErrExceedMaxExtendTimes
+	}
+
+	returnDate, err := s.DueDate(record.ReturnDate, policy.RenewDays)
+	if err != nil {
+		return err
+	}
+	prevExtendTimes := record.ExtendTimes
+	record.ReturnDate = returnDate
+	record.ExtendTimes++
+	record.RenewedBy = staffID
+	if err := s.Records.RenewRecord(record, prevExtendTimes); err != nil {
+		return err
+	}
+
+	if staffID == 0 {
+		s.Logger.Infof("User %v renewed copy %v of book %v", record.UserID, record.CopyID, record.BookID)
+	} else {
+		s.Logger.Infof("Admin %v renewed copy %v of book %v for user %v",
+			staffID, record.CopyID, record.BookID, record.UserID)
+	}
+
+	return nil
+}
+
+// Return closes a loan and puts the copy back into circulation
+// Returns the fine charged, or nil if the book is returned in time
+func (s *Circulation) Return(record *models.Record, staffID uint) (*models.Fine, error) {
+	var fine *models.Fine
+	err := s.transaction(func(tx *Circulation) error {
+		var err error
+		fine, err = tx.returnRecord(record, staffID)
+		return err
+	})
+	return fine, err
+}
+
+func (s *Circulation) returnRecord(record *models.Record, staffID uint) (*models.Fine, error) {
+	if _, err := s.Users.LockUser(record.UserID); err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+
+	now := s.Now()
+	if staffID != 0 {
+		record.ReceivedBy = staffID
+	}
+	if err := s.Records.CloseRecord(record, now); err != nil {
+		return nil, err
+	}
+	fine, err := s.ChargeFine(*record, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keeps the copy for the next user in line, or puts it back into circulation
+	item, err := s.Books.FindCopy(record.CopyID)
+	if err == nil && item.Status == models.CopyOnLoan {
+		if err := s.assignCopy(item); err != nil {
+			return fine, err
+		}
+	}
+
+	if staffID == 0 {
+		s.Logger.Infof("User %v returned copy %v of book %v", record.UserID, record.CopyID, record.BookID)
+	} else {
+		s.Logger.Infof("Admin %v checked in copy %v of book %v from user %v",
+			staffID, record.CopyID, record.BookID, record.UserID)
+	}
+	if fine != nil {
+		s.Logger.Infof("Charged fine %v of %.2f to user %v", fine.ID, fine.Amount, record.UserID)
+	}
+
+	return fine, nil
+}
+
+// AssignCopy keeps a copy for the first user waiting in line for the book,
+// or puts it back into circulation if nobody is waiting
+func (s *Circulation) AssignCopy(item models.Copy) error {
+	return s.transaction(func(tx *Circulation) error {
+		return tx.assignCopy(item)
+	})
+}
+
+func (s *Circulation) assignCopy(item models.Copy) error {
+	hold, err := s.Holds.NextWaitingHold(item.BookID)
+	if err == repository.ErrNotFound {
+		return s.Books.SetCopyStatus(&item, models.CopyAvailable)
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline := s.Now().Add(time.Duration(s.Config.HoldPickupDays) * day)
+	hold.Status = models.HoldReady
+	hold.CopyID = item.ID
+	hold.PickupDeadline = &deadline
+	if err := s.Holds.UpdateHold(&hold); err != nil {
+		return err
+	}
+	if err := s.Books.SetCopyStatus(&item, models.CopyOnHold); err != nil {
+		return err
+	}
+
+	s.Logger.Infof("Kept copy %v of book %v for user %v until %v",
+		item.ID, item.BookID, hold.UserID, deadline.Format(time.RFC3339))
+	return nil
+}
+
+// ExpireHolds expires the holds which have not been picked up before the
+// pickup deadline, and passes the copies kept on to the next users in line
+func (s *Circulation) ExpireHolds() error {
+	return s.transaction(func(tx *Circulation) error {
+		return tx.expireHolds()
+	})
+}
+
+func (s *Circulation) expireHolds() error {
+	holds, err := s.Holds.ExpiredHolds(s.Now())
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		hold.Status = models.HoldExpired
+		if err := s.Holds.UpdateHold(&hold); err != nil {
+			return err
+		}
+		s.Logger.Infof("Hold %v of user %v on book %v expired", hold.ID, hold.UserID, hold.BookID)
+
+		item, err := s.Books.FindCopy(hold.CopyID)
+		if err == nil && item.Status == models.CopyOnHold {
+			if err := s.assignCopy(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ChargeFine charges a fine for the record if the book is returned late
+// Returns nil if there's no need to charge
+func (s *Circulation) ChargeFine(record models.Record, returnedAt time.Time) (*models.Fine, error) {
+	overdueDays, amount := ComputeFine(s.Config, record.ReturnDate, returnedAt)
+	if amount <= 0 {
+		return nil, nil
+	}
+	fine := models.Fine{
+		UserID:      record.UserID,
+		RecordID:    record.ID,
+		OverdueDays: overdueDays,
+		Amount:      amount,
+		Status:      models.FineUnpaid,
+	}
+	if err := s.Fines.CreateFine(&fine); err != nil {
+		return nil, err
+	}
+	return &fine, nil
+}
+
+// ComputeFine calculates the overdue days and the fine to charge
+// A partial day counts as a whole day
+func ComputeFine(libcfg config.LibraryConfig, returnDate, returnedAt time.Time) (uint, float64) {
+	if !returnedAt.After(returnDate) {
+		return 0, 0
+	}
+	overdueDays := uint(math.Ceil(float64(returnedAt.Sub(returnDate)) / float64(day)))
+	if overdueDays <= libcfg.FineGraceDays {
+		return overdueDays, 0
+	}
+	amount := float64(overdueDays-libcfg.FineGraceDays) * libcfg.FinePerDay
+	if libcfg.FineCap > 0 && amount > libcfg.FineCap {
+		amount = libcfg.FineCap
+	}
+	return overdueDays, math.Round(amount*100) / 100
+}
@@ -0,0 +1,579 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/migrations"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"go.uber.org/zap"
+)
+
+// testConfig is the library config used in the tests, in UTC so that the due
+// dates don't depend on the local time zone
+var testConfig = config.LibraryConfig{
+	BorrowExpireDays: 14,
+	DdlExtendDays:    7,
+	MaxExtendTimes:   2,
+	MaxOverdueBooks:  2,
+	MaxActiveLoans:   3,
+	HoldPickupDays:   3,
+	FinePerDay:       0.5,
+	FineGraceDays:    2,
+	FineCap:          10,
+	MaxUnpaidFines:   5,
+	TimeZone:         "UTC",
+}
+
+// testNow is a Monday morning
+var testNow = time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+
+// circulationTest is a circulation service over a repository in memory, with
+// a clock set by the test
+type circulationTest struct {
+	*Circulation
+	repo *repository.Memory
+	now  time.Time
+}
+
+func newCirculationTest(t *testing.T) *circulationTest {
+	t.Helper()
+	ct := &circulationTest{repo: repository.NewMemory(), now: testNow}
+	ct.Circulation = NewCirculation(ct.repo, testConfig, zap.NewNop().Sugar())
+	ct.Now = func() time.Time { return ct.now }
+	return ct
+}
+
+// addUser adds an active student
+func (ct *circulationTest) addUser(t *testing.T) models.User {
+	t.Helper()
+	user := models.User{Role: models.RoleUser, Category: models.PatronStudent, State: models.UserActive}
+	if err := ct.repo.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// addBook adds a book with available copies of the item categories
+func (ct *circulationTest) addBook(t *testing.T, categories ...string) (models.Book, []models.Copy) {
+	t.Helper()
+	book := models.Book{Title: "Computer Systems"}
+	if err := ct.repo.CreateBook(&book); err != nil {
+		t.Fatal(err)
+	}
+	var items []models.Copy
+	for _, category := range categories {
+		item := models.Copy{BookID: book.ID, Status: models.CopyAvailable, Category: category}
+		if err := ct.repo.CreateCopy(&item); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	return book, items
+}
+
+// lend lends the book to the user, failing the test on errors
+func (ct *circulationTest) lend(t *testing.T, userID, bookID uint) models.Record {
+	t.Helper()
+	record, err := ct.Lend(userID, bookID, "", time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("Lend(%v, %v) failed: %v", userID, bookID, err)
+	}
+	return record
+}
+
+// copyStatus returns the current status of the copy
+func (ct *circulationTest) copyStatus(t *testing.T, id uint) string {
+	t.Helper()
+	item, err := ct.repo.FindCopy(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item.Status
+}
+
+func TestLendDueDate(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, items := ct.addBook(t, models.ItemRegular)
+
+	record := ct.lend(t, user.ID, book.ID)
+	if want := time.Date(2026, time.March, 16, 23, 59, 59, 0, time.UTC); !record.ReturnDate.Equal(want) {
+		t.Errorf("ReturnDate = %v, want %v", record.ReturnDate, want)
+	}
+	if record.CopyID != items[0].ID || record.BorrowDate != testNow {
+		t.Errorf("record = %+v, want copy %v borrowed at %v", record, items[0].ID, testNow)
+	}
+	if status := ct.copyStatus(t, items[0].ID); status != models.CopyOnLoan {
+		t.Errorf("copy status = %v, want %v", status, models.CopyOnLoan)
+	}
+}
+
+func TestLendDueDateSkipsClosedDays(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+
+	// The 14th day is a Monday, which is a holiday, and the library is closed
+	// on Tuesdays, so the book falls due at the closing time on Wednesday
+	ct.repo.SetOpeningHours(&models.OpeningHours{Weekday: uint(time.Tuesday), Closed: true})
+	ct.repo.SetOpeningHours(&models.OpeningHours{Weekday: uint(time.Wednesday), Opens: "09:00", Closes: "18:00"})
+	ct.repo.CreateClosedDay(&models.ClosedDay{Date: "2026-03-16", Name: "Holiday"})
+
+	record := ct.lend(t, user.ID, book.ID)
+	if want := time.Date(2026, time.March, 18, 18, 0, 0, 0, time.UTC); !record.ReturnDate.Equal(want) {
+		t.Errorf("ReturnDate = %v, want %v", record.ReturnDate, want)
+	}
+}
+
+func TestLendPolicyLoanDays(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemReserve)
+	ct.repo.CreatePolicy(&models.Policy{
+		PatronCategory: models.PatronStudent,
+		ItemCategory:   models.ItemReserve,
+		Circulates:     true,
+		LoanDays:       1,
+	})
+
+	record := ct.lend(t, user.ID, book.ID)
+	if want := time.Date(2026, time.March, 3, 23, 59, 59, 0, time.UTC); !record.ReturnDate.Equal(want) {
+		t.Errorf("ReturnDate = %v, want %v", record.ReturnDate, want)
+	}
+}
+
+func TestLendLoanLimits(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	var books []models.Book
+	for i := 0; i < 4; i++ {
+		book, _ := ct.addBook(t, models.ItemRegular)
+		books = append(books, book)
+	}
+
+	for _, book := range books[:3] {
+		ct.lend(t, user.ID, book.ID)
+	}
+	if _, err := ct.Lend(user.ID, books[3].ID, "", time.Time{}, 0); err != ErrExceedMaxActiveLoans {
+		t.Errorf("Lend past max_active_loans: err = %v, want %v", err, ErrExceedMaxActiveLoans)
+	}
+	if _, err := ct.Lend(user.ID, books[0].ID, "", time.Time{}, 0); err != ErrBookBorrowed {
+		t.Errorf("Lend borrowed book: err = %v, want %v", err, ErrBookBorrowed)
+	}
+
+	quota, err := ct.Quota(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Loans != 3 || quota.Limit != 3 || quota.Remaining == nil || *quota.Remaining != 0 {
+		t.Errorf("Quota = %+v, want 3 loans of 3", quota)
+	}
+}
+
+func TestLendUserMaxLoans(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := models.User{Category: models.PatronStudent, State: models.UserActive, MaxLoans: 1}
+	ct.repo.CreateUser(&user)
+	first, _ := ct.addBook(t, models.ItemRegular)
+	second, _ := ct.addBook(t, models.ItemRegular)
+
+	ct.lend(t, user.ID, first.ID)
+	if _, err := ct.Lend(user.ID, second.ID, "", time.Time{}, 0); err != ErrExceedMaxActiveLoans {
+		t.Errorf("Lend past the user's max_loans: err = %v, want %v", err, ErrExceedMaxActiveLoans)
+	}
+}
+
+func TestLendCategoryPolicies(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	ct.repo.CreatePolicy(&models.Policy{
+		PatronCategory: models.PatronStudent,
+		ItemCategory:   models.ItemReference,
+		Circulates:     false,
+	})
+	ct.repo.CreatePolicy(&models.Policy{
+		PatronCategory: models.PatronStudent,
+		ItemCategory:   models.ItemReserve,
+		Circulates:     true,
+		LoanDays:       1,
+		MaxLoans:       1,
+	})
+	reference, _ := ct.addBook(t, models.ItemReference)
+	first, _ := ct.addBook(t, models.ItemReserve)
+	second, _ := ct.addBook(t, models.ItemReserve)
+
+	if _, err := ct.Lend(user.ID, reference.ID, "", time.Time{}, 0); err != ErrNotForLoan {
+		t.Errorf("Lend reference copy: err = %v, want %v", err, ErrNotForLoan)
+	}
+	ct.lend(t, user.ID, first.ID)
+	if _, err := ct.Lend(user.ID, second.ID, "", time.Time{}, 0); err != ErrExceedCategoryLoans {
+		t.Errorf("Lend past the category's max_loans: err = %v, want %v", err, ErrExceedCategoryLoans)
+	}
+}
+
+func TestLendNoCopyAvailable(t *testing.T) {
+	ct := newCirculationTest(t)
+	first := ct.addUser(t)
+	second := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+
+	ct.lend(t, first.ID, book.ID)
+	if _, err := ct.Lend(second.ID, book.ID, "", time.Time{}, 0); err != ErrNoCopyAvailable {
+		t.Errorf("Lend without copies: err = %v, want %v", err, ErrNoCopyAvailable)
+	}
+	if _, err := ct.Lend(first.ID, book.ID+1, "", time.Time{}, 0); err != ErrBookNotFound {
+		t.Errorf("Lend unknown book: err = %v, want %v", err, ErrBookNotFound)
+	}
+}
+
+func TestLendSuspendedBorrower(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	var books []models.Book
+	for i := 0; i < 3; i++ {
+		book, _ := ct.addBook(t, models.ItemRegular)
+		books = append(books, book)
+	}
+
+	// Two books overdue
+	ct.lend(t, user.ID, books[0].ID)
+	ct.lend(t, user.ID, books[1].ID)
+	ct.now = ct.now.AddDate(0, 1, 0)
+	if _, err := ct.Lend(user.ID, books[2].ID, "", time.Time{}, 0); err != ErrExceedMaxOverdueBooks {
+		t.Errorf("Lend with overdue books: err = %v, want %v", err, ErrExceedMaxOverdueBooks)
+	}
+}
+
+func TestLendUnpaidFines(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	ct.repo.CreateFine(&models.Fine{UserID: user.ID, Amount: 5.5, Status: models.FineUnpaid})
+
+	if _, err := ct.Lend(user.ID, book.ID, "", time.Time{}, 0); err != ErrExceedMaxUnpaidFines {
+		t.Errorf("Lend with unpaid fines: err = %v, want %v", err, ErrExceedMaxUnpaidFines)
+	}
+}
+
+func TestLendInactiveAccount(t *testing.T) {
+	ct := newCirculationTest(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	until := testNow.Add(day)
+	for _, user := range []models.User{
+		{State: models.UserPending},
+		{State: models.UserBanned},
+		{State: models.UserSuspended, SuspendedUntil: &until},
+	} {
+		ct.repo.CreateUser(&user)
+		if _, err := ct.Lend(user.ID, book.ID, "", time.Time{}, 0); err != ErrAccountInactive {
+			t.Errorf("Lend to %v user: err = %v, want %v", user.State, err, ErrAccountInactive)
+		}
+	}
+}
+
+func TestRenew(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+
+	for i := 1; i <= 2; i++ {
+		if err := ct.Renew(&record, 0); err != nil {
+			t.Fatalf("Renew #%v failed: %v", i, err)
+		}
+	}
+	if want := time.Date(2026, time.March, 30, 23, 59, 59, 0, time.UTC); !record.ReturnDate.Equal(want) {
+		t.Errorf("ReturnDate = %v, want %v", record.ReturnDate, want)
+	}
+	if record.ExtendTimes != 2 {
+		t.Errorf("ExtendTimes = %v, want 2", record.ExtendTimes)
+	}
+	if err := ct.Renew(&record, 0); err != ErrExceedMaxExtendTimes {
+		t.Errorf("Renew past max_extend_times: err = %v, want %v", err, ErrExceedMaxExtendTimes)
+	}
+
+	stored, err := ct.repo.FindLoan(user.ID, book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.ReturnDate.Equal(record.ReturnDate) || stored.ExtendTimes != 2 {
+		t.Errorf("stored loan = %+v, want renewed twice", stored)
+	}
+}
+
+func TestRenewInactiveAccount(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+
+	until := testNow.Add(day)
+	user.State, user.SuspendedUntil = models.UserSuspended, &until
+	ct.repo.CreateUser(&user)
+	if err := ct.Renew(&record, 0); err != ErrAccountInactive {
+		t.Errorf("Renew for suspended user: err = %v, want %v", err, ErrAccountInactive)
+	}
+	if err := ct.Renew(&record, 1); err != ErrAccountInactive {
+		t.Errorf("Renew at the desk for suspended user: err = %v, want %v", err, ErrAccountInactive)
+	}
+
+	// The loan can be renewed again once the suspension is over
+	ct.now = until
+	if err := ct.Renew(&record, 0); err != nil {
+		t.Errorf("Renew after suspension failed: %v", err)
+	}
+}
+
+func TestRenewStaleRecord(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+
+	stale := record
+	if err := ct.Renew(&record, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ct.Renew(&stale, 0); err != repository.ErrConflict {
+		t.Errorf("Renew stale record: err = %v, want %v", err, repository.ErrConflict)
+	}
+}
+
+func TestReturnInTime(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, items := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+
+	ct.now = record.ReturnDate
+	fine, err := ct.Return(&record, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fine != nil {
+		t.Errorf("fine = %+v, want nil", fine)
+	}
+	if record.DeletedAt == nil {
+		t.Error("record not closed")
+	}
+	if status := ct.copyStatus(t, items[0].ID); status != models.CopyAvailable {
+		t.Errorf("copy status = %v, want %v", status, models.CopyAvailable)
+	}
+	if _, err := ct.Return(&record, 0); err != repository.ErrConflict {
+		t.Errorf("Return twice: err = %v, want %v", err, repository.ErrConflict)
+	}
+}
+
+func TestReturnFines(t *testing.T) {
+	tests := []struct {
+		name        string
+		late        time.Duration
+		overdueDays uint
+		amount      float64
+	}{
+		{"within grace days", 2 * day, 0, 0},
+		{"partial day", 2*day + time.Hour, 3, 0.5},
+		{"after grace days", 6 * day, 6, 2},
+		{"capped", 60 * day, 60, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := newCirculationTest(t)
+			user := ct.addUser(t)
+			book, _ := ct.addBook(t, models.ItemRegular)
+			record := ct.lend(t, user.ID, book.ID)
+
+			ct.now = record.ReturnDate.Add(tt.late)
+			fine, err := ct.Return(&record, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.amount == 0 {
+				if fine != nil {
+					t.Errorf("fine = %+v, want nil", fine)
+				}
+				return
+			}
+			if fine == nil {
+				t.Fatal("fine = nil")
+			}
+			if fine.OverdueDays != tt.overdueDays || fine.Amount != tt.amount ||
+				fine.Status != models.FineUnpaid || fine.RecordID != record.ID {
+				t.Errorf("fine = %+v, want %v days of %v unpaid", fine, tt.overdueDays, tt.amount)
+			}
+			if unpaid, _ := ct.repo.UnpaidFines(user.ID); unpaid != tt.amount {
+				t.Errorf("UnpaidFines = %v, want %v", unpaid, tt.amount)
+			}
+		})
+	}
+}
+
+func TestHoldQueue(t *testing.T) {
+	ct := newCirculationTest(t)
+	borrower := ct.addUser(t)
+	first := ct.addUser(t)
+	second := ct.addUser(t)
+	book, items := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, borrower.ID, book.ID)
+
+	for _, user := range []models.User{first, second} {
+		hold := models.Hold{UserID: user.ID, BookID: book.ID, Status: models.HoldWaiting}
+		if err := ct.repo.CreateHold(&hold); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The returned copy is kept for the first user in line
+	if _, err := ct.Return(&record, 0); err != nil {
+		t.Fatal(err)
+	}
+	if status := ct.copyStatus(t, items[0].ID); status != models.CopyOnHold {
+		t.Errorf("copy status = %v, want %v", status, models.CopyOnHold)
+	}
+	hold, err := ct.repo.FindReadyHold(first.ID, book.ID)
+	if err != nil {
+		t.Fatalf("hold of the first user not ready: %v", err)
+	}
+	deadline := testNow.Add(3 * day)
+	if hold.CopyID != items[0].ID || hold.PickupDeadline == nil || !hold.PickupDeadline.Equal(deadline) {
+		t.Errorf("hold = %+v, want copy %v kept until %v", hold, items[0].ID, deadline)
+	}
+	if _, err := ct.Lend(second.ID, book.ID, "", time.Time{}, 0); err != ErrNoCopyAvailable {
+		t.Errorf("Lend copy kept for another user: err = %v, want %v", err, ErrNoCopyAvailable)
+	}
+
+	// The first user picks it up, and the hold is fulfilled
+	ct.lend(t, first.ID, book.ID)
+	if _, err := ct.repo.FindReadyHold(first.ID, book.ID); err != repository.ErrNotFound {
+		t.Errorf("hold still ready after pickup: err = %v", err)
+	}
+	if next, err := ct.repo.NextWaitingHold(book.ID); err != nil || next.UserID != second.ID {
+		t.Errorf("NextWaitingHold = %+v, %v, want the second user", next, err)
+	}
+}
+
+func TestHoldExpires(t *testing.T) {
+	ct := newCirculationTest(t)
+	first := ct.addUser(t)
+	second := ct.addUser(t)
+	book, items := ct.addBook(t, models.ItemRegular)
+	ct.repo.SetCopyStatus(&items[0], models.CopyOnLoan)
+
+	for _, user := range []models.User{first, second} {
+		hold := models.Hold{UserID: user.ID, BookID: book.ID, Status: models.HoldWaiting}
+		ct.repo.CreateHold(&hold)
+	}
+	if err := ct.AssignCopy(items[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first user misses the pickup deadline, so the copy is passed on
+	ct.now = ct.now.Add(4 * day)
+	if err := ct.ExpireHolds(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ct.repo.FindReadyHold(first.ID, book.ID); err != repository.ErrNotFound {
+		t.Errorf("expired hold still ready: err = %v", err)
+	}
+	if hold, err := ct.repo.FindReadyHold(second.ID, book.ID); err != nil || hold.CopyID != items[0].ID {
+		t.Errorf("hold of the second user = %+v, %v, want copy %v kept", hold, err, items[0].ID)
+	}
+	if _, err := ct.Lend(first.ID, book.ID, "", time.Time{}, 0); err != ErrNoCopyAvailable {
+		t.Errorf("Lend after hold expired: err = %v, want %v", err, ErrNoCopyAvailable)
+	}
+	ct.lend(t, second.ID, book.ID)
+}
+
+// lendConcurrently lends the only copy of a book to different users at the
+// same time, and checks that exactly one of them gets it
+func lendConcurrently(t *testing.T, repo repository.Repository) {
+	t.Helper()
+	const n = 10
+	book := models.Book{Title: "Computer Systems"}
+	if err := repo.CreateBook(&book); err != nil {
+		t.Fatal(err)
+	}
+	item := models.Copy{BookID: book.ID, Barcode: "CS-1", Status: models.CopyAvailable, Category: models.ItemRegular}
+	if err := repo.CreateCopy(&item); err != nil {
+		t.Fatal(err)
+	}
+	users := make([]models.User, n)
+	for i := range users {
+		users[i] = models.User{
+			Username: fmt.Sprintf("user%v", i),
+			Role:     models.RoleUser,
+			Category: models.PatronStudent,
+			State:    models.UserActive,
+		}
+		if err := repo.CreateUser(&users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewCirculation(repo, testConfig, zap.NewNop().Sugar())
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = svc.Lend(users[i].ID, book.ID, "", time.Time{}, 0)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var lent, loans uint
+	for i, err := range errs {
+		switch err {
+		case nil:
+			lent++
+		case ErrNoCopyAvailable, repository.ErrConflict:
+		default:
+			t.Errorf("Lend to user %v: unexpected error %v", users[i].ID, err)
+		}
+		count, err := repo.CountLoans(users[i].ID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		loans += count
+	}
+	if lent != 1 || loans != 1 {
+		t.Errorf("%v of %v concurrent loans succeeded with %v records, want exactly 1", lent, n, loans)
+	}
+	if stored, err := repo.FindCopy(item.ID); err != nil || stored.Status != models.CopyOnLoan {
+		t.Errorf("copy = %+v, %v, want on loan", stored, err)
+	}
+}
+
+func TestLendConcurrentlyInMemory(t *testing.T) {
+	lendConcurrently(t, repository.NewMemory())
+}
+
+func TestLendConcurrentlyOnSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := models.DbSetup(config.DbConfig{Type: models.DbSQLite, Path: filepath.Join(dir, "realms.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	lendConcurrently(t, repository.NewGorm(db))
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/notify"
+	"github.com/hakula139/REALMS/internal/app/repository"
+	"go.uber.org/zap"
+)
+
+// noticeDateLayout is the format of return dates in notices
+const noticeDateLayout = "Mon, 2006-01-02 15:04"
+
+// Reminder sends due-date reminders of the loans due within DueSoonDays, and
+// notices of the overdue loans through the notifiers
+// Each notice is recorded once sent, so that it's sent only once for each
+// return date of a loan through each channel. Notices failed to send are
+// retried on the next run. Users receive the notices by their preferences
+type Reminder struct {
+	Books   repository.BookRepository
+	Users   repository.UserRepository
+	Records repository.RecordRepository
+	Notices repository.NoticeRepository
+
+	Notifiers []notify.Notifier
+	Config    config.NotifyConfig
+	Library   config.LibraryConfig
+	Logger    *zap.SugaredLogger
+	// Now returns the current time, which is the local time by default
+	Now func() time.Time
+
+	// mu keeps the runs from overlapping, e.g. a scheduled one and a manual one
+	mu sync.Mutex
+}
+
+// NewReminder creates a reminder using the repository
+func NewReminder(
+	repo repository.Repository,
+	notifiers []notify.Notifier,
+	cfg config.NotifyConfig,
+	libcfg config.LibraryConfig,
+	logger *zap.SugaredLogger,
+) *Reminder {
+	return &Reminder{
+		Books:     repo,
+		Users:     repo,
+		Records:   repo,
+		Notices:   repo,
+		Notifiers: notifiers,
+		Config:    cfg,
+		Library:   libcfg,
+		Logger:    logger,
+		Now:       func() time.Time { return time.Now().Local() },
+	}
+}
+
+// Schedule runs the reminder right away, and then every IntervalMinutes until
+// stop is closed
+func (s *Reminder) Schedule(stop <-chan struct{}) {
+	interval := time.Duration(s.Config.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Run(); err != nil {
+			s.Logger.Errorf("Failed to send notices: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Run sends the notices due now, and returns the number of notices sent
+func (s *Reminder) Run() (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loc, err := s.Library.Location()
+	if err != nil {
+		return 0, err
+	}
+	now := s.Now()
+	records, err := s.Records.LoansDueBefore(now.AddDate(0, 0, int(s.Config.DueSoonDays)))
+	if err != nil {
+		return 0, err
+	}
+
+	var sent uint
+	users := make(map[uint]*models.User)
+	books := make(map[uint]*models.Book)
+	for _, record := range records {
+		kind := models.NoticeDueSoon
+		if record.ReturnDate.Before(now) {
+			kind = models.NoticeOverdue
+		}
+
+		// Skips the loans of users removed or not willing to receive the
+		// notice, and the books removed
+		user, ok := users[record.UserID]
+		if !ok {
+			if found, err := s.Users.FindUser(record.UserID); err == nil {
+				user = &found
+			} else if err != repository.ErrNotFound {
+				return sent, err
+			}
+			users[record.UserID] = user
+		}
+		if user == nil || !wantsNotice(*user, kind) {
+			continue
+		}
+		book, ok := books[record.BookID]
+		if !ok {
+			if found, err := s.Books.FindBook(record.BookID); err == nil {
+				book = &found
+			} else if err != repository.ErrNotFound {
+				return sent, err
+			}
+			books[record.BookID] = book
+		}
+		if book == nil {
+			continue
+		}
+
+		msg := noticeMessage(kind, *user, *book, record, loc)
+		for _, notifier := range s.Notifiers {
+			ok, err := s.notify(notifier, msg, record, now)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	if sent > 0 {
+		s.Logger.Infof("Sent %v notices", sent)
+	}
+	return sent, nil
+}
+
+// notify sends the message through the notifier unless sent before, and
+// records it if sent
+// Failures of the notifier are logged rather than returned, so that the
+// other notices are still sent
+func (s *Reminder) notify(notifier notify.Notifier, msg notify.Message, record models.Record, now time.Time) (bool, error) {
+	channel := notifier.Channel()
+	sent, err := s.Notices.NoticeSent(record, msg.Kind, channel)
+	if err != nil || sent {
+		return false, err
+	}
+
+	if err := notifier.Notify(msg); err == notify.ErrNoAddress {
+		return false, nil
+	} else if err != nil {
+		s.Logger.Warnf("Failed to send %v notice of record %v to user %v through %v: %v",
+			msg.Kind, record.ID, record.UserID, channel, err)
+		return false, nil
+	}
+
+	notice := models.Notice{
+		UserID:      record.UserID,
+		RecordID:    record.ID,
+		Kind:        msg.Kind,
+		Channel:     channel,
+		ExtendTimes: record.ExtendTimes,
+		ReturnDate:  record.ReturnDate,
+		SentAt:      now,
+	}
+	if err := s.Notices.CreateNotice(&notice); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// wantsNotice checks if the user wants to receive the notice of the kind
+func wantsNotice(user models.User, kind string) bool {
+	switch user.NotifyPreference() {
+	case models.NotifyAll:
+		return true
+	case models.NotifyOverdue:
+		return kind == models.NoticeOverdue
+	}
+	return false
+}
+
+// noticeMessage writes the notice of the loan
+func noticeMessage(kind string, user models.User, book models.Book, record models.Record, loc *time.Location) notify.Message {
+	returnDate := record.ReturnDate.In(loc)
+	msg := notify.Message{
+		Kind:       kind,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		RecordID:   record.ID,
+		BookID:     book.ID,
+		Title:      book.Title,
+		ReturnDate: returnDate,
+	}
+	date := returnDate.Format(noticeDateLayout)
+	if kind == models.NoticeOverdue {
+		msg.Subject = fmt.Sprintf("Overdue: %q was due on %v", book.Title, date)
+		msg.Text = fmt.Sprintf("Dear %v,\n\n"+
+			"The book %q you borrowed was due on %v, and is now overdue. "+
+			"Please return it as soon as possible, since a fine is charged for each overdue day.\n\n"+
+			"REALMS\n", user.Username, book.Title, date)
+	} else {
+		msg.Subject = fmt.Sprintf("Reminder: %q is due on %v", book.Title, date)
+		msg.Text = fmt.Sprintf("Dear %v,\n\n"+
+			"The book %q you borrowed is due on %v. "+
+			"Please return or renew it in time.\n\n"+
+			"REALMS\n", user.Username, book.Title, date)
+	}
+	return msg
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/notify"
+	"go.uber.org/zap"
+)
+
+// fakeNotifier records the messages sent through it, or fails with err
+type fakeNotifier struct {
+	channel string
+	err     error
+	sent    []notify.Message
+}
+
+func (n *fakeNotifier) Channel() string {
+	return n.channel
+}
+
+func (n *fakeNotifier) Notify(msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// kinds returns the kinds of the messages sent
+func (n *fakeNotifier) kinds() []string {
+	var kinds []string
+	for _, msg := range n.sent {
+		kinds = append(kinds, msg.Kind)
+	}
+	return kinds
+}
+
+// newReminderTest creates a reminder over the repository of the circulation
+// test, sharing its clock
+func newReminderTest(ct *circulationTest, notifiers ...notify.Notifier) *Reminder {
+	cfg := config.NotifyConfig{DueSoonDays: 3}
+	reminder := NewReminder(ct.repo, notifiers, cfg, testConfig, zap.NewNop().Sugar())
+	reminder.Now = func() time.Time { return ct.now }
+	return reminder
+}
+
+// run runs the reminder, and checks the number of notices sent
+func run(t *testing.T, reminder *Reminder, want uint) {
+	t.Helper()
+	sent, err := reminder.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != want {
+		t.Errorf("Run at %v sent %v notices, want %v", reminder.Now(), sent, want)
+	}
+}
+
+func TestReminderSendsEachNoticeOnce(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+	email := &fakeNotifier{channel: notify.ChannelSMTP}
+	reminder := newReminderTest(ct, email)
+
+	// The loan falls due on 2026-03-16, and is not due soon until 3 days before
+	run(t, reminder, 0)
+	ct.now = time.Date(2026, time.March, 14, 8, 0, 0, 0, time.UTC)
+	run(t, reminder, 1)
+	run(t, reminder, 0)
+	ct.now = ct.now.Add(24 * time.Hour)
+	run(t, reminder, 0)
+
+	// Once overdue, a notice of another kind is sent, also only once
+	ct.now = time.Date(2026, time.March, 17, 8, 0, 0, 0, time.UTC)
+	run(t, reminder, 1)
+	ct.now = ct.now.Add(24 * time.Hour)
+	run(t, reminder, 0)
+
+	if got := email.kinds(); len(got) != 2 || got[0] != models.NoticeDueSoon || got[1] != models.NoticeOverdue {
+		t.Errorf("notices = %v, want due_soon and overdue", got)
+	}
+	if msg := email.sent[0]; msg.RecordID != record.ID || msg.UserID != user.ID || msg.BookID != book.ID {
+		t.Errorf("notice = %+v, want of record %v", msg, record.ID)
+	}
+}
+
+func TestReminderRemindsAgainAfterRenewal(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	record := ct.lend(t, user.ID, book.ID)
+	email := &fakeNotifier{channel: notify.ChannelSMTP}
+	reminder := newReminderTest(ct, email)
+
+	ct.now = time.Date(2026, time.March, 14, 8, 0, 0, 0, time.UTC)
+	run(t, reminder, 1)
+
+	// The renewal extends the loan by 7 days to 2026-03-23, which is reminded
+	// of as a new return date
+	if err := ct.Renew(&record, 0); err != nil {
+		t.Fatal(err)
+	}
+	run(t, reminder, 0)
+	ct.now = time.Date(2026, time.March, 21, 8, 0, 0, 0, time.UTC)
+	run(t, reminder, 1)
+	run(t, reminder, 0)
+
+	if len(email.sent) != 2 || !email.sent[1].ReturnDate.Equal(record.ReturnDate) {
+		t.Errorf("notices = %+v, want the second of return date %v", email.sent, record.ReturnDate)
+	}
+}
+
+func TestReminderRetriesFailedChannel(t *testing.T) {
+	ct := newCirculationTest(t)
+	user := ct.addUser(t)
+	book, _ := ct.addBook(t, models.ItemRegular)
+	ct.lend(t, user.ID, book.ID)
+	email := &fakeNotifier{channel: notify.ChannelSMTP, err: errors.New("connection refused")}
+	webhook := &fakeNotifier{channel: notify.ChannelWebhook}
+	noAddress := &fakeNotifier{channel: notify.ChannelLog, err: notify.ErrNoAddress}
+	reminder := newReminderTest(ct, email, webhook, noAddress)
+
+	// The failed notice is retried on the next run, without resending the
+	// notice through the channel which worked
+	ct.now = time.Date(2026, time.March, 14, 8, 0, 0, 0, time.UTC)
+	run(t, reminder, 1)
+	run(t, reminder, 0)
+	email.err = nil
+	run(t, reminder, 1)
+	run(t, reminder, 0)
+
+	if len(email.sent) != 1 || len(webhook.sent) != 1 || len(noAddress.sent) != 0 {
+		t.Errorf("sent %v emails, %v webhooks and %v logs, want 1, 1 and 0",
+			len(email.sent), len(webhook.sent), len(noAddress.sent))
+	}
+}
+
+func TestReminderPreferences(t *testing.T) {
+	tests := []struct {
+		notify string
+		want   []string
+	}{
+		{"", []string{models.NoticeDueSoon, models.NoticeOverdue}},
+		{models.NotifyAll, []string{models.NoticeDueSoon, models.NoticeOverdue}},
+		{models.NotifyOverdue, []string{models.NoticeOverdue}},
+		{models.NotifyNone, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notify, func(t *testing.T) {
+			ct := newCirculationTest(t)
+			user := models.User{
+				Role:     models.RoleUser,
+				Category: models.PatronStudent,
+				State:    models.UserActive,
+				Notify:   tt.notify,
+			}
+			if err := ct.repo.CreateUser(&user); err != nil {
+				t.Fatal(err)
+			}
+			book, _ := ct.addBook(t, models.ItemRegular)
+			ct.lend(t, user.ID, book.ID)
+			email := &fakeNotifier{channel: notify.ChannelSMTP}
+			reminder := newReminderTest(ct, email)
+
+			for _, now := range []time.Time{
+				time.Date(2026, time.March, 14, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, time.March, 17, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, time.March, 18, 8, 0, 0, 0, time.UTC),
+			} {
+				ct.now = now
+				if _, err := reminder.Run(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := email.kinds()
+			if len(got) != len(tt.want) {
+				t.Fatalf("notices = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("notices = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package sessionstore
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"github.com/hakula139/REALMS/internal/app/config"
+	"github.com/hakula139/REALMS/internal/app/models"
+	"github.com/hakula139/REALMS/internal/app/repository"
+)
+
+// touchInterval is how often the time a session was last seen is saved, so
+// that not every request writes to the database
+const touchInterval = time.Minute
+
+// IDKey is the key of the ID of the current session in the session values,
+// which is set once the session is loaded or started, and never saved
+const IDKey = "session_id"
+
+// Store keeps the login sessions in the repository, where the cookie only
+// holds a random token signed with the secret
+// A session holds nothing but the ID of the logged-in user under UserKey, and
+// its own ID under IDKey, the other values are dropped when saved. Sessions idle or old for longer
+// than the timeouts in the config are removed once found, and the stale ones
+// are removed on each login
+type Store struct {
+	Sessions repository.SessionRepository
+	Config   config.SessionConfig
+	// UserKey is the key of the user ID in the session values
+	UserKey string
+	// Now returns the current time, which is the local time by default
+	Now func() time.Time
+
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+var _ sessions.Store = (*Store)(nil)
+
+// New creates a store using the repository, where the cookies expire along
+// with the sessions
+func New(repo repository.SessionRepository, cfg config.SessionConfig, userKey string) *Store {
+	s := &Store{
+		Sessions: repo,
+		Config:   cfg,
+		UserKey:  userKey,
+		Now:      func() time.Time { return time.Now().Local() },
+		codecs:   securecookie.CodecsFromPairs([]byte(cfg.Secret)),
+		options:  &gsessions.Options{Path: "/", HttpOnly: true},
+	}
+	s.options.MaxAge = int(cfg.AbsoluteTimeout() / time.Second)
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			// Cookies older than the absolute timeout are rejected as well
+			sc.MaxAge(s.options.MaxAge)
+		}
+	}
+	return s
+}
+
+// Options sets the options of the cookies
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+}
+
+// Get returns the session of the request, which is cached for the request
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session of the request, or returns a new one if the token in
+// the cookie is missing, invalid, revoked or expired
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return session, nil
+	}
+	found, err := s.Sessions.FindSession(models.HashToken(token))
+	if err == repository.ErrNotFound {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	now := s.Now()
+	if found.Expired(now, s.Config.IdleTimeout(), s.Config.AbsoluteTimeout()) {
+		return session, s.Sessions.DeleteSession(found.ID)
+	}
+	if now.Sub(found.LastSeenAt) >= touchInterval {
+		if err := s.Sessions.TouchSession(found.ID, now); err != nil {
+			return session, err
+		}
+	}
+
+	session.ID = token
+	session.Values[s.UserKey] = found.UserID
+	session.Values[IDKey] = found.ID
+	session.IsNew = false
+	return session, nil
+}
+
+// Save starts a session once a user is set, or ends it once the user is
+// deleted from the session values
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	userID, ok := session.Values[s.UserKey].(uint)
+	if !ok || session.Options.MaxAge < 0 {
+		return s.end(w, session)
+	}
+	if session.ID != "" {
+		return nil
+	}
+
+	token, hash, err := models.NewSessionToken()
+	if err != nil {
+		return err
+	}
+	now := s.Now()
+	if err := s.prune(now); err != nil {
+		return err
+	}
+	created := models.Session{
+		TokenHash:  hash,
+		UserID:     userID,
+		IP:         RemoteIP(r),
+		UserAgent:  r.UserAgent(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.Sessions.CreateSession(&created); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), token, s.codecs...)
+	if err != nil {
+		return err
+	}
+	session.ID = token
+	session.Values[IDKey] = created.ID
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// RevokeUser ends all sessions of the user, and returns the number of
+// sessions ended
+func (s *Store) RevokeUser(userID uint) (uint, error) {
+	return s.Sessions.DeleteUserSessions(userID)
+}
+
+// RevokeOthers ends all sessions of the user except the one of given ID, e.g.
+// the current one when the password is changed, and returns the number of
+// sessions ended
+func (s *Store) RevokeOthers(userID, keepID uint) (uint, error) {
+	return s.Sessions.DeleteOtherSessions(userID, keepID)
+}
+
+// end removes the session, and expires the cookie
+func (s *Store) end(w http.ResponseWriter, session *gsessions.Session) error {
+	if session.ID != "" {
+		found, err := s.Sessions.FindSession(models.HashToken(session.ID))
+		if err == nil {
+			err = s.Sessions.DeleteSession(found.ID)
+		}
+		if err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		session.ID = ""
+	}
+	opts := *session.Options
+	opts.MaxAge = -1
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), "", &opts))
+	return nil
+}
+
+// prune removes the sessions expired
+func (s *Store) prune(now time.Time) error {
+	var idleBefore, createdBefore time.Time
+	if idle := s.Config.IdleTimeout(); idle > 0 {
+		idleBefore = now.Add(-idle)
+	}
+	if absolute := s.Config.AbsoluteTimeout(); absolute > 0 {
+		createdBefore = now.Add(-absolute)
+	}
+	return s.Sessions.DeleteStaleSessions(idleBefore, createdBefore)
+}
+
+// RemoteIP returns the IP address of the client, which is the peer address
+// of the connection rather than headers set by the client like
+// X-Forwarded-For
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}